@@ -23,7 +23,14 @@ import (
 // @host      localhost:8080
 // @BasePath  /api/v1
 
-// @securityDefinitions.basic  BasicAuth
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT (HS256 или RS256) в формате "Bearer <token>"
 func main() {
 	application, err := app.Bootstrap()
 	if err != nil {
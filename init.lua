@@ -3,6 +3,10 @@ box.cfg{
     log_level = 5,
 }
 
+local log = require('log')
+local ffi = require('ffi')
+local msgpack = require('msgpack')
+
 -- Создать пользователя, если не существует
 local user = 'admin'
 local password = 'admin'
@@ -14,19 +18,685 @@ else
     box.schema.user.passwd(user, password)
 end
 
--- Создать пространство, если не существует
-if not box.space.kv then
-    box.schema.space.create('kv', { if_not_exists = true })
-    box.space.kv:format({
+-- Пространства имён (namespace). Namespace default хранится в исходных space kv,
+-- kv_history и kv_changelog, остальные — в собственных kv_ns_<name>, kv_ns_<name>_history
+-- и kv_ns_<name>_changelog, поэтому ключи разных namespace не пересекаются.
+local KV_DEFAULT_NAMESPACE = 'default'
+
+local function kv_space_names(ns)
+    if ns == KV_DEFAULT_NAMESPACE then
+        return { kv = 'kv', history = 'kv_history', changelog = 'kv_changelog' }
+    end
+    local prefix = 'kv_ns_' .. ns
+    return { kv = prefix, history = prefix .. '_history', changelog = prefix .. '_changelog' }
+end
+
+-- Создаёт space одного namespace, если их ещё нет.
+-- Формат задаётся при каждом старте: новые поля добавляются как nullable,
+-- чтобы кортежи, записанные до их появления, оставались валидными
+local function kv_define_spaces(ns)
+    local names = kv_space_names(ns)
+
+    local kv = box.schema.space.create(names.kv, { if_not_exists = true })
+    kv:format({
         { name = 'key', type = 'string' },
         { name = 'value', type = '*' },
         { name = 'created_at', type = 'unsigned' },
         { name = 'updated_at', type = 'unsigned' },
         { name = 'deleted_at', type = 'unsigned' },
         { name = 'is_deleted', type = 'boolean' },
+        { name = 'expires_at', type = 'unsigned', is_nullable = true },
+        { name = 'version', type = 'unsigned', is_nullable = true },
+        { name = 'content_type', type = 'string', is_nullable = true },
+    })
+    kv:create_index('primary', { parts = { 'key',  }, if_not_exists = true })
+    kv:create_index('deleted', { parts = { 'is_deleted', 'key' }, if_not_exists = true })
+    kv:create_index('expires', {
+        parts = { { field = 'expires_at', type = 'unsigned', is_nullable = true } },
+        unique = false,
+        if_not_exists = true,
+    })
+
+    -- Журнал изменений: каждая мутация kv пишет сюда запись в той же транзакции.
+    -- lsn выдаётся последовательностью и только растёт, по нему потребители дочитывают журнал.
+    local changelog = box.schema.space.create(names.changelog, { if_not_exists = true })
+    changelog:format({
+        { name = 'lsn', type = 'unsigned' },
+        { name = 'key', type = 'string' },
+        { name = 'op', type = 'string' },
+        { name = 'old_value', type = '*', is_nullable = true },
+        { name = 'new_value', type = '*', is_nullable = true },
+        { name = 'version', type = 'unsigned' },
+        { name = 'timestamp', type = 'unsigned' },
+    })
+    changelog:create_index('primary', { parts = { 'lsn' }, sequence = true, if_not_exists = true })
+
+    -- Предыдущие ревизии записей: перед update и soft delete сюда копируется текущее
+    -- состояние, для каждого ключа хранятся только последние history_depth ревизий
+    local history = box.schema.space.create(names.history, { if_not_exists = true })
+    history:format({
+        { name = 'key', type = 'string' },
+        { name = 'version', type = 'unsigned' },
+        { name = 'value', type = '*' },
+        { name = 'created_at', type = 'unsigned' },
+        { name = 'updated_at', type = 'unsigned' },
+        { name = 'expires_at', type = 'unsigned', is_nullable = true },
+        { name = 'archived_at', type = 'unsigned' },
+        { name = 'content_type', type = 'string', is_nullable = true },
+    })
+    history:create_index('primary', { parts = { 'key', 'version' }, if_not_exists = true })
+end
+
+box.schema.space.create('kv_namespaces', { if_not_exists = true })
+box.space.kv_namespaces:format({
+    { name = 'name', type = 'string' },
+    { name = 'created_at', type = 'unsigned' },
+})
+box.space.kv_namespaces:create_index('primary', { parts = { 'name' }, if_not_exists = true })
+if box.space.kv_namespaces:get({ KV_DEFAULT_NAMESPACE }) == nil then
+    box.space.kv_namespaces:insert({ KV_DEFAULT_NAMESPACE, os.time() })
+end
+
+for _, namespace in box.space.kv_namespaces:pairs() do
+    kv_define_spaces(namespace.name)
+end
+
+-- Аренды (распределённые блокировки). Время хранится в миллисекундах, чтобы короткие
+-- TTL не округлялись до секунды. token — fencing token: выдаётся последовательностью
+-- при каждом новом захвате и только растёт, поэтому устаревший владелец отличим по нему.
+-- Аренды не хранятся записями kv: там их видели бы списки, история и журнал изменений,
+-- их можно было бы перезаписать обычным put, а версия записи сбрасывается при пересоздании.
+box.schema.space.create('kv_locks', { if_not_exists = true })
+box.space.kv_locks:format({
+    { name = 'name', type = 'string' },
+    { name = 'owner', type = 'string' },
+    { name = 'token', type = 'unsigned' },
+    { name = 'acquired_at', type = 'unsigned' },
+    { name = 'expires_at', type = 'unsigned' },
+})
+box.space.kv_locks:create_index('primary', { parts = { 'name' }, if_not_exists = true })
+box.space.kv_locks:create_index('expires', { parts = { 'expires_at' }, unique = false, if_not_exists = true })
+box.schema.sequence.create('kv_lock_token', { if_not_exists = true })
+
+local KV_FIELD_COUNT = #box.space.kv:format()
+
+local function kv_is_expired(tuple, now)
+    local expires_at = tuple.expires_at
+    return expires_at ~= nil and expires_at ~= 0 and expires_at <= now
+end
+
+-- Запись видна клиентам, если она не удалена и её TTL ещё не истёк
+local function kv_is_live(tuple, now)
+    return tuple ~= nil and not tuple.is_deleted and not kv_is_expired(tuple, now)
+end
+
+-- Кортежи, записанные до появления поля version, считаются первой версией
+local function kv_version(tuple)
+    local version = tuple.version
+    if version == nil then
+        return 1
+    end
+    return version
+end
+
+-- Дополняет кортеж старого формата до полного набора полей
+local function kv_totable(tuple)
+    local t = tuple:totable()
+    for i = #t + 1, KV_FIELD_COUNT do
+        t[i] = box.NULL
+    end
+    return t
+end
+
+-- Выполняет fn в транзакции, чтобы запись в kv и в журнал фиксировались вместе.
+-- Внутри уже открытой транзакции (атомарный kv_batch) fn вызывается как есть.
+local function kv_atomic(fn, ...)
+    if box.is_in_txn() then
+        return fn(...)
+    end
+    return box.atomic(fn, ...)
+end
+
+-- Возвращает space namespace ns либо nil, если namespace не зарегистрирован
+local function kv_spaces(ns)
+    if box.space.kv_namespaces:get({ ns }) == nil then
+        return nil
+    end
+    local names = kv_space_names(ns)
+    return {
+        kv = box.space[names.kv],
+        history = box.space[names.history],
+        changelog = box.space[names.changelog],
+    }
+end
+
+-- Выполняет fn(s, ...) в транзакции над space namespace ns
+local function kv_call(ns, fn, ...)
+    local s = kv_spaces(ns)
+    if s == nil then
+        return box.NULL, 'namespace_not_found'
+    end
+    return kv_atomic(fn, s, ...)
+end
+
+local function kv_log(s, key, op, old_value, new_value, version, now)
+    s.changelog:insert({ box.NULL, key, op, old_value, new_value, version, now })
+end
+
+local function kv_drop_history(s, key)
+    local versions = {}
+    for _, revision in s.history:pairs({ key }) do
+        table.insert(versions, revision.version)
+    end
+    for _, version in ipairs(versions) do
+        s.history:delete({ key, version })
+    end
+end
+
+-- Сохраняет текущее состояние записи как ревизию и удаляет самые старые сверх depth
+local function kv_archive(s, tuple, now, depth)
+    if depth == nil or depth <= 0 then
+        return
+    end
+
+    s.history:replace({
+        tuple.key, kv_version(tuple), tuple.value,
+        tuple.created_at, tuple.updated_at, tuple.expires_at or 0, now, tuple.content_type,
     })
-    box.space.kv:create_index('primary', { parts = { 'key',  }, if_not_exists = true })
-    box.space.kv:create_index('deleted', { parts = { 'is_deleted', 'key' }, if_not_exists = true })
+
+    local excess = s.history:count({ tuple.key }) - depth
+    if excess <= 0 then
+        return
+    end
+    local versions = {}
+    for _, revision in s.history:pairs({ tuple.key }) do
+        if #versions >= excess then
+            break
+        end
+        table.insert(versions, revision.version)
+    end
+    for _, version in ipairs(versions) do
+        s.history:delete({ tuple.key, version })
+    end
+end
+
+-- Создаёт запись. Удалённая запись и запись с истёкшим TTL, которую ещё не удалил reaper,
+-- для клиентов уже не существуют и перезаписываются. Ревизии удалённой записи остаются
+-- в истории, ревизии истёкшей удаляются, как это сделал бы reaper.
+local function create(s, key, value, content_type, now, expires_at)
+    local tuple = s.kv:get(key)
+    if kv_is_live(tuple, now) then
+        return box.NULL, 'already_exists'
+    end
+
+    -- Версия продолжает последовательность прежней записи: ETag и expected_version,
+    -- полученные для неё, не должны совпасть с версией новой записи
+    local version = 1
+    if tuple ~= nil then
+        version = kv_version(tuple) + 1
+    end
+    if tuple == nil or kv_is_expired(tuple, now) then
+        kv_drop_history(s, key)
+    end
+    tuple = s.kv:replace({ key, value, now, now, 0, false, expires_at or 0, version, content_type })
+    kv_log(s, key, 'create', box.NULL, value, version, now)
+    return tuple
+end
+
+function kv_create(ns, key, value, content_type, now, expires_at)
+    return kv_call(ns, create, key, value, content_type, now, expires_at)
+end
+
+local function update(s, key, value, content_type, now, expires_at, expected_version, history_depth)
+    local tuple = s.kv:get(key)
+    if not kv_is_live(tuple, now) then
+        return box.NULL, 'not_found'
+    end
+    if expected_version ~= nil and kv_version(tuple) ~= expected_version then
+        return box.NULL, 'version_conflict'
+    end
+
+    local t = kv_totable(tuple)
+    t[2] = value
+    t[4] = now
+    t[9] = content_type
+    if expires_at ~= nil then
+        t[7] = expires_at
+    end
+    t[8] = kv_version(tuple) + 1
+    kv_archive(s, tuple, now, history_depth)
+    kv_log(s, key, 'update', tuple.value, value, t[8], now)
+    return s.kv:replace(t), box.NULL, tuple
+end
+
+-- Обновляет значение записи и возвращает новый и предыдущий кортежи.
+-- Если expected_version передан и не совпадает с текущей версией,
+-- возвращает 'version_conflict' и ничего не меняет.
+function kv_update(ns, key, value, content_type, now, expires_at, expected_version, history_depth)
+    return kv_call(ns, update, key, value, content_type, now, expires_at, expected_version, history_depth)
+end
+
+local function soft_delete(s, key, now, expected_version, history_depth)
+    local tuple = s.kv:get(key)
+    if not kv_is_live(tuple, now) then
+        return box.NULL, 'not_found'
+    end
+    if expected_version ~= nil and kv_version(tuple) ~= expected_version then
+        return box.NULL, 'version_conflict'
+    end
+
+    local t = kv_totable(tuple)
+    t[4] = now
+    t[5] = now
+    t[6] = true
+    t[8] = kv_version(tuple) + 1
+    kv_archive(s, tuple, now, history_depth)
+    kv_log(s, key, 'soft_delete', tuple.value, box.NULL, t[8], now)
+    return s.kv:replace(t)
+end
+
+-- Помечает запись удалённой с той же проверкой версии, что и kv_update
+function kv_soft_delete(ns, key, now, expected_version, history_depth)
+    return kv_call(ns, soft_delete, key, now, expected_version, history_depth)
+end
+
+local function restore(s, key, now)
+    local tuple = s.kv:get(key)
+    if tuple == nil then
+        return box.NULL, 'not_found'
+    end
+    if not tuple.is_deleted then
+        return box.NULL, 'not_deleted'
+    end
+
+    local t = kv_totable(tuple)
+    t[4] = now
+    t[5] = 0
+    t[6] = false
+    t[8] = kv_version(tuple) + 1
+    kv_log(s, key, 'restore', box.NULL, tuple.value, t[8], now)
+    return s.kv:replace(t)
+end
+
+function kv_restore(ns, key, now)
+    return kv_call(ns, restore, key, now)
+end
+
+-- Целые за пределами double приходят в Lua как cdata int64/uint64
+local function kv_is_number(value)
+    return type(value) == 'number' or ffi.istype('int64_t', value) or ffi.istype('uint64_t', value)
+end
+
+-- Сравнивает значения по содержимому: числа независимо от представления,
+-- таблицы поэлементно, остальное (строки, bool, бинарные данные) по MessagePack
+local function kv_equal(a, b)
+    if kv_is_number(a) and kv_is_number(b) then
+        return a == b
+    end
+    if type(a) == 'table' and type(b) == 'table' then
+        for k, v in pairs(a) do
+            if not kv_equal(v, b[k]) then
+                return false
+            end
+        end
+        for k in pairs(b) do
+            if a[k] == nil then
+                return false
+            end
+        end
+        return true
+    end
+    return msgpack.encode(a) == msgpack.encode(b)
+end
+
+local function cas(s, key, expected_value, expected_version, value, content_type, now, expires_at, history_depth)
+    local tuple = s.kv:get(key)
+    if not kv_is_live(tuple, now) then
+        return box.NULL, 'not_found'
+    end
+    if expected_version ~= nil and kv_version(tuple) ~= expected_version then
+        return box.NULL, 'version_conflict'
+    end
+    if expected_value ~= nil and not kv_equal(tuple.value, expected_value) then
+        return box.NULL, 'value_mismatch'
+    end
+    return update(s, key, value, content_type, now, expires_at, nil, history_depth)
+end
+
+-- Заменяет значение, только если текущее совпадает с expected_value и/или
+-- версия совпадает с expected_version. Проверка и запись идут в одной транзакции.
+function kv_cas(ns, key, expected_value, expected_version, value, content_type, now, expires_at, history_depth)
+    return kv_call(ns, cas, key, expected_value, expected_version, value, content_type, now, expires_at, history_depth)
+end
+
+-- Создаёт запись, если ключа нет. Иначе возвращает 'already_exists'
+-- и существующий кортеж третьим значением.
+function kv_put_if_absent(ns, key, value, content_type, now, expires_at)
+    local tuple, status = kv_create(ns, key, value, content_type, now, expires_at)
+    if status == 'already_exists' then
+        return box.NULL, status, kv_spaces(ns).kv:get(key)
+    end
+    return tuple, status
+end
+
+local function incr(s, key, delta, initial, now, expires_at)
+    local tuple = s.kv:get(key)
+    -- Удалённый или истёкший ключ считается отсутствующим и создаётся заново,
+    -- версия продолжает последовательность прежней записи
+    local version = 1
+    if tuple ~= nil and not kv_is_live(tuple, now) then
+        -- Ревизии удалённой записи остаются в истории, истёкшей — удаляются, как при reaper
+        if kv_is_expired(tuple, now) then
+            kv_drop_history(s, key)
+        end
+        version = kv_version(tuple) + 1
+        s.kv:delete({ key })
+        tuple = nil
+    elseif tuple == nil then
+        kv_drop_history(s, key)
+    elseif not kv_is_number(tuple.value) then
+        return box.NULL, 'not_numeric'
+    elseif #tuple < KV_FIELD_COUNT then
+        -- Присваивать можно только существующим полям, поэтому старый кортеж дополняется
+        s.kv:replace(kv_totable(tuple))
+    end
+
+    local operations = { { '+', 2, delta }, { '=', 4, now } }
+    if tuple ~= nil then
+        table.insert(operations, { '=', 8, kv_version(tuple) + 1 })
+    end
+    s.kv:upsert(
+        { key, initial + delta, now, now, 0, false, expires_at or 0, version, 'application/json' },
+        operations
+    )
+
+    local updated = s.kv:get(key)
+    if tuple == nil then
+        kv_log(s, key, 'create', box.NULL, updated.value, version, now)
+        return updated
+    end
+    kv_log(s, key, 'update', tuple.value, updated.value, updated.version, now)
+    return updated, box.NULL, tuple
+end
+
+-- Атомарно увеличивает числовое значение на delta через upsert с операцией '+'.
+-- Отсутствующий ключ создаётся со значением initial + delta и сроком expires_at.
+-- Возвращает новый и предыдущий кортежи либо статус 'not_numeric'.
+function kv_incr(ns, key, delta, initial, now, expires_at)
+    return kv_call(ns, incr, key, delta, initial, now, expires_at)
+end
+
+-- Выполняет одну операцию пакета: возвращает кортеж либо nil и статус
+local function kv_apply(s, op, now, history_depth)
+    if op.op == 'get' then
+        local tuple = s.kv:get(op.key)
+        if not kv_is_live(tuple, now) then
+            return box.NULL, 'not_found'
+        end
+        return tuple
+    elseif op.op == 'create' then
+        local tuple, status = kv_atomic(create, s, op.key, op.value, op.content_type, now, op.expires_at)
+        return tuple, status
+    elseif op.op == 'update' then
+        local tuple, status = kv_atomic(update, s, op.key, op.value, op.content_type, now, op.expires_at, op.version, history_depth)
+        return tuple, status
+    elseif op.op == 'delete' then
+        return kv_atomic(soft_delete, s, op.key, now, op.version, history_depth)
+    end
+    return box.NULL, 'invalid_operation'
+end
+
+-- Выполняет пакет операций. В атомарном режиме все операции идут в одной
+-- транзакции, и первая же неудачная откатывает весь пакет. get отсутствующего
+-- ключа тоже неудачен: так get служит условием пакета на существование записи.
+-- Возвращает признак фиксации и список пар {кортеж, статус}.
+function kv_batch(ns, ops, now, atomic, history_depth)
+    local s = kv_spaces(ns)
+    if s == nil then
+        return box.NULL, 'namespace_not_found'
+    end
+    local results = {}
+
+    if atomic then
+        box.begin()
+    end
+
+    for _, op in ipairs(ops) do
+        local ok, tuple, status = pcall(kv_apply, s, op, now, history_depth)
+        if not ok then
+            log.error('kv_batch: %s', tuple)
+            tuple, status = box.NULL, 'error'
+        end
+        table.insert(results, { tuple or box.NULL, status or box.NULL })
+
+        if atomic and status ~= nil then
+            box.rollback()
+            return false, results
+        end
+    end
+
+    if atomic then
+        box.commit()
+    end
+
+    return true, results
+end
+
+local function kv_live_count(s, now)
+    local total = s.kv.index.deleted:count({ false })
+    for _, tuple in s.kv.index.expires:pairs({ 0 }, { iterator = 'GT' }) do
+        if tuple.expires_at > now then
+            break
+        end
+        if not tuple.is_deleted then
+            total = total - 1
+        end
+    end
+    return total
+end
+
+-- Количество записей для списков. Для живых записей считается индекс deleted,
+-- из него вычитаются записи с истёкшим TTL, которые reaper ещё не удалил.
+function kv_count(ns, include_deleted, now)
+    local s = kv_spaces(ns)
+    if s == nil then
+        return box.NULL, 'namespace_not_found'
+    end
+    if include_deleted then
+        return s.kv:len()
+    end
+    return kv_live_count(s, now)
+end
+
+-- Удаляет не более limit записей, чей expires_at наступил к моменту now, во всех namespace.
+-- 0 и nil в expires_at означают "без TTL" и в выборку не попадают.
+function kv_purge_expired(now, limit)
+    local purged = 0
+    for _, namespace in box.space.kv_namespaces:pairs() do
+        local s = kv_spaces(namespace.name)
+        local tuples = {}
+        for _, tuple in s.kv.index.expires:pairs({ 0 }, { iterator = 'GT' }) do
+            if tuple.expires_at > now or purged + #tuples >= limit then
+                break
+            end
+            table.insert(tuples, tuple)
+        end
+
+        box.begin()
+        for _, tuple in ipairs(tuples) do
+            s.kv:delete({ tuple.key })
+            kv_drop_history(s, tuple.key)
+            kv_log(s, tuple.key, 'expire', tuple.value, box.NULL, kv_version(tuple), now)
+        end
+        box.commit()
+
+        purged = purged + #tuples
+        if purged >= limit then
+            break
+        end
+    end
+    return purged
+end
+
+-- Удаляет из журналов всех namespace не более limit записей старше before.
+-- lsn растёт вместе со временем, поэтому обход по первичному индексу идёт от самых старых.
+function kv_changelog_trim(before, limit)
+    local trimmed = 0
+    for _, namespace in box.space.kv_namespaces:pairs() do
+        local s = kv_spaces(namespace.name)
+        local lsns = {}
+        for _, entry in s.changelog:pairs() do
+            if entry.timestamp >= before or trimmed + #lsns >= limit then
+                break
+            end
+            table.insert(lsns, entry.lsn)
+        end
+
+        box.begin()
+        for _, lsn in ipairs(lsns) do
+            s.changelog:delete({ lsn })
+        end
+        box.commit()
+
+        trimmed = trimmed + #lsns
+        if trimmed >= limit then
+            break
+        end
+    end
+    return trimmed
+end
+
+-- Статистика namespace: живые записи, все записи с удалёнными, объём данных,
+-- число сохранённых ревизий и записей журнала
+local function kv_namespace_stats(namespace, now)
+    local s = kv_spaces(namespace.name)
+    return {
+        namespace.name,
+        namespace.created_at,
+        kv_live_count(s, now),
+        s.kv:len(),
+        s.kv:bsize(),
+        s.history:len(),
+        s.changelog:len(),
+    }
+end
+
+-- Регистрирует namespace и создаёт его space. Space создаются до регистрации,
+-- поэтому прерванное создание можно просто повторить.
+function kv_namespace_create(name, now)
+    if box.space.kv_namespaces:get({ name }) ~= nil then
+        return box.NULL, 'already_exists'
+    end
+    kv_define_spaces(name)
+    local namespace = box.space.kv_namespaces:insert({ name, now })
+    return kv_namespace_stats(namespace, now)
+end
+
+function kv_namespace_get(name, now)
+    local namespace = box.space.kv_namespaces:get({ name })
+    if namespace == nil then
+        return box.NULL, 'not_found'
+    end
+    return kv_namespace_stats(namespace, now)
+end
+
+function kv_namespace_list(now)
+    local result = {}
+    for _, namespace in box.space.kv_namespaces:pairs() do
+        table.insert(result, kv_namespace_stats(namespace, now))
+    end
+    return result
+end
+
+-- Удаляет namespace вместе со всеми его записями, ревизиями и журналом.
+-- default удалить нельзя.
+function kv_namespace_drop(name, now)
+    if name == KV_DEFAULT_NAMESPACE then
+        return box.NULL, 'invalid_operation'
+    end
+    local namespace = box.space.kv_namespaces:get({ name })
+    if namespace == nil then
+        return box.NULL, 'not_found'
+    end
+
+    local stats = kv_namespace_stats(namespace, now)
+    box.space.kv_namespaces:delete({ name })
+    local names = kv_space_names(name)
+    box.space[names.kv]:drop()
+    box.space[names.history]:drop()
+    box.space[names.changelog]:drop()
+    return stats
+end
+
+-- Захватывает аренду name для owner на ttl миллисекунд. Свободная или истёкшая аренда
+-- получает новый fencing token. Повторный захват тем же владельцем продлевает аренду
+-- с прежним токеном. Занятая другим владельцем — 'locked' и текущая аренда третьим значением.
+function kv_lock_acquire(name, owner, ttl, now)
+    return kv_atomic(function()
+        local lock = box.space.kv_locks:get({ name })
+        if lock ~= nil and lock.expires_at > now then
+            if lock.owner ~= owner then
+                return box.NULL, 'locked', lock
+            end
+            return box.space.kv_locks:update({ name }, { { '=', 'expires_at', now + ttl } })
+        end
+        local token = box.sequence.kv_lock_token:next()
+        return box.space.kv_locks:replace({ name, owner, token, now, now + ttl })
+    end)
+end
+
+-- Находит живую аренду и проверяет владельца и токен: 'not_found' для свободной
+-- или истёкшей, 'not_owner' (с текущей арендой) при несовпадении
+local function lock_check(name, owner, token, now)
+    local lock = box.space.kv_locks:get({ name })
+    if lock == nil or lock.expires_at <= now then
+        return nil, 'not_found'
+    end
+    if lock.owner ~= owner or lock.token ~= token then
+        return nil, 'not_owner', lock
+    end
+    return lock
+end
+
+-- Продлевает аренду на ttl миллисекунд от now, токен не меняется
+function kv_lock_renew(name, owner, token, ttl, now)
+    return kv_atomic(function()
+        local lock, status, current = lock_check(name, owner, token, now)
+        if lock == nil then
+            return box.NULL, status, current
+        end
+        return box.space.kv_locks:update({ name }, { { '=', 'expires_at', now + ttl } })
+    end)
+end
+
+-- Освобождает аренду и возвращает её последнее состояние
+function kv_lock_release(name, owner, token, now)
+    return kv_atomic(function()
+        local lock, status, current = lock_check(name, owner, token, now)
+        if lock == nil then
+            return box.NULL, status, current
+        end
+        return box.space.kv_locks:delete({ name })
+    end)
+end
+
+-- Удаляет не более limit аренд, истёкших к моменту now
+function kv_lock_purge_expired(now, limit)
+    local names = {}
+    for _, lock in box.space.kv_locks.index.expires:pairs({ now }, { iterator = 'LE' }) do
+        if #names >= limit then
+            break
+        end
+        table.insert(names, lock.name)
+    end
+
+    box.begin()
+    for _, name in ipairs(names) do
+        box.space.kv_locks:delete({ name })
+    end
+    box.commit()
+
+    return #names
 end
 
 -- Всё готово, можно принимать соединения
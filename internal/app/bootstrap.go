@@ -9,17 +9,32 @@ import (
 	"time"
 
 	"kv-storage/internal/config"
+	"kv-storage/internal/events"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/metrics"
+	"kv-storage/internal/rbac"
 	"kv-storage/internal/repository"
 	"kv-storage/internal/service"
+	"kv-storage/internal/tracing"
+	"kv-storage/internal/transport/grpc"
 	"kv-storage/internal/transport/http"
+	"kv-storage/internal/transport/http/middleware"
+	"kv-storage/internal/transport/resp"
 )
 
 type Application struct {
-	router interfaces.Router
-	logger interfaces.Logger
-	config *config.Config
-	repo   interfaces.KVRepository
+	router     interfaces.Router
+	grpcServer interfaces.Router
+	respServer interfaces.Router
+	logger     interfaces.Logger
+	config     *config.Config
+	repo       interfaces.KVRepository
+	reaper     *service.ExpiryReaper
+	trimmer    *service.ChangelogTrimmer
+	lockReaper *service.LockReaper
+	broker     *events.Broker
+	// shutdownTracing отправляет оставшиеся span-ы при остановке
+	shutdownTracing func(context.Context) error
 }
 
 func Bootstrap() (*Application, error) {
@@ -27,35 +42,156 @@ func Bootstrap() (*Application, error) {
 
 	logger := NewLogger(cfg.App.Environment)
 
-	repo, err := repository.NewTarantoolRepository(cfg, logger)
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, cfg.App.Name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize repository: %w", err)
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
 	}
+	if cfg.Tracing.Enabled {
+		logger.Info("Tracing enabled", "exporter", cfg.Tracing.Exporter, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
+
+	// Недоступный при старте Tarantool не мешает запуску: пулы экземпляров подключатся
+	// в фоне, а до тех пор /readyz сообщает о неготовности
+	shards, err := repository.NewShards(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tarantool shards: %w", err)
+	}
+
+	// При выключенных метриках m равен nil, и все точки учёта ничего не делают
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+		m.RegisterPool(shards)
+		logger.Info("Metrics enabled")
+	}
+
+	var repo interfaces.KVRepository
+	var namespaceRepo interfaces.NamespaceRepository
+	var lockRepo interfaces.LockRepository
+	if len(shards) == 1 {
+		repo = repository.NewTarantoolRepository(shards[0].Cluster, cfg, m, logger)
+		namespaceRepo = repository.NewTarantoolNamespaceRepository(shards[0].Cluster, logger)
+		lockRepo = repository.NewTarantoolLockRepository(shards[0].Cluster, logger)
+	} else {
+		names := make([]string, len(shards))
+		shardRepos := make([]interfaces.KVRepository, len(shards))
+		shardNamespaces := make([]interfaces.NamespaceRepository, len(shards))
+		shardLocks := make([]interfaces.LockRepository, len(shards))
+		for i, shard := range shards {
+			names[i] = shard.Name
+			shardRepos[i] = repository.NewTarantoolRepository(shard.Cluster, cfg, m, logger)
+			shardNamespaces[i] = repository.NewTarantoolNamespaceRepository(shard.Cluster, logger)
+			shardLocks[i] = repository.NewTarantoolLockRepository(shard.Cluster, logger)
+		}
+		repo = repository.NewShardedRepository(names, shardRepos, logger)
+		namespaceRepo = repository.NewShardedNamespaceRepository(shardNamespaces)
+		lockRepo = repository.NewShardedLockRepository(names, shardLocks)
+		logger.Info("Sharding enabled", "shards", names)
+	}
+
+	broker := events.NewBroker(cfg.Events.BufferSize, logger)
+
+	kvService := service.NewKVService(repo, logger, broker)
 
-	kvService := service.NewKVService(repo, logger)
+	lockService := service.NewLockService(lockRepo, logger, cfg.Locks.DefaultTTL, cfg.Locks.MaxTTL)
 
-	router := http.NewRouter(cfg, logger, kvService)
+	namespaceService := service.NewNamespaceService(namespaceRepo, logger)
+
+	healthService := service.NewHealthService(repository.NewHealthCheckers(shards), cfg.Health.CheckTimeout, logger)
+
+	var authenticator *middleware.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = middleware.NewAuthenticator(cfg.Auth, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize authentication: %w", err)
+		}
+	}
+
+	// Правила RBAC сопоставляются с аутентифицированным клиентом, поэтому без аутентификации не работают
+	var policy *rbac.Policy
+	if cfg.RBAC.Enabled {
+		if !cfg.Auth.Enabled {
+			return nil, fmt.Errorf("rbac requires auth to be enabled")
+		}
+		policy, err = rbac.NewPolicy(cfg.RBAC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rbac policy: %w", err)
+		}
+		logger.Info("RBAC enabled", "rules", len(cfg.RBAC.Rules))
+	}
+
+	router := http.NewRouter(cfg, logger, kvService, lockService, namespaceService, healthService, broker, authenticator, policy, m)
+
+	var grpcServer interfaces.Router
+	if cfg.GRPCServer.Port != "" {
+		grpcServer = grpc.NewServer(cfg, logger, kvService, authenticator, policy)
+	}
+
+	var respServer interfaces.Router
+	if cfg.RESP.Port != "" {
+		respServer = resp.NewServer(cfg, logger, kvService, authenticator, policy)
+	}
+
+	reaper := service.NewExpiryReaper(repo, logger, cfg.Expiry.ReapInterval, cfg.Expiry.BatchSize)
+
+	trimmer := service.NewChangelogTrimmer(repo, logger,
+		cfg.Changelog.Retention,
+		cfg.Changelog.TrimInterval,
+		cfg.Changelog.BatchSize,
+	)
+
+	lockReaper := service.NewLockReaper(lockRepo, logger, cfg.Locks.ReapInterval, cfg.Locks.BatchSize)
 
 	return &Application{
-		router: router,
-		logger: logger,
-		config: cfg,
-		repo:   repo,
+		router:     router,
+		grpcServer: grpcServer,
+		respServer: respServer,
+		logger:     logger,
+		config:     cfg,
+		repo:       repo,
+		reaper:     reaper,
+		trimmer:    trimmer,
+		lockReaper: lockReaper,
+		broker:     broker,
+
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
 func (a *Application) Run() {
 	a.logger.Info("Starting KV Storage application",
 		"port", a.config.HTTPServer.Port,
+		"grpc_port", a.config.GRPCServer.Port,
+		"resp_port", a.config.RESP.Port,
 		"environment", a.config.App.Environment,
 	)
 
+	a.reaper.Start()
+	a.trimmer.Start()
+	a.lockReaper.Start()
+
 	go func() {
 		if err := a.router.Run(":" + a.config.HTTPServer.Port); err != nil {
 			a.logger.Error("HTTP server error", "error", err)
 		}
 	}()
 
+	if a.grpcServer != nil {
+		go func() {
+			if err := a.grpcServer.Run(":" + a.config.GRPCServer.Port); err != nil {
+				a.logger.Error("gRPC server error", "error", err)
+			}
+		}()
+	}
+
+	if a.respServer != nil {
+		go func() {
+			if err := a.respServer.Run(":" + a.config.RESP.Port); err != nil {
+				a.logger.Error("RESP server error", "error", err)
+			}
+		}()
+	}
+
 	a.waitForShutdown()
 }
 
@@ -69,13 +205,37 @@ func (a *Application) waitForShutdown() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Потоки изменений не завершаются сами, их нужно закрыть до остановки сервера
+	a.broker.Close()
+
 	if err := a.router.Shutdown(ctx); err != nil {
 		a.logger.Error("Error during server shutdown", "error", err)
 	}
 
+	if a.grpcServer != nil {
+		if err := a.grpcServer.Shutdown(ctx); err != nil {
+			a.logger.Error("Error during gRPC server shutdown", "error", err)
+		}
+	}
+
+	if a.respServer != nil {
+		if err := a.respServer.Shutdown(ctx); err != nil {
+			a.logger.Error("Error during RESP server shutdown", "error", err)
+		}
+	}
+
+	a.reaper.Stop()
+	a.trimmer.Stop()
+	a.lockReaper.Stop()
+
+	// Пулы соединений общие для всех репозиториев и закрывается вместе с KV-репозиторием
 	if err := a.repo.Close(); err != nil {
 		a.logger.Error("Error closing repository", "error", err)
 	}
 
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.logger.Error("Error flushing traces", "error", err)
+	}
+
 	a.logger.Info("Application shutdown completed")
 }
@@ -13,7 +13,19 @@ import (
 type Config struct {
 	App        AppConfig        `yaml:"app"`
 	HTTPServer HTTPServerConfig `yaml:"http_server"`
+	GRPCServer GRPCServerConfig `yaml:"grpc_server"`
+	RESP       RESPConfig       `yaml:"resp"`
 	Tarantool  TarantoolConfig  `yaml:"tarantool"`
+	Expiry     ExpiryConfig     `yaml:"expiry"`
+	Events     EventsConfig     `yaml:"events"`
+	Changelog  ChangelogConfig  `yaml:"changelog"`
+	History    HistoryConfig    `yaml:"history"`
+	Locks      LocksConfig      `yaml:"locks"`
+	Auth       AuthConfig       `yaml:"auth"`
+	RBAC       RBACConfig       `yaml:"rbac"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Health     HealthConfig     `yaml:"health"`
 }
 
 type AppConfig struct {
@@ -21,18 +33,164 @@ type AppConfig struct {
 	Environment string `yaml:"environment"`
 }
 
+// HTTPServerConfig задаёт срок обработки запросов RequestTimeout; RouteTimeouts
+// переопределяет его для маршрутов вида "POST /api/v1/kv/_batch", 0 снимает срок
 type HTTPServerConfig struct {
-	Port         string        `yaml:"port"`
-	ReadTimeout  time.Duration `yaml:"read_timeout"`
-	WriteTimeout time.Duration `yaml:"write_timeout"`
+	Port           string                   `yaml:"port"`
+	ReadTimeout    time.Duration            `yaml:"read_timeout"`
+	WriteTimeout   time.Duration            `yaml:"write_timeout"`
+	RequestTimeout time.Duration            `yaml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"`
 }
 
+// GRPCServerConfig задаёт порт gRPC-транспорта; пустой порт отключает его
+type GRPCServerConfig struct {
+	Port string `yaml:"port"`
+}
+
+// RESPConfig задаёт порт listener-а протокола Redis; пустой порт отключает его
+type RESPConfig struct {
+	Port string `yaml:"port"`
+}
+
+// TarantoolConfig задаёт один экземпляр через Host и Port либо набор реплицируемых
+// экземпляров в Instances; Shards делит ключи между несколькими такими наборами.
+// Учётные данные и пул общие для всех экземпляров.
 type TarantoolConfig struct {
-	Host     string        `yaml:"host"`
-	Port     int           `yaml:"port"`
-	Username string        `yaml:"username"`
-	Password string        `yaml:"password"`
-	Timeout  time.Duration `yaml:"timeout"`
+	Host      string           `yaml:"host"`
+	Port      int              `yaml:"port"`
+	Instances []InstanceConfig `yaml:"instances"`
+	Shards    []ShardConfig    `yaml:"shards"`
+	Username  string           `yaml:"username"`
+	Password  string           `yaml:"password"`
+	Timeout   time.Duration    `yaml:"timeout"`
+	Pool      PoolConfig       `yaml:"pool"`
+}
+
+// InstanceConfig — экземпляр Tarantool. Role ("master" или "replica") используется,
+// пока роль не определена по box.info.ro; дальше запросы направляются по фактической роли.
+type InstanceConfig struct {
+	Name string `yaml:"name"`
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	Role string `yaml:"role"`
+}
+
+// ShardConfig — шард: один экземпляр (Host и Port) или мастер с репликами (Instances).
+// Name определяет положение шарда на кольце хеширования, поэтому после записи данных
+// его нельзя менять.
+type ShardConfig struct {
+	Name      string           `yaml:"name"`
+	Host      string           `yaml:"host"`
+	Port      int              `yaml:"port"`
+	Instances []InstanceConfig `yaml:"instances"`
+}
+
+// PoolConfig задаёт размер пула соединений: MinSize соединений держатся открытыми
+// и восстанавливаются после обрыва, сверх них пул открывает соединения по требованию
+// до MaxSize и закрывает простаивающие дольше IdleTimeout. Простаивающие соединения
+// проверяются ping-ом раз в HealthCheckInterval.
+type PoolConfig struct {
+	MinSize             int           `yaml:"min_size"`
+	MaxSize             int           `yaml:"max_size"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout"`
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"`
+}
+
+type ExpiryConfig struct {
+	ReapInterval time.Duration `yaml:"reap_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+type EventsConfig struct {
+	BufferSize int `yaml:"buffer_size"`
+}
+
+// ChangelogConfig задаёт срок хранения журнала изменений; 0 — журнал не очищается
+type ChangelogConfig struct {
+	Retention    time.Duration `yaml:"retention"`
+	TrimInterval time.Duration `yaml:"trim_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+// HistoryConfig задаёт число предыдущих ревизий, хранимых для каждого ключа
+type HistoryConfig struct {
+	Depth int `yaml:"depth"`
+}
+
+// LocksConfig задаёт TTL аренд по умолчанию и максимальный, а также
+// период удаления истёкших аренд
+type LocksConfig struct {
+	DefaultTTL   time.Duration `yaml:"default_ttl"`
+	MaxTTL       time.Duration `yaml:"max_ttl"`
+	ReapInterval time.Duration `yaml:"reap_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+}
+
+// AuthConfig включает аутентификацию запросов к /api/v1 по статическим
+// API-ключам и JWT; пробы /livez, /readyz, /health и /swagger остаются открытыми
+type AuthConfig struct {
+	Enabled bool           `yaml:"enabled"`
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	JWT     JWTConfig      `yaml:"jwt"`
+}
+
+type APIKeyConfig struct {
+	Name  string   `yaml:"name"`
+	Key   string   `yaml:"key"`
+	Roles []string `yaml:"roles"`
+}
+
+// JWTConfig задаёт ключи проверки подписи: HS256Secret для HS256 и JWKS-файл
+// с RSA (RS256) и симметричными (HS256) ключами. Пустые Issuer и Audience не проверяются.
+type JWTConfig struct {
+	HS256Secret string        `yaml:"hs256_secret"`
+	JWKSFile    string        `yaml:"jwks_file"`
+	Issuer      string        `yaml:"issuer"`
+	Audience    string        `yaml:"audience"`
+	RolesClaim  string        `yaml:"roles_claim"`
+	Leeway      time.Duration `yaml:"leeway"`
+}
+
+// RBACConfig задаёт политику доступа к /api/v1 для аутентифицированных клиентов.
+// Правила только разрешают; запрос, не подходящий ни под одно правило, отклоняется.
+type RBACConfig struct {
+	Enabled bool       `yaml:"enabled"`
+	Rules   []RBACRule `yaml:"rules"`
+}
+
+// RBACRule разрешает действия Actions клиентам из Principals (по имени) или с одной
+// из ролей Roles над ключами, подходящими под шаблоны Keys, в namespace Namespaces.
+// В шаблонах "*" — любая последовательность символов, "?" — один символ;
+// пустые Principals и Roles означают любого клиента, пустые Namespaces и Keys — "*".
+type RBACRule struct {
+	Principals []string `yaml:"principals"`
+	Roles      []string `yaml:"roles"`
+	Actions    []string `yaml:"actions"`
+	Namespaces []string `yaml:"namespaces"`
+	Keys       []string `yaml:"keys"`
+}
+
+// MetricsConfig включает экспорт метрик Prometheus по пути Path HTTP-сервера
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+// TracingConfig задаёт экспорт трасс OpenTelemetry: Exporter "otlp" отправляет
+// их по gRPC на Endpoint, "stdout" печатает в стандартный вывод.
+// SampleRatio — доля трасс, начатых этим сервисом; решение вызывающего из traceparent соблюдается.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// HealthConfig задаёт предельное время каждой проверки /readyz
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -53,12 +211,55 @@ func Load(configPath string) (*Config, error) {
 	config.HTTPServer.Port = getEnv("HTTP_PORT", config.HTTPServer.Port)
 	config.HTTPServer.ReadTimeout = getEnvDuration("HTTP_READ_TIMEOUT", config.HTTPServer.ReadTimeout)
 	config.HTTPServer.WriteTimeout = getEnvDuration("HTTP_WRITE_TIMEOUT", config.HTTPServer.WriteTimeout)
+	config.HTTPServer.RequestTimeout = getEnvDuration("HTTP_REQUEST_TIMEOUT", config.HTTPServer.RequestTimeout)
+
+	config.GRPCServer.Port = getEnv("GRPC_PORT", config.GRPCServer.Port)
+	config.RESP.Port = getEnv("RESP_PORT", config.RESP.Port)
 
 	config.Tarantool.Host = getEnv("TARANTOOL_HOST", config.Tarantool.Host)
 	config.Tarantool.Port = getEnvInt("TARANTOOL_PORT", config.Tarantool.Port)
 	config.Tarantool.Username = getEnv("TARANTOOL_USERNAME", config.Tarantool.Username)
 	config.Tarantool.Password = getEnv("TARANTOOL_PASSWORD", config.Tarantool.Password)
 	config.Tarantool.Timeout = getEnvDuration("TARANTOOL_TIMEOUT", config.Tarantool.Timeout)
+	config.Tarantool.Pool.MinSize = getEnvInt("TARANTOOL_POOL_MIN_SIZE", config.Tarantool.Pool.MinSize)
+	config.Tarantool.Pool.MaxSize = getEnvInt("TARANTOOL_POOL_MAX_SIZE", config.Tarantool.Pool.MaxSize)
+	config.Tarantool.Pool.IdleTimeout = getEnvDuration("TARANTOOL_POOL_IDLE_TIMEOUT", config.Tarantool.Pool.IdleTimeout)
+	config.Tarantool.Pool.HealthCheckInterval = getEnvDuration("TARANTOOL_POOL_HEALTH_CHECK_INTERVAL", config.Tarantool.Pool.HealthCheckInterval)
+
+	config.Expiry.ReapInterval = getEnvDuration("EXPIRY_REAP_INTERVAL", config.Expiry.ReapInterval)
+	config.Expiry.BatchSize = getEnvInt("EXPIRY_BATCH_SIZE", config.Expiry.BatchSize)
+
+	config.Events.BufferSize = getEnvInt("EVENTS_BUFFER_SIZE", config.Events.BufferSize)
+
+	config.Changelog.Retention = getEnvDuration("CHANGELOG_RETENTION", config.Changelog.Retention)
+	config.Changelog.TrimInterval = getEnvDuration("CHANGELOG_TRIM_INTERVAL", config.Changelog.TrimInterval)
+	config.Changelog.BatchSize = getEnvInt("CHANGELOG_BATCH_SIZE", config.Changelog.BatchSize)
+
+	config.History.Depth = getEnvInt("HISTORY_DEPTH", config.History.Depth)
+
+	config.Locks.DefaultTTL = getEnvDuration("LOCKS_DEFAULT_TTL", config.Locks.DefaultTTL)
+	config.Locks.MaxTTL = getEnvDuration("LOCKS_MAX_TTL", config.Locks.MaxTTL)
+	config.Locks.ReapInterval = getEnvDuration("LOCKS_REAP_INTERVAL", config.Locks.ReapInterval)
+	config.Locks.BatchSize = getEnvInt("LOCKS_BATCH_SIZE", config.Locks.BatchSize)
+
+	config.Auth.Enabled = getEnvBool("AUTH_ENABLED", config.Auth.Enabled)
+	config.Auth.JWT.HS256Secret = getEnv("AUTH_JWT_HS256_SECRET", config.Auth.JWT.HS256Secret)
+	config.Auth.JWT.JWKSFile = getEnv("AUTH_JWT_JWKS_FILE", config.Auth.JWT.JWKSFile)
+	config.Auth.JWT.Issuer = getEnv("AUTH_JWT_ISSUER", config.Auth.JWT.Issuer)
+	config.Auth.JWT.Audience = getEnv("AUTH_JWT_AUDIENCE", config.Auth.JWT.Audience)
+
+	config.RBAC.Enabled = getEnvBool("RBAC_ENABLED", config.RBAC.Enabled)
+
+	config.Metrics.Enabled = getEnvBool("METRICS_ENABLED", config.Metrics.Enabled)
+	config.Metrics.Path = getEnv("METRICS_PATH", config.Metrics.Path)
+
+	config.Tracing.Enabled = getEnvBool("TRACING_ENABLED", config.Tracing.Enabled)
+	config.Tracing.Exporter = getEnv("TRACING_EXPORTER", config.Tracing.Exporter)
+	config.Tracing.Endpoint = getEnv("TRACING_ENDPOINT", config.Tracing.Endpoint)
+	config.Tracing.Insecure = getEnvBool("TRACING_INSECURE", config.Tracing.Insecure)
+	config.Tracing.SampleRatio = getEnvFloat("TRACING_SAMPLE_RATIO", config.Tracing.SampleRatio)
+
+	config.Health.CheckTimeout = getEnvDuration("HEALTH_CHECK_TIMEOUT", config.Health.CheckTimeout)
 
 	return &config, nil
 }
@@ -87,6 +288,24 @@ func getEnvInt(key string, fallback int) int {
 	return fallback
 }
 
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
 func getEnvDuration(key string, fallback time.Duration) time.Duration {
 	if value, ok := os.LookupEnv(key); ok {
 		if d, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,15 @@
+package domain
+
+// Способы аутентификации Principal
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// Principal — аутентифицированный клиент запроса
+type Principal struct {
+	// Name — имя API-ключа из конфигурации или claim sub JWT
+	Name   string   `json:"name"`
+	Method string   `json:"method"`
+	Roles  []string `json:"roles,omitempty"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "context"
+
+// Consistency — требование к чтению: eventual допускает чтение с реплики,
+// которая может отставать от мастера, strong читает с мастера
+type Consistency string
+
+const (
+	ConsistencyEventual Consistency = "eventual"
+	ConsistencyStrong   Consistency = "strong"
+)
+
+type consistencyKey struct{}
+
+func ValidConsistency(c Consistency) bool {
+	return c == ConsistencyEventual || c == ConsistencyStrong
+}
+
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// ConsistencyFromContext возвращает требование запроса; по умолчанию eventual
+func ConsistencyFromContext(ctx context.Context) Consistency {
+	if c, ok := ctx.Value(consistencyKey{}).(Consistency); ok {
+		return c
+	}
+	return ConsistencyEventual
+}
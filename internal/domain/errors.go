@@ -11,4 +11,31 @@ var (
 	ErrValidationError  = errors.New("validation error")
 	ErrNotDeleted       = errors.New("value haven`t deleted")
 	ErrKeyAlreadyExists = errors.New("key already exists")
+	ErrInvalidTTL       = errors.New("invalid ttl")
+	ErrVersionConflict  = errors.New("version conflict")
+	ErrBatchAborted     = errors.New("batch aborted")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrEventsExpired    = errors.New("requested events are no longer available")
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrNotNumeric       = errors.New("value is not a number")
+	ErrValueMismatch    = errors.New("value does not match expected")
+	ErrLockHeld         = errors.New("lock is held by another owner")
+	ErrLockNotFound     = errors.New("lock not found")
+	ErrNotLockOwner     = errors.New("lock is not owned by caller")
+
+	ErrInvalidNamespace   = errors.New("invalid namespace")
+	ErrNamespaceNotFound  = errors.New("namespace not found")
+	ErrNamespaceExists    = errors.New("namespace already exists")
+	ErrNamespaceProtected = errors.New("default namespace cannot be dropped")
+
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+
+	ErrForbidden = errors.New("access denied")
+
+	// Операция затрагивает ключи нескольких шардов и не может быть выполнена целиком
+	ErrCrossShard = errors.New("operation spans multiple shards")
+
+	// Запрос прерван: истёк его срок или клиент отключился
+	ErrTimeout  = errors.New("request timed out")
+	ErrCanceled = errors.New("request canceled")
 )
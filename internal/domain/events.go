@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+type ChangeOp string
+
+const (
+	ChangeOpCreate     ChangeOp = "create"
+	ChangeOpUpdate     ChangeOp = "update"
+	ChangeOpDelete     ChangeOp = "delete"
+	ChangeOpSoftDelete ChangeOp = "soft_delete"
+	ChangeOpRestore    ChangeOp = "restore"
+	// ChangeOpExpire пишется в журнал, когда reaper удаляет запись с истёкшим TTL
+	ChangeOpExpire ChangeOp = "expire"
+)
+
+// ChangeEvent описывает одно изменение записи
+type ChangeEvent struct {
+	// ID назначается брокером и растёт монотонно в пределах процесса
+	ID        uint64      `json:"id"`
+	Namespace string      `json:"namespace"`
+	Key       string      `json:"key"`
+	Op        ChangeOp    `json:"op"`
+	OldValue  interface{} `json:"old_value,omitempty"`
+	NewValue  interface{} `json:"new_value,omitempty"`
+	Version   uint64      `json:"version"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+func (e ChangeEvent) Matches(namespace, prefix string) bool {
+	return e.Namespace == namespace && strings.HasPrefix(e.Key, prefix)
+}
+
+// ChangeLogEntry — запись журнала изменений kv_changelog
+type ChangeLogEntry struct {
+	// LSN выдаётся Tarantool и растёт монотонно, переживая перезапуски
+	LSN uint64 `json:"lsn"`
+	// Shard — имя шарда записи; при шардировании LSN уникален только в пределах шарда
+	Shard     string      `json:"shard,omitempty"`
+	Key       string      `json:"key"`
+	Op        ChangeOp    `json:"op"`
+	OldValue  interface{} `json:"old_value,omitempty"`
+	NewValue  interface{} `json:"new_value,omitempty"`
+	Version   uint64      `json:"version"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// ChangesCursor — позиция чтения журнала: LSN последней обработанной записи каждого
+// шарда в порядке конфигурации. Без шардирования позиция состоит из одного LSN,
+// пустая позиция означает начало журнала.
+type ChangesCursor []uint64
+
+type ChangesRequest struct {
+	// Since — LSN последней обработанной записи; возвращаются записи строго после него
+	Since uint64 `json:"since"`
+	// Cursor — next_cursor предыдущего ответа; имеет приоритет над Since
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit"`
+}
+
+type ChangesResponse struct {
+	Items []ChangeLogEntry `json:"items"`
+	// NextSince передаётся в ?since= следующего запроса; при шардировании отсутствует
+	NextSince uint64 `json:"next_since,omitempty"`
+	// NextCursor передаётся в ?cursor= следующего запроса
+	NextCursor string `json:"next_cursor"`
+}
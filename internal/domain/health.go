@@ -0,0 +1,26 @@
+package domain
+
+// HealthStatus — результат проверки готовности. Warn не снимает сервис
+// с балансировки, Fail означает, что обслуживать запросы он не может.
+type HealthStatus string
+
+const (
+	HealthOK   HealthStatus = "ok"
+	HealthWarn HealthStatus = "warn"
+	HealthFail HealthStatus = "fail"
+)
+
+// HealthCheck — результат одной проверки в ответе /readyz
+type HealthCheck struct {
+	Name      string                 `json:"name"`
+	Status    HealthStatus           `json:"status"`
+	LatencyMs float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// HealthReport — сводный ответ /readyz; Status — худший из статусов проверок
+type HealthReport struct {
+	Status HealthStatus  `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
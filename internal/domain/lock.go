@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Lock — аренда (распределённая блокировка). Token — fencing token: растёт с каждым
+// новым захватом, и ресурс, защищённый блокировкой, должен отклонять запросы со
+// значением меньше уже виденного.
+type Lock struct {
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner"`
+	Token      uint64    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// AcquireLockRequest — захват аренды. TTL в секундах, 0 — TTL по умолчанию из конфигурации.
+type AcquireLockRequest struct {
+	Owner string `json:"owner" binding:"required"`
+	TTL   int64  `json:"ttl,omitempty"`
+}
+
+// RenewLockRequest продлевает аренду на TTL секунд от текущего момента
+type RenewLockRequest struct {
+	Owner string `json:"owner" binding:"required"`
+	Token uint64 `json:"token" binding:"required"`
+	TTL   int64  `json:"ttl,omitempty"`
+}
+
+type ReleaseLockRequest struct {
+	Owner string `json:"owner" binding:"required"`
+	Token uint64 `json:"token" binding:"required"`
+}
@@ -1,23 +1,73 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
+// KV — запись хранилища. Value содержит JSON-значение (объект, массив, строку,
+// число или bool) либо []byte, если ContentType равен application/octet-stream.
 type KV struct {
-	Key       string     `json:"key"`
-	Value     string     `json:"value"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
-	IsDeleted bool       `json:"is_deleted,omitempty"`
+	Key         string      `json:"key"`
+	Value       interface{} `json:"value" swaggertype:"object"`
+	ContentType string      `json:"content_type"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
+	IsDeleted   bool        `json:"is_deleted,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	Version     uint64      `json:"version"`
 }
 
+// NoExpiry в ExpiresAt обновления снимает срок жизни записи: в Tarantool
+// expires_at = 0 означает запись без TTL
+var NoExpiry = time.Unix(0, 0)
+
+// IsExpired сообщает, истёк ли TTL записи к моменту now.
+func (kv *KV) IsExpired(now time.Time) bool {
+	return kv.ExpiresAt != nil && !kv.ExpiresAt.Equal(NoExpiry) && !kv.ExpiresAt.After(now)
+}
+
+// CreateKVRequest принимает любое JSON-значение; для application/octet-stream
+// Value передаётся строкой в base64
 type CreateKVRequest struct {
-	Key   string `json:"key" binding:"required"`
-	Value string `json:"value" binding:"required"`
+	Key         string      `json:"key" binding:"required"`
+	Value       interface{} `json:"value" swaggertype:"object"`
+	ContentType string      `json:"content_type,omitempty"`
+	TTL         int64       `json:"ttl,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
 }
 
 type UpdateKVRequest struct {
-	Value string `json:"value" binding:"required"`
+	Value       interface{} `json:"value" swaggertype:"object"`
+	ContentType string      `json:"content_type,omitempty"`
+	TTL         int64       `json:"ttl,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	// Persist снимает срок жизни записи; без него, ttl и expires_at прежний срок сохраняется
+	Persist bool `json:"persist,omitempty"`
+	// ExpectedVersion заполняется из заголовка If-Match; 0 — обновление без проверки версии
+	ExpectedVersion uint64 `json:"-"`
+}
+
+// CASRequest заменяет значение на NewValue, если текущее равно ExpectedValue
+// и/или версия равна ExpectedVersion; хотя бы одно условие обязательно
+type CASRequest struct {
+	ExpectedValue   interface{} `json:"expected_value,omitempty" swaggertype:"object"`
+	ExpectedVersion uint64      `json:"expected_version,omitempty"`
+	NewValue        interface{} `json:"new_value" swaggertype:"object"`
+	// ContentType относится и к ExpectedValue, и к NewValue
+	ContentType string     `json:"content_type,omitempty"`
+	TTL         int64      `json:"ttl,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// IncrementRequest — параметры атомарного изменения счётчика.
+// Delta по умолчанию 1; Initial, TTL и ExpiresAt применяются только при создании ключа.
+type IncrementRequest struct {
+	Delta     *int64     `json:"delta,omitempty"`
+	Initial   int64      `json:"initial,omitempty"`
+	TTL       int64      `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type DeleteKVRequest struct {
@@ -25,10 +75,13 @@ type DeleteKVRequest struct {
 }
 
 type ListKVResponse struct {
-	Items  []*KV `json:"items"`
-	Total  int   `json:"total"`
-	Limit  int   `json:"limit"`
-	Offset int   `json:"offset"`
+	Items []*KV `json:"items"`
+	// Total отсутствует, если подсчёт отключён через ?count=false
+	Total  *int `json:"total,omitempty"`
+	Limit  int  `json:"limit"`
+	Offset int  `json:"offset"`
+	// Cursor передаётся в ?cursor= для получения следующей страницы; пуст на последней странице
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type RestoreKVRequest struct {
@@ -36,13 +89,123 @@ type RestoreKVRequest struct {
 }
 
 type KVResponse struct {
-	Key       string    `json:"key"`
-	Value     string    `json:"value"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Key       string      `json:"key"`
+	Value     interface{} `json:"value"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
 
 type ListKVRequest struct {
-	Limit  int `json:"limit" validate:"min=1,max=100"`
-	Offset int `json:"offset" validate:"min=0"`
+	Limit     int    `json:"limit" validate:"min=1,max=100"`
+	Offset    int    `json:"offset" validate:"min=0"`
+	Cursor    string `json:"cursor"`
+	SkipCount bool   `json:"skip_count"`
+}
+
+// ListOptions — параметры выборки страницы в репозитории
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// After — ключ, после которого продолжается выборка; если задан, Offset не используется
+	After string
+	// SkipCount отключает подсчёт общего числа записей на горячих путях
+	SkipCount bool
+}
+
+// ScanOptions — параметры обхода диапазона ключей по первичному индексу
+type ScanOptions struct {
+	// Prefix ограничивает выборку ключами с этим префиксом
+	Prefix string
+	// From — нижняя граница диапазона включительно
+	From string
+	// To — верхняя граница диапазона, не включая её
+	To    string
+	Limit int
+	// After — ключ, после которого продолжается выборка
+	After          string
+	IncludeDeleted bool
+}
+
+// InRange сообщает, попадает ли ключ в границы префикса и диапазона
+func (o ScanOptions) InRange(key string) bool {
+	if o.Prefix != "" && !strings.HasPrefix(key, o.Prefix) {
+		return false
+	}
+	if o.From != "" && key < o.From {
+		return false
+	}
+	if o.To != "" && key >= o.To {
+		return false
+	}
+	return true
+}
+
+type ScanKVRequest struct {
+	Prefix         string `json:"prefix"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Limit          int    `json:"limit"`
+	Cursor         string `json:"cursor"`
+	IncludeDeleted bool   `json:"include_deleted"`
+}
+
+// ListPage — страница, возвращаемая репозиторием
+type ListPage struct {
+	Items []*KV
+	// Total — общее число записей; не заполняется при ListOptions.SkipCount
+	Total int
+	// NextKey — ключ для продолжения выборки; пуст, если записей больше нет
+	NextKey string
+}
+
+type HistoryResponse struct {
+	Key string `json:"key"`
+	// Items — текущее состояние и сохранённые ревизии, от новых к старым
+	Items []*KV `json:"items"`
+}
+
+type BatchOp string
+
+const (
+	BatchOpGet    BatchOp = "get"
+	BatchOpCreate BatchOp = "create"
+	BatchOpUpdate BatchOp = "update"
+	BatchOpDelete BatchOp = "delete"
+)
+
+type BatchMode string
+
+const (
+	// BatchModeAtomic выполняет пакет в одной транзакции: либо все операции, либо ни одной
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort выполняет операции независимо и возвращает результат по каждой
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+type BatchOperation struct {
+	Op          BatchOp     `json:"op" binding:"required"`
+	Key         string      `json:"key" binding:"required"`
+	Value       interface{} `json:"value,omitempty" swaggertype:"object"`
+	ContentType string      `json:"content_type,omitempty"`
+	TTL         int64       `json:"ttl,omitempty"`
+	ExpiresAt   *time.Time  `json:"expires_at,omitempty"`
+	// Version — ожидаемая версия для update и delete, аналог If-Match
+	Version uint64 `json:"version,omitempty"`
+}
+
+type BatchRequest struct {
+	Mode       BatchMode        `json:"mode"`
+	Operations []BatchOperation `json:"operations" binding:"required"`
+}
+
+type BatchItemResult struct {
+	Op    BatchOp `json:"op"`
+	Key   string  `json:"key"`
+	KV    *KV     `json:"kv,omitempty"`
+	Error string  `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode    BatchMode         `json:"mode"`
+	Results []BatchItemResult `json:"results"`
 }
@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+)
+
+// DefaultNamespace — namespace маршрутов /api/v1/kv без явного namespace
+const DefaultNamespace = "default"
+
+// Имя namespace входит в имена space Tarantool, поэтому допускаются только
+// строчные латинские буквы, цифры и подчёркивание
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,47}$`)
+
+// Namespace — изолированное пространство ключей со статистикой
+type Namespace struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// Keys — живые записи, Total — все записи, включая удалённые и ещё не удалённые reaper'ом
+	Keys             int   `json:"keys"`
+	Total            int   `json:"total"`
+	Bytes            int64 `json:"bytes"`
+	Revisions        int   `json:"revisions"`
+	ChangelogEntries int   `json:"changelog_entries"`
+}
+
+type CreateNamespaceRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type ListNamespacesResponse struct {
+	Items []*Namespace `json:"items"`
+}
+
+func ValidNamespace(name string) bool {
+	return namespacePattern.MatchString(name)
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// PoolStats — состояние пула соединений с Tarantool. Size — открытые соединения,
+// не больше MaxSize. Счётчики ожиданий и неудачных подключений накапливаются
+// с момента создания пула.
+type PoolStats struct {
+	Size         int
+	MaxSize      int
+	Idle         int
+	InUse        int
+	WaitCount    uint64
+	WaitDuration time.Duration
+	Timeouts     uint64
+	DialFailures uint64
+}
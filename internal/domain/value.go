@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// ContentTypeJSON — значение хранится как нативный MessagePack: объект, массив, строка, число или bool
+	ContentTypeJSON = "application/json"
+	// ContentTypeBinary — значение хранится как MessagePack bin; в JSON-ответах передаётся в base64
+	ContentTypeBinary = "application/octet-stream"
+)
+
+// NormalizeValue приводит значение из запроса к виду, который хранится в Tarantool.
+// Для JSON числа из json.Number становятся int64 или float64, для бинарных данных
+// строка декодируется из base64. Пустое значение считается ошибкой.
+func NormalizeValue(value interface{}, contentType string) (interface{}, string, error) {
+	switch contentType {
+	case "", ContentTypeJSON:
+		normalized, err := normalizeJSON(value)
+		if err != nil {
+			return nil, "", ErrInvalidValue
+		}
+		if IsEmptyValue(normalized) {
+			return nil, "", ErrInvalidValue
+		}
+		return normalized, ContentTypeJSON, nil
+	case ContentTypeBinary:
+		var data []byte
+		switch v := value.(type) {
+		case []byte:
+			data = v
+		case string:
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, "", ErrInvalidValue
+			}
+			data = decoded
+		default:
+			return nil, "", ErrInvalidValue
+		}
+		if len(data) == 0 {
+			return nil, "", ErrInvalidValue
+		}
+		return data, ContentTypeBinary, nil
+	default:
+		return nil, "", ErrUnsupportedContentType
+	}
+}
+
+// IsEmptyValue сообщает, что значение отсутствует или является пустой строкой либо
+// пустым набором байт. 0, false, пустые объект и массив пустыми не считаются.
+func IsEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []byte:
+		return len(v) == 0
+	}
+	return false
+}
+
+func normalizeJSON(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			n, err := normalizeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			normalized[key] = n
+		}
+		return normalized, nil
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			n, err := normalizeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+			normalized[i] = n
+		}
+		return normalized, nil
+	case nil, string, bool, float64, float32,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", value)
+}
+
+// DecodeStoredValue приводит значение, прочитанное из MessagePack, к виду,
+// пригодному для JSON: ключи map[interface{}]interface{} становятся строками.
+func DecodeStoredValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		decoded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			k, ok := key.(string)
+			if !ok {
+				k = fmt.Sprint(key)
+			}
+			d, err := DecodeStoredValue(item)
+			if err != nil {
+				return nil, err
+			}
+			decoded[k] = d
+		}
+		return decoded, nil
+	case map[string]interface{}:
+		decoded := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			d, err := DecodeStoredValue(item)
+			if err != nil {
+				return nil, err
+			}
+			decoded[key] = d
+		}
+		return decoded, nil
+	case []interface{}:
+		decoded := make([]interface{}, len(v))
+		for i, item := range v {
+			d, err := DecodeStoredValue(item)
+			if err != nil {
+				return nil, err
+			}
+			decoded[i] = d
+		}
+		return decoded, nil
+	case nil, string, []byte, bool, float32, float64,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported stored value type %T", value)
+}
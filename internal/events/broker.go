@@ -0,0 +1,147 @@
+package events
+
+import (
+	"errors"
+	"sync"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+const (
+	defaultBufferSize = 1024
+	subscriberBuffer  = 256
+)
+
+var ErrClosed = errors.New("event broker is closed")
+
+// Broker раздаёт события изменений подписчикам и хранит последние события
+// в кольцевом буфере, чтобы переподключившийся клиент мог дочитать пропущенное.
+type Broker struct {
+	mu     sync.Mutex
+	ring   []domain.ChangeEvent
+	start  int
+	size   int
+	lastID uint64
+	subs   map[*Subscription]struct{}
+	closed bool
+	logger interfaces.Logger
+}
+
+// Subscription получает события namespace с ключами, начинающимися с prefix.
+// Канал закрывается при Close или если подписчик не успевает читать события.
+type Subscription struct {
+	broker    *Broker
+	namespace string
+	prefix    string
+	events    chan domain.ChangeEvent
+	once      sync.Once
+}
+
+func NewBroker(bufferSize int, logger interfaces.Logger) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	return &Broker{
+		ring:   make([]domain.ChangeEvent, bufferSize),
+		subs:   make(map[*Subscription]struct{}),
+		logger: logger,
+	}
+}
+
+func (b *Broker) Publish(event domain.ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	if b.size < len(b.ring) {
+		b.ring[(b.start+b.size)%len(b.ring)] = event
+		b.size++
+	} else {
+		b.ring[b.start] = event
+		b.start = (b.start + 1) % len(b.ring)
+	}
+
+	for sub := range b.subs {
+		if !event.Matches(sub.namespace, sub.prefix) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// Медленный подписчик отключается и дочитает пропущенное из буфера по Last-Event-ID
+			b.logger.Warn("Dropping slow change feed subscriber", "namespace", sub.namespace, "prefix", sub.prefix)
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe регистрирует подписчика и возвращает события из буфера с ID больше lastEventID.
+// Если часть запрошенных событий уже вытеснена из буфера, возвращает domain.ErrEventsExpired.
+func (b *Broker) Subscribe(namespace, prefix string, lastEventID uint64) (*Subscription, []domain.ChangeEvent, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+
+	var replay []domain.ChangeEvent
+	if lastEventID > 0 {
+		oldestID := b.lastID - uint64(b.size) + 1
+		if lastEventID > b.lastID || lastEventID+1 < oldestID {
+			return nil, nil, domain.ErrEventsExpired
+		}
+
+		for i := 0; i < b.size; i++ {
+			event := b.ring[(b.start+i)%len(b.ring)]
+			if event.ID > lastEventID && event.Matches(namespace, prefix) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		broker:    b,
+		namespace: namespace,
+		prefix:    prefix,
+		events:    make(chan domain.ChangeEvent, subscriberBuffer),
+	}
+	b.subs[sub] = struct{}{}
+
+	return sub, replay, nil
+}
+
+// Close отключает всех подписчиков, чтобы открытые потоки завершились до остановки HTTP-сервера
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+func (s *Subscription) Events() <-chan domain.ChangeEvent {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mu.Lock()
+		defer s.broker.mu.Unlock()
+		s.broker.remove(s)
+	})
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+import "kv-storage/internal/domain"
+
+type EventPublisher interface {
+	Publish(event domain.ChangeEvent)
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+
+	"kv-storage/internal/domain"
+)
+
+// HealthChecker — проверка зависимости для /readyz. Check заполняет Status,
+// Error и Details; имя и задержку проставляет HealthService.
+type HealthChecker interface {
+	Name() string
+
+	Check(ctx context.Context) domain.HealthCheck
+}
@@ -1,15 +1,66 @@
 package interfaces
 
-import "kv-storage/internal/domain"
+import (
+	"context"
+	"time"
+
+	"kv-storage/internal/domain"
+)
 
 type KVRepository interface {
-	Create(kv *domain.KV) error
-	Get(key string) (*domain.KV, error)
-	Update(kv *domain.KV) error
-	Delete(key string) (*domain.KV, error)
-	SoftDelete(key string) error
-	Restore(key string) (*domain.KV, error)
-	List(limit, offset int) ([]*domain.KV, int, error)
-	ListIncludingDeleted(limit, offset int) ([]*domain.KV, int, error)
+	// Namespace возвращает репозиторий того же хранилища, ограниченный namespace name;
+	// операции над незарегистрированным namespace возвращают domain.ErrNamespaceNotFound
+	Namespace(name string) KVRepository
+	Create(ctx context.Context, kv *domain.KV) error
+	Get(ctx context.Context, key string) (*domain.KV, error)
+	// Update возвращает состояние записи до изменения
+	Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error)
+	// CompareAndSwap заменяет значение, если текущее равно expectedValue (nil — не проверять)
+	// и версия равна expectedVersion (0 — не проверять); возвращает состояние до изменения
+	CompareAndSwap(ctx context.Context, kv *domain.KV, expectedValue interface{}, expectedVersion uint64) (*domain.KV, error)
+	// PutIfAbsent создаёт запись, только если ключа нет; иначе возвращает
+	// существующую запись вместе с domain.ErrKeyAlreadyExists
+	PutIfAbsent(ctx context.Context, kv *domain.KV) (*domain.KV, error)
+	// Increment атомарно прибавляет delta к числовому значению, создавая ключ со
+	// значением initial + delta, если его нет; возвращает новое и предыдущее состояние
+	Increment(ctx context.Context, key string, delta, initial int64, expiresAt *time.Time) (*domain.KV, *domain.KV, error)
+	Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error)
+	SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error)
+	Restore(ctx context.Context, key string) (*domain.KV, error)
+	// History возвращает текущее состояние записи (в том числе удалённой) и её
+	// сохранённые ревизии, от новых к старым
+	History(ctx context.Context, key string) ([]*domain.KV, error)
+	List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error)
+	ListIncludingDeleted(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error)
+	Scan(ctx context.Context, opts domain.ScanOptions) (*domain.ListPage, error)
+	// Batch возвращает результаты вместе с domain.ErrBatchAborted,
+	// если атомарный пакет был откачен
+	Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchItemResult, error)
+	PurgeExpired(ctx context.Context, limit int) (int, error)
+	// Changes возвращает до limit записей журнала изменений после позиции cursor
+	// и позицию после них
+	Changes(ctx context.Context, cursor domain.ChangesCursor, limit int) ([]domain.ChangeLogEntry, domain.ChangesCursor, error)
+	// TrimChangelog удаляет до limit записей журнала, созданных раньше before
+	TrimChangelog(ctx context.Context, before time.Time, limit int) (int, error)
 	Close() error
 }
+
+type LockRepository interface {
+	// Acquire захватывает аренду; если она занята другим владельцем, возвращает
+	// текущую аренду вместе с domain.ErrLockHeld
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (*domain.Lock, error)
+	// Renew и Release возвращают domain.ErrLockNotFound для свободной или истёкшей
+	// аренды и domain.ErrNotLockOwner, если не совпали владелец или токен
+	Renew(ctx context.Context, name, owner string, token uint64, ttl time.Duration) (*domain.Lock, error)
+	Release(ctx context.Context, name, owner string, token uint64) (*domain.Lock, error)
+	Get(ctx context.Context, name string) (*domain.Lock, error)
+	PurgeExpired(ctx context.Context, limit int) (int, error)
+}
+
+type NamespaceRepository interface {
+	Create(ctx context.Context, name string) (*domain.Namespace, error)
+	Get(ctx context.Context, name string) (*domain.Namespace, error)
+	List(ctx context.Context) ([]*domain.Namespace, error)
+	// Drop удаляет namespace со всеми записями и возвращает его последнюю статистику
+	Drop(ctx context.Context, name string) (*domain.Namespace, error)
+}
@@ -1,19 +1,66 @@
 package interfaces
 
-import "kv-storage/internal/domain"
+import (
+	"context"
+	"time"
+
+	"kv-storage/internal/domain"
+)
 
 type KVService interface {
-	Create(req *domain.CreateKVRequest) (*domain.KV, error)
+	Create(ctx context.Context, req *domain.CreateKVRequest) (*domain.KV, error)
+
+	Get(ctx context.Context, key string) (*domain.KV, error)
+
+	Update(ctx context.Context, key string, req *domain.UpdateKVRequest) (*domain.KV, error)
+
+	CompareAndSwap(ctx context.Context, key string, req *domain.CASRequest) (*domain.KV, error)
+
+	PutIfAbsent(ctx context.Context, key string, req *domain.UpdateKVRequest) (*domain.KV, error)
+
+	Increment(ctx context.Context, key string, req *domain.IncrementRequest) (*domain.KV, error)
+
+	Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error)
+
+	SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error)
+
+	Restore(ctx context.Context, key string) (*domain.KV, error)
+
+	List(ctx context.Context, req *domain.ListKVRequest) (*domain.ListKVResponse, error)
+
+	ListIncludingDeleted(ctx context.Context, req *domain.ListKVRequest) (*domain.ListKVResponse, error)
 
-	Get(key string) (*domain.KV, error)
+	Scan(ctx context.Context, req *domain.ScanKVRequest) (*domain.ListKVResponse, error)
 
-	Update(key string, req *domain.UpdateKVRequest) (*domain.KV, error)
+	Batch(ctx context.Context, req *domain.BatchRequest) (*domain.BatchResponse, error)
+
+	Changes(ctx context.Context, req *domain.ChangesRequest) (*domain.ChangesResponse, error)
+
+	History(ctx context.Context, key string) (*domain.HistoryResponse, error)
+
+	GetVersion(ctx context.Context, key string, version uint64) (*domain.KV, error)
+
+	GetAt(ctx context.Context, key string, at time.Time) (*domain.KV, error)
+
+	Revert(ctx context.Context, key string, version, expectedVersion uint64) (*domain.KV, error)
+}
+
+type LockService interface {
+	Acquire(ctx context.Context, name string, req *domain.AcquireLockRequest) (*domain.Lock, error)
+
+	Renew(ctx context.Context, name string, req *domain.RenewLockRequest) (*domain.Lock, error)
+
+	Release(ctx context.Context, name string, req *domain.ReleaseLockRequest) (*domain.Lock, error)
+
+	Get(ctx context.Context, name string) (*domain.Lock, error)
+}
 
-	Delete(key string) (*domain.KV, error)
+type NamespaceService interface {
+	Create(ctx context.Context, req *domain.CreateNamespaceRequest) (*domain.Namespace, error)
 
-	Restore(key string) (*domain.KV, error)
+	Get(ctx context.Context, name string) (*domain.Namespace, error)
 
-	List(limit, offset int) (*domain.ListKVResponse, error)
+	List(ctx context.Context) (*domain.ListNamespacesResponse, error)
 
-	ListIncludingDeleted(limit, offset int) (*domain.ListKVResponse, error)
+	Drop(ctx context.Context, name string) (*domain.Namespace, error)
 }
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kv_storage"
+
+// Metrics хранит метрики приложения в собственном реестре. Все методы допускают
+// nil-получатель: при выключенных метриках вызывающему коду не нужны проверки.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	rateLimited  prometheus.Counter
+	repoDuration *prometheus.HistogramVec
+	repoErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "rate_limited_total",
+			Help:      "Requests rejected by the rate limiter.",
+		}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "tarantool",
+			Name:      "operation_duration_seconds",
+			Help:      "Tarantool repository operation latency.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"operation"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "tarantool",
+			Name:      "operation_errors_total",
+			Help:      "Tarantool repository operations that failed with a database error.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.rateLimited,
+		m.repoDuration,
+		m.repoErrors,
+	)
+	return m
+}
+
+// Handler отдаёт метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest учитывает запрос; route — шаблон маршрута, а не путь,
+// чтобы ключи в URL не порождали новые ряды
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+func (m *Metrics) RateLimited() {
+	if m == nil {
+		return
+	}
+	m.rateLimited.Inc()
+}
+
+// ObserveRepository учитывает операцию репозитория; failed — ошибка базы данных,
+// а не ожидаемый результат вроде отсутствующего ключа или конфликта версий
+func (m *Metrics) ObserveRepository(operation string, duration time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	m.repoDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if failed {
+		m.repoErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterPool экспортирует состояние пула соединений; значения читаются при каждом сборе
+func (m *Metrics) RegisterPool(pool PoolStatsSource) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(newPoolCollector(pool))
+}
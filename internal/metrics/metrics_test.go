@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+type staticPool domain.PoolStats
+
+func (p staticPool) Stats() domain.PoolStats {
+	return domain.PoolStats(p)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.ObserveHTTPRequest("GET", "/health", 200, time.Millisecond)
+	m.RateLimited()
+	m.ObserveRepository("get", time.Millisecond, true)
+	m.RegisterPool(staticPool{})
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterPool(staticPool{Size: 10, MaxSize: 20, Idle: 7, InUse: 3, WaitCount: 2, WaitDuration: 1500 * time.Millisecond, Timeouts: 1, DialFailures: 4})
+	m.ObserveHTTPRequest("GET", "/api/v1/kv/:key", 404, 5*time.Millisecond)
+	m.RateLimited()
+	m.ObserveRepository("get", 2*time.Millisecond, false)
+	m.ObserveRepository("update", 3*time.Millisecond, true)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`kv_storage_http_requests_total{method="GET",route="/api/v1/kv/:key",status="404"} 1`,
+		`kv_storage_http_request_duration_seconds_count{method="GET",route="/api/v1/kv/:key",status="404"} 1`,
+		`kv_storage_http_rate_limited_total 1`,
+		`kv_storage_tarantool_operation_duration_seconds_count{operation="get"} 1`,
+		`kv_storage_tarantool_operation_errors_total{operation="update"} 1`,
+		`kv_storage_pool_connections{state="idle"} 7`,
+		`kv_storage_pool_connections{state="in_use"} 3`,
+		`kv_storage_pool_wait_seconds_total 1.5`,
+		`kv_storage_pool_timeouts_total 1`,
+		`kv_storage_pool_max_size 20`,
+		`kv_storage_pool_dial_failures_total 4`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output does not contain %q", want)
+		}
+	}
+	if strings.Contains(string(body), `kv_storage_tarantool_operation_errors_total{operation="get"}`) {
+		t.Error("successful operation counted as error")
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"kv-storage/internal/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PoolStatsSource interface {
+	Stats() domain.PoolStats
+}
+
+// poolCollector читает статистику пула в момент сбора, поэтому пулу не нужно
+// обновлять метрики на каждой операции
+type poolCollector struct {
+	pool PoolStatsSource
+
+	size         *prometheus.Desc
+	maxSize      *prometheus.Desc
+	connections  *prometheus.Desc
+	waits        *prometheus.Desc
+	waitDuration *prometheus.Desc
+	timeouts     *prometheus.Desc
+	dialFailures *prometheus.Desc
+}
+
+func newPoolCollector(pool PoolStatsSource) *poolCollector {
+	name := func(metric string) string {
+		return prometheus.BuildFQName(namespace, "pool", metric)
+	}
+	return &poolCollector{
+		pool:         pool,
+		size:         prometheus.NewDesc(name("size"), "Open connections.", nil, nil),
+		maxSize:      prometheus.NewDesc(name("max_size"), "Maximum number of connections.", nil, nil),
+		connections:  prometheus.NewDesc(name("connections"), "Connections by state.", []string{"state"}, nil),
+		waits:        prometheus.NewDesc(name("waits_total"), "Acquisitions that had to wait for a free connection.", nil, nil),
+		waitDuration: prometheus.NewDesc(name("wait_seconds_total"), "Total time spent waiting for a free connection.", nil, nil),
+		timeouts:     prometheus.NewDesc(name("timeouts_total"), "Acquisitions that timed out waiting for a connection.", nil, nil),
+		dialFailures: prometheus.NewDesc(name("dial_failures_total"), "Failed attempts to open a connection.", nil, nil),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.size
+	ch <- c.maxSize
+	ch <- c.connections
+	ch <- c.waits
+	ch <- c.waitDuration
+	ch <- c.timeouts
+	ch <- c.dialFailures
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.pool.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.maxSize, prometheus.GaugeValue, float64(stats.MaxSize))
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.Idle), "idle")
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(stats.InUse), "in_use")
+	ch <- prometheus.MustNewConstMetric(c.waits, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.dialFailures, prometheus.CounterValue, float64(stats.DialFailures))
+}
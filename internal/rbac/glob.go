@@ -0,0 +1,62 @@
+package rbac
+
+// match сопоставляет строку с шаблоном, где "*" — любая последовательность символов
+// (включая "/" и ":"), "?" — ровно один символ
+func match(pattern, s string) bool {
+	p, i := 0, 0
+	// Позиция последней "*" в шаблоне и строки, с которой она начала совпадать
+	star, restart := -1, 0
+
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star, restart = p, i
+			p++
+		case star >= 0:
+			// Откат: "*" поглощает ещё один символ
+			restart++
+			p, i = star+1, restart
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// coversPrefix сообщает, подходит ли под шаблон любой ключ с префиксом prefix.
+// Это так, когда шаблон оканчивается на "*", а его часть до этой "*" совпадает
+// с каким-либо началом префикса: хвостовая "*" поглотит всё остальное.
+func coversPrefix(pattern, prefix string) bool {
+	if len(pattern) == 0 || pattern[len(pattern)-1] != '*' {
+		return false
+	}
+
+	head := pattern[:len(pattern)-1]
+	for n := 0; n <= len(prefix); n++ {
+		if match(head, prefix[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// RangePrefix возвращает префикс, общий для всех ключей, которые может вернуть список:
+// prefix, если он задан, иначе общее начало границ диапазона [from, to)
+func RangePrefix(prefix, from, to string) string {
+	if prefix != "" || from == "" || to == "" {
+		return prefix
+	}
+
+	n := 0
+	for n < len(from) && n < len(to) && from[n] == to[n] {
+		n++
+	}
+	return from[:n]
+}
@@ -0,0 +1,214 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+)
+
+// Action — операция, на которую выдаётся разрешение
+type Action string
+
+const (
+	// ActionRead — чтение ключей, истории, списков, журнала и потока изменений
+	ActionRead Action = "read"
+	// ActionWrite — создание и изменение ключей, в том числе restore, revert, incr и cas
+	ActionWrite Action = "write"
+	// ActionDelete — мягкое удаление
+	ActionDelete Action = "delete"
+	// ActionHardDelete — удаление без возможности восстановления
+	ActionHardDelete Action = "hard_delete"
+	// ActionListDeleted — списки вместе с мягко удалёнными записями (GET /kv/all)
+	ActionListDeleted Action = "list_deleted"
+	// ActionLock — операции с арендами; ключом служит имя аренды, namespace не учитывается
+	ActionLock Action = "lock"
+	// ActionAdmin — управление namespace; шаблоны ключей не учитываются
+	ActionAdmin Action = "admin"
+
+	actionAny Action = "*"
+)
+
+var knownActions = map[Action]bool{
+	ActionRead:        true,
+	ActionWrite:       true,
+	ActionDelete:      true,
+	ActionHardDelete:  true,
+	ActionListDeleted: true,
+	ActionLock:        true,
+	ActionAdmin:       true,
+	actionAny:         true,
+}
+
+// Request описывает проверяемый доступ. При Prefix=true Key — префикс, и доступ нужен
+// ко всем ключам с этим префиксом (списки, журнал и поток изменений).
+// Пустой Namespace не проверяется: так описываются аренды и список namespace.
+type Request struct {
+	Principal *domain.Principal
+	Action    Action
+	Namespace string
+	Key       string
+	Prefix    bool
+}
+
+// DeniedError — отказ в доступе с причиной для ответа 403
+type DeniedError struct {
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return domain.ErrForbidden.Error() + ": " + e.Reason
+}
+
+func (e *DeniedError) Unwrap() error {
+	return domain.ErrForbidden
+}
+
+type rule struct {
+	principals map[string]bool
+	roles      map[string]bool
+	actions    map[Action]bool
+	namespaces []string
+	keys       []string
+}
+
+// Policy — набор разрешающих правил из конфигурации. Nil-политика разрешает всё,
+// так выглядит выключенный RBAC.
+type Policy struct {
+	rules []rule
+}
+
+func NewPolicy(cfg config.RBACConfig) (*Policy, error) {
+	p := &Policy{rules: make([]rule, 0, len(cfg.Rules))}
+
+	for i, r := range cfg.Rules {
+		if len(r.Actions) == 0 {
+			return nil, fmt.Errorf("rbac rule %d: actions are required", i)
+		}
+
+		compiled := rule{
+			principals: toSet(r.Principals),
+			roles:      toSet(r.Roles),
+			actions:    make(map[Action]bool, len(r.Actions)),
+			namespaces: orAny(r.Namespaces),
+			keys:       orAny(r.Keys),
+		}
+		for _, action := range r.Actions {
+			if !knownActions[Action(action)] {
+				return nil, fmt.Errorf("rbac rule %d: unknown action %q", i, action)
+			}
+			compiled.actions[Action(action)] = true
+		}
+
+		p.rules = append(p.rules, compiled)
+	}
+
+	return p, nil
+}
+
+// Authorize возвращает *DeniedError, если ни одно правило не разрешает запрос
+func (p *Policy) Authorize(req Request) error {
+	if p == nil {
+		return nil
+	}
+	if req.Principal == nil {
+		return &DeniedError{Reason: "request is not authenticated"}
+	}
+
+	for _, r := range p.rules {
+		if r.allows(req) {
+			return nil
+		}
+	}
+
+	return &DeniedError{Reason: describe(req)}
+}
+
+func (r *rule) allows(req Request) bool {
+	if !r.actions[req.Action] && !r.actions[actionAny] {
+		return false
+	}
+	if !r.appliesTo(req.Principal) {
+		return false
+	}
+	if req.Namespace != "" && !matchAny(r.namespaces, req.Namespace) {
+		return false
+	}
+	if req.Action == ActionAdmin {
+		return true
+	}
+
+	for _, pattern := range r.keys {
+		if req.Prefix && coversPrefix(pattern, req.Key) {
+			return true
+		}
+		if !req.Prefix && match(pattern, req.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) appliesTo(principal *domain.Principal) bool {
+	if len(r.principals) == 0 && len(r.roles) == 0 {
+		return true
+	}
+	if r.principals[principal.Name] {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if r.roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+func describe(req Request) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%q", req.Principal.Name)
+	if len(req.Principal.Roles) > 0 {
+		fmt.Fprintf(&b, " (roles: %s)", strings.Join(req.Principal.Roles, ", "))
+	}
+	fmt.Fprintf(&b, " may not %s", req.Action)
+
+	switch {
+	case req.Prefix && req.Key == "":
+		b.WriteString(" all keys")
+	case req.Prefix:
+		fmt.Fprintf(&b, " keys with prefix %q", req.Key)
+	case req.Key != "":
+		fmt.Fprintf(&b, " key %q", req.Key)
+	}
+	if req.Namespace != "" {
+		fmt.Fprintf(&b, " in namespace %q", req.Namespace)
+	}
+
+	return b.String()
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func orAny(patterns []string) []string {
+	if len(patterns) == 0 {
+		return []string{"*"}
+	}
+	return patterns
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
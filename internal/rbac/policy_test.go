@@ -0,0 +1,113 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"*", "billing/invoice:1", true},
+		{"billing/*", "billing/invoice:1", true},
+		{"billing/*", "billing", false},
+		{"billing/*", "users/1", false},
+		{"user:???", "user:123", true},
+		{"user:???", "user:1234", false},
+		{"*/invoice:*", "billing/invoice:1", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCoversPrefix(t *testing.T) {
+	tests := []struct {
+		pattern, prefix string
+		want            bool
+	}{
+		{"*", "", true},
+		{"billing/*", "billing/", true},
+		{"billing/*", "billing/invoices/", true},
+		{"billing/*", "billing", false},
+		{"billing/*", "", false},
+		{"billing/invoice:1", "billing/", false},
+		{"*/archive/*", "billing/archive/", true},
+	}
+
+	for _, tt := range tests {
+		if got := coversPrefix(tt.pattern, tt.prefix); got != tt.want {
+			t.Errorf("coversPrefix(%q, %q) = %v, want %v", tt.pattern, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy, err := NewPolicy(config.RBACConfig{
+		Enabled: true,
+		Rules: []config.RBACRule{
+			{Roles: []string{"admin"}, Actions: []string{"*"}},
+			{Principals: []string{"billing"}, Actions: []string{"read", "write"}, Keys: []string{"billing/*"}},
+			{Roles: []string{"reader"}, Actions: []string{"read"}, Namespaces: []string{"default"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	admin := &domain.Principal{Name: "root", Roles: []string{"admin"}}
+	billing := &domain.Principal{Name: "billing"}
+	reader := &domain.Principal{Name: "dashboard", Roles: []string{"reader"}}
+
+	tests := []struct {
+		name  string
+		req   Request
+		allow bool
+	}{
+		{"admin hard delete", Request{Principal: admin, Action: ActionHardDelete, Namespace: "default", Key: "users/1"}, true},
+		{"admin namespaces", Request{Principal: admin, Action: ActionAdmin, Namespace: "tenant_a"}, true},
+		{"billing write own key", Request{Principal: billing, Action: ActionWrite, Namespace: "default", Key: "billing/invoice:1"}, true},
+		{"billing write foreign key", Request{Principal: billing, Action: ActionWrite, Namespace: "default", Key: "users/1"}, false},
+		{"billing hard delete", Request{Principal: billing, Action: ActionHardDelete, Namespace: "default", Key: "billing/invoice:1"}, false},
+		{"billing list own prefix", Request{Principal: billing, Action: ActionRead, Namespace: "default", Key: "billing/", Prefix: true}, true},
+		{"billing list everything", Request{Principal: billing, Action: ActionRead, Namespace: "default", Prefix: true}, false},
+		{"reader other namespace", Request{Principal: reader, Action: ActionRead, Namespace: "tenant_a", Key: "k"}, false},
+		{"reader list deleted", Request{Principal: reader, Action: ActionListDeleted, Namespace: "default", Prefix: true}, false},
+		{"anonymous", Request{Action: ActionRead, Namespace: "default", Key: "k"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.req)
+			if tt.allow && err != nil {
+				t.Fatalf("Authorize() error = %v, want allowed", err)
+			}
+			if !tt.allow && !errors.Is(err, domain.ErrForbidden) {
+				t.Fatalf("Authorize() error = %v, want %v", err, domain.ErrForbidden)
+			}
+		})
+	}
+
+	var disabled *Policy
+	if err := disabled.Authorize(Request{Action: ActionHardDelete, Key: "k"}); err != nil {
+		t.Errorf("nil policy Authorize() error = %v, want allowed", err)
+	}
+}
+
+func TestNewPolicy_UnknownAction(t *testing.T) {
+	_, err := NewPolicy(config.RBACConfig{Rules: []config.RBACRule{{Actions: []string{"purge"}}}})
+	if err == nil {
+		t.Fatal("NewPolicy() error = nil, want unknown action error")
+	}
+}
@@ -0,0 +1,64 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kv-storage/internal/domain"
+)
+
+func TestIntegration_CompareAndSwap(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+	key := prefix + "cas"
+
+	kv := &domain.KV{Key: key, Value: "a", ContentType: domain.ContentTypeJSON}
+	if err := repo.Create(ctx, kv); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if kv.Version != 1 {
+		t.Errorf("Create() version = %d, want 1", kv.Version)
+	}
+
+	swap := &domain.KV{Key: key, Value: "b", ContentType: domain.ContentTypeJSON}
+	if _, err := repo.CompareAndSwap(ctx, swap, "x", 0); !errors.Is(err, domain.ErrValueMismatch) {
+		t.Errorf("CompareAndSwap(wrong value) error = %v, want %v", err, domain.ErrValueMismatch)
+	}
+	if _, err := repo.CompareAndSwap(ctx, swap, nil, 5); !errors.Is(err, domain.ErrVersionConflict) {
+		t.Errorf("CompareAndSwap(wrong version) error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+
+	previous, err := repo.CompareAndSwap(ctx, swap, "a", 1)
+	if err != nil {
+		t.Fatalf("CompareAndSwap() error = %v", err)
+	}
+	if previous.Value != "a" || swap.Value != "b" || swap.Version != 2 {
+		t.Errorf("CompareAndSwap() previous = %+v, updated = %+v", previous, swap)
+	}
+
+	if _, err := repo.CompareAndSwap(ctx, &domain.KV{Key: prefix + "missing", Value: "b"}, "a", 0); !errors.Is(err, domain.ErrKeyNotFound) {
+		t.Errorf("CompareAndSwap(missing key) error = %v, want %v", err, domain.ErrKeyNotFound)
+	}
+}
+
+func TestIntegration_PutIfAbsent(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+	key := prefix + "put"
+
+	kv := &domain.KV{Key: key, Value: "a", ContentType: domain.ContentTypeJSON}
+	if existing, err := repo.PutIfAbsent(ctx, kv); err != nil || existing != nil {
+		t.Fatalf("PutIfAbsent() = %v, %v", existing, err)
+	}
+
+	existing, err := repo.PutIfAbsent(ctx, &domain.KV{Key: key, Value: "b", ContentType: domain.ContentTypeJSON})
+	if !errors.Is(err, domain.ErrKeyAlreadyExists) {
+		t.Fatalf("PutIfAbsent(existing key) error = %v, want %v", err, domain.ErrKeyAlreadyExists)
+	}
+	if existing == nil || existing.Value != "a" {
+		t.Errorf("PutIfAbsent(existing key) existing = %+v, want value a", existing)
+	}
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+func TestParseChangeLogEntry(t *testing.T) {
+	entry, err := parseChangeLogEntry([]interface{}{
+		uint64(42), "user:1", "update", "a", "b", uint64(2), uint32(100),
+	})
+	if err != nil {
+		t.Fatalf("parseChangeLogEntry() error = %v", err)
+	}
+	if entry.LSN != 42 || entry.Key != "user:1" || entry.Op != domain.ChangeOp("update") ||
+		entry.OldValue != "a" || entry.NewValue != "b" || entry.Version != 2 ||
+		!entry.Timestamp.Equal(time.Unix(100, 0)) {
+		t.Errorf("parseChangeLogEntry() = %+v", entry)
+	}
+
+	if _, err := parseChangeLogEntry([]interface{}{uint64(42), "user:1"}); err == nil {
+		t.Error("parseChangeLogEntry() of short record succeeded")
+	}
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	RoleMaster  = "master"
+	RoleReplica = "replica"
+
+	// roleRefreshInterval ограничивает внеочередные проверки ролей, когда мастер не найден
+	roleRefreshInterval = time.Second
+)
+
+var errNoMaster = errors.New("no writable tarantool instance")
+
+// Cluster направляет запросы по экземплярам Tarantool: записи — на текущий мастер,
+// чтения — по кругу на реплики. Роли отслеживаются пулами по box.info.ro, поэтому
+// после переключения мастера запросы сами переходят на новый экземпляр.
+type Cluster struct {
+	pools  []*ConnectionPool
+	logger interfaces.Logger
+	next   atomic.Uint64
+
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+}
+
+// NewCluster создаёт пул для каждого экземпляра; экземпляры без роли после первого
+// считаются репликами, пока их роль не определена
+func NewCluster(cfg *config.Config, instances []config.InstanceConfig, logger interfaces.Logger) *Cluster {
+	pools := make([]*ConnectionPool, 0, len(instances))
+	for i, instance := range instances {
+		if instance.Name == "" {
+			instance.Name = fmt.Sprintf("%s:%d", instance.Host, instance.Port)
+		}
+		if instance.Role == "" && i > 0 {
+			instance.Role = RoleReplica
+		}
+		pools = append(pools, NewConnectionPool(cfg, instance, logger))
+	}
+
+	return newCluster(pools, logger)
+}
+
+func newCluster(pools []*ConnectionPool, logger interfaces.Logger) *Cluster {
+	return &Cluster{
+		pools:  pools,
+		logger: logger,
+	}
+}
+
+// Execute выполняет запрос на мастере. Если экземпляр отказал в записи (ER_READONLY),
+// роли перепроверяются и запрос повторяется один раз на новом мастере
+func (c *Cluster) Execute(ctx context.Context, fn func(*tarantool.Connection) error) error {
+	pool, err := c.master(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = pool.Execute(ctx, fn)
+	if !isReadOnlyError(err) {
+		return err
+	}
+
+	c.logger.Warn("Tarantool instance is read-only, looking for new master", "instance", pool.Name())
+	c.refreshRoles(ctx, true)
+
+	pool, masterErr := c.master(ctx)
+	if masterErr != nil {
+		return err
+	}
+	return pool.Execute(ctx, fn)
+}
+
+// ExecuteRead выполняет чтение на одной из доступных реплик, а если их нет — на мастере.
+// При строгой согласованности из контекста чтение всегда идёт на мастер
+func (c *Cluster) ExecuteRead(ctx context.Context, fn func(*tarantool.Connection) error) error {
+	if domain.ConsistencyFromContext(ctx) == domain.ConsistencyStrong {
+		return c.Execute(ctx, fn)
+	}
+
+	if pool := c.replica(); pool != nil {
+		return pool.Execute(ctx, fn)
+	}
+	return c.Execute(ctx, fn)
+}
+
+// master возвращает пул доступного экземпляра с box.info.ro = false
+func (c *Cluster) master(ctx context.Context) (*ConnectionPool, error) {
+	if pool := c.findMaster(); pool != nil {
+		return pool, nil
+	}
+
+	// Мастер мог смениться между фоновыми проверками
+	c.refreshRoles(ctx, false)
+	if pool := c.findMaster(); pool != nil {
+		return pool, nil
+	}
+
+	// Все экземпляры недоступны: пул единственного экземпляра сам вернёт причину
+	if len(c.pools) == 1 {
+		return c.pools[0], nil
+	}
+	return nil, errNoMaster
+}
+
+func (c *Cluster) findMaster() *ConnectionPool {
+	for _, pool := range c.pools {
+		if !pool.ReadOnly() && pool.Available() {
+			return pool
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) replica() *ConnectionPool {
+	replicas := make([]*ConnectionPool, 0, len(c.pools))
+	for _, pool := range c.pools {
+		if pool.ReadOnly() && pool.Available() {
+			replicas = append(replicas, pool)
+		}
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+	return replicas[(c.next.Add(1)-1)%uint64(len(replicas))]
+}
+
+// refreshRoles перепроверяет роли всех доступных экземпляров. Без force проверка
+// выполняется не чаще roleRefreshInterval, чтобы поток запросов без мастера не
+// превращался в поток запросов box.info
+func (c *Cluster) refreshRoles(ctx context.Context, force bool) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if !force && time.Since(c.lastRefresh) < roleRefreshInterval {
+		return
+	}
+	c.lastRefresh = time.Now()
+
+	for _, pool := range c.pools {
+		if !pool.Available() {
+			continue
+		}
+		if err := pool.RefreshRole(ctx); err != nil {
+			c.logger.Debug("Failed to refresh instance role", "instance", pool.Name(), "error", err)
+		}
+	}
+}
+
+func isReadOnlyError(err error) bool {
+	var tntErr tarantool.Error
+	return errors.As(err, &tntErr) && tntErr.Code == iproto.ER_READONLY
+}
+
+// Stats суммирует состояние пулов всех экземпляров
+func (c *Cluster) Stats() domain.PoolStats {
+	var total domain.PoolStats
+	for _, pool := range c.pools {
+		addPoolStats(&total, pool.Stats())
+	}
+	return total
+}
+
+func addPoolStats(total *domain.PoolStats, stats domain.PoolStats) {
+	total.Size += stats.Size
+	total.MaxSize += stats.MaxSize
+	total.Idle += stats.Idle
+	total.InUse += stats.InUse
+	total.WaitCount += stats.WaitCount
+	total.WaitDuration += stats.WaitDuration
+	total.Timeouts += stats.Timeouts
+	total.DialFailures += stats.DialFailures
+}
+
+func (c *Cluster) Close() error {
+	for _, pool := range c.pools {
+		pool.Close()
+	}
+	return nil
+}
+
+func roleName(readOnly bool) string {
+	if readOnly {
+		return RoleReplica
+	}
+	return RoleMaster
+}
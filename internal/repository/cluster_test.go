@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kv-storage/internal/domain"
+
+	"github.com/tarantool/go-iproto"
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// testPool создаёт пул без соединений с заданной ролью и доступностью
+func testPool(name string, readOnly, available bool) *ConnectionPool {
+	pool := &ConnectionPool{name: name, address: name + ":3301", logger: nopLogger{}}
+	pool.readOnly.Store(readOnly)
+	if !available {
+		pool.dialErr = errors.New("connection refused")
+	}
+	return pool
+}
+
+func TestCluster_Routing(t *testing.T) {
+	master := testPool("master", false, true)
+	replica1 := testPool("replica1", true, true)
+	replica2 := testPool("replica2", true, true)
+	down := testPool("replica3", true, false)
+	cluster := newCluster([]*ConnectionPool{replica1, master, down, replica2}, nopLogger{})
+
+	got, err := cluster.master(context.Background())
+	if err != nil || got != master {
+		t.Fatalf("Cluster.master() = %v, %v, want master", got, err)
+	}
+
+	// Чтения чередуются между доступными репликами
+	seen := map[*ConnectionPool]int{}
+	for i := 0; i < 4; i++ {
+		seen[cluster.replica()]++
+	}
+	if seen[replica1] != 2 || seen[replica2] != 2 || seen[down] != 0 {
+		t.Errorf("Cluster.replica() distribution = %v, want replica1 and replica2 twice", seen)
+	}
+
+	// Переключение: бывший мастер стал репликой, реплика — мастером
+	master.readOnly.Store(true)
+	replica2.readOnly.Store(false)
+	if got := cluster.findMaster(); got != replica2 {
+		t.Errorf("Cluster.findMaster() after failover = %v, want replica2", got.Name())
+	}
+}
+
+func TestCluster_NoMaster(t *testing.T) {
+	cluster := newCluster([]*ConnectionPool{
+		testPool("master", false, false),
+		testPool("replica", true, false),
+	}, nopLogger{})
+
+	if _, err := cluster.master(context.Background()); err != errNoMaster {
+		t.Errorf("Cluster.master() error = %v, want %v", err, errNoMaster)
+	}
+	if got := cluster.replica(); got != nil {
+		t.Errorf("Cluster.replica() = %v, want nil", got.Name())
+	}
+
+	check := (&instancesCheck{cluster: cluster}).Check(context.Background())
+	if check.Status != domain.HealthFail {
+		t.Errorf("instancesCheck status = %v, want %v", check.Status, domain.HealthFail)
+	}
+}
+
+func TestCluster_ReplicaDown(t *testing.T) {
+	cluster := newCluster([]*ConnectionPool{
+		testPool("master", false, true),
+		testPool("replica", true, false),
+	}, nopLogger{})
+
+	check := (&instancesCheck{cluster: cluster}).Check(context.Background())
+	if check.Status != domain.HealthWarn {
+		t.Errorf("instancesCheck status = %v, want %v", check.Status, domain.HealthWarn)
+	}
+}
+
+func TestIsReadOnlyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"read only", tarantool.Error{Code: iproto.ER_READONLY, Msg: "Can't modify data"}, true},
+		{"wrapped", errors.Join(errors.New("call"), tarantool.Error{Code: iproto.ER_READONLY}), true},
+		{"other box error", tarantool.Error{Code: iproto.ER_NO_SUCH_SPACE}, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReadOnlyError(tt.err); got != tt.want {
+				t.Errorf("isReadOnlyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kv-storage/internal/domain"
+)
+
+func TestIntegration_Increment(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+	key := prefix + "counter"
+
+	kv, previous, err := repo.Increment(ctx, key, 5, 10, nil)
+	if err != nil {
+		t.Fatalf("Increment() error = %v", err)
+	}
+	if n, _ := toInt64(kv.Value); n != 15 || previous != nil {
+		t.Errorf("Increment() of missing key = %v, previous %v, want 15 and nil", kv.Value, previous)
+	}
+
+	kv, previous, err = repo.Increment(ctx, key, -3, 0, nil)
+	if err != nil {
+		t.Fatalf("Increment() error = %v", err)
+	}
+	if n, _ := toInt64(kv.Value); n != 12 || kv.Version != 2 {
+		t.Errorf("Increment() = %v version %d, want 12 version 2", kv.Value, kv.Version)
+	}
+	if n, _ := toInt64(previous.Value); n != 15 {
+		t.Errorf("Increment() previous = %v, want 15", previous.Value)
+	}
+
+	text := &domain.KV{Key: prefix + "text", Value: "a", ContentType: domain.ContentTypeJSON}
+	if err := repo.Create(ctx, text); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := repo.Increment(ctx, text.Key, 1, 0, nil); !errors.Is(err, domain.ErrNotNumeric) {
+		t.Errorf("Increment(text) error = %v, want %v", err, domain.ErrNotNumeric)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+// Записи с истёкшим TTL не видны чтению и не учитываются в total до удаления reaper'ом
+func TestIntegration_Expiry(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+
+	before, err := repo.List(ctx, domain.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+	expired := &domain.KV{Key: prefix + "expired", Value: "a", ContentType: domain.ContentTypeJSON, ExpiresAt: &past}
+	live := &domain.KV{Key: prefix + "live", Value: "b", ContentType: domain.ContentTypeJSON, ExpiresAt: &future}
+	for _, kv := range []*domain.KV{expired, live} {
+		if err := repo.Create(ctx, kv); err != nil {
+			t.Fatalf("Create(%s) error = %v", kv.Key, err)
+		}
+	}
+
+	if _, err := repo.Get(ctx, expired.Key); !errors.Is(err, domain.ErrKeyNotFound) {
+		t.Errorf("Get(expired) error = %v, want %v", err, domain.ErrKeyNotFound)
+	}
+	if kv, err := repo.Get(ctx, live.Key); err != nil || kv.ExpiresAt == nil {
+		t.Errorf("Get(live) = %+v, %v, want record with expires_at", kv, err)
+	}
+
+	after, err := repo.List(ctx, domain.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if after.Total != before.Total+1 {
+		t.Errorf("List() total = %d, want %d: expired record is counted", after.Total, before.Total+1)
+	}
+
+	// Новая запись поверх истёкшей продолжает её последовательность версий
+	if _, err := repo.Update(ctx, &domain.KV{Key: expired.Key, Value: "c", ContentType: domain.ContentTypeJSON}, 0); !errors.Is(err, domain.ErrKeyNotFound) {
+		t.Errorf("Update(expired) error = %v, want %v", err, domain.ErrKeyNotFound)
+	}
+	recreated := &domain.KV{Key: prefix + "recreated", Value: "a", ContentType: domain.ContentTypeJSON, ExpiresAt: &past}
+	if err := repo.Create(ctx, recreated); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	recreated = &domain.KV{Key: recreated.Key, Value: "c", ContentType: domain.ContentTypeJSON}
+	if err := repo.Create(ctx, recreated); err != nil {
+		t.Fatalf("Create(expired key) error = %v", err)
+	}
+	if recreated.Version != 2 {
+		t.Errorf("Create(expired key) version = %d, want 2", recreated.Version)
+	}
+
+	if _, err := repo.PurgeExpired(ctx, 100); err != nil {
+		t.Fatalf("PurgeExpired() error = %v", err)
+	}
+	all, err := repo.Scan(ctx, domain.ScanOptions{Prefix: prefix, Limit: 10, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(all.Items) != 2 || all.Items[0].Key != live.Key || all.Items[1].Key != recreated.Key {
+		t.Errorf("Scan() after purge = %+v, want the live and recreated records", all.Items)
+	}
+}
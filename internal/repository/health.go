@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+const (
+	kvSpace = "kv"
+
+	// poolSaturationWarn — доля занятых соединений, начиная с которой проверка пула
+	// сообщает warn: новые запросы вот-вот начнут ждать соединение
+	poolSaturationWarn = 0.9
+)
+
+// kvIndexes — индексы space kv, без которых не работают чтение, мягкое удаление и TTL (см. init.lua)
+var kvIndexes = []string{"primary", "deleted", "expires"}
+
+// NewHealthCheckers возвращает проверки готовности Tarantool: ping мастера, схема space kv,
+// загрузка пулов соединений и состояние экземпляров. При нескольких шардах проверки
+// выполняются для каждого и называются по шарду: "shard-1/tarantool".
+func NewHealthCheckers(shards Shards) []interfaces.HealthChecker {
+	checks := make([]interfaces.HealthChecker, 0, 4*len(shards))
+	for _, shard := range shards {
+		for _, check := range clusterHealthCheckers(shard.Cluster) {
+			if len(shards) > 1 {
+				check = &shardCheck{HealthChecker: check, shard: shard.Name}
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+func clusterHealthCheckers(cluster *Cluster) []interfaces.HealthChecker {
+	return []interfaces.HealthChecker{
+		&pingCheck{cluster: cluster},
+		&schemaCheck{cluster: cluster},
+		&poolCheck{cluster: cluster},
+		&instancesCheck{cluster: cluster},
+	}
+}
+
+// shardCheck добавляет имя шарда к имени проверки
+type shardCheck struct {
+	interfaces.HealthChecker
+	shard string
+}
+
+func (c *shardCheck) Name() string {
+	return c.shard + "/" + c.HealthChecker.Name()
+}
+
+type pingCheck struct {
+	cluster *Cluster
+}
+
+func (c *pingCheck) Name() string {
+	return "tarantool"
+}
+
+func (c *pingCheck) Check(ctx context.Context) domain.HealthCheck {
+	err := c.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		_, err := conn.Do(tarantool.NewPingRequest().Context(ctx)).Get()
+		return err
+	})
+	if err != nil {
+		return domain.HealthCheck{Status: domain.HealthFail, Error: err.Error()}
+	}
+	return domain.HealthCheck{Status: domain.HealthOK}
+}
+
+// schemaCheck проверяет по системным view _vspace и _vindex, что space kv
+// и его индексы созданы
+type schemaCheck struct {
+	cluster *Cluster
+}
+
+func (c *schemaCheck) Name() string {
+	return "schema"
+}
+
+func (c *schemaCheck) Check(ctx context.Context) domain.HealthCheck {
+	var indexes []string
+
+	err := c.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		spaces, err := conn.Do(
+			tarantool.NewSelectRequest("_vspace").
+				Index("name").
+				Key([]interface{}{kvSpace}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("_vspace select failed: %w", err)
+		}
+		if len(spaces) == 0 {
+			return fmt.Errorf("space %s not found", kvSpace)
+		}
+		space, ok := spaces[0].([]interface{})
+		if !ok || len(space) == 0 {
+			return fmt.Errorf("invalid _vspace tuple format")
+		}
+
+		records, err := conn.Do(
+			tarantool.NewSelectRequest("_vindex").
+				Key([]interface{}{space[0]}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("_vindex select failed: %w", err)
+		}
+		for _, record := range records {
+			tuple, ok := record.([]interface{})
+			if !ok || len(tuple) < 3 {
+				return fmt.Errorf("invalid _vindex tuple format")
+			}
+			if name, ok := tuple[2].(string); ok {
+				indexes = append(indexes, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.HealthCheck{Status: domain.HealthFail, Error: err.Error()}
+	}
+
+	details := map[string]interface{}{"space": kvSpace, "indexes": indexes}
+	if missing := missingIndexes(indexes); len(missing) > 0 {
+		return domain.HealthCheck{
+			Status:  domain.HealthFail,
+			Error:   fmt.Sprintf("space %s is missing indexes %v", kvSpace, missing),
+			Details: details,
+		}
+	}
+	return domain.HealthCheck{Status: domain.HealthOK, Details: details}
+}
+
+func missingIndexes(indexes []string) []string {
+	present := make(map[string]bool, len(indexes))
+	for _, name := range indexes {
+		present[name] = true
+	}
+
+	var missing []string
+	for _, name := range kvIndexes {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// poolCheck не обращается к Tarantool и только сообщает загрузку пула
+type poolCheck struct {
+	cluster *Cluster
+}
+
+func (c *poolCheck) Name() string {
+	return "pool"
+}
+
+func (c *poolCheck) Check(ctx context.Context) domain.HealthCheck {
+	return poolHealth(c.cluster.Stats())
+}
+
+func poolHealth(stats domain.PoolStats) domain.HealthCheck {
+	saturation := 1.0
+	if stats.MaxSize > 0 {
+		saturation = float64(stats.InUse) / float64(stats.MaxSize)
+	}
+
+	check := domain.HealthCheck{
+		Status: domain.HealthOK,
+		Details: map[string]interface{}{
+			"size":          stats.Size,
+			"max_size":      stats.MaxSize,
+			"idle":          stats.Idle,
+			"in_use":        stats.InUse,
+			"saturation":    saturation,
+			"waits":         stats.WaitCount,
+			"timeouts":      stats.Timeouts,
+			"dial_failures": stats.DialFailures,
+		},
+	}
+	if saturation >= poolSaturationWarn {
+		check.Status = domain.HealthWarn
+		check.Error = fmt.Sprintf("pool saturation %.0f%%", saturation*100)
+	}
+	return check
+}
+
+// instancesCheck сообщает роль и доступность каждого экземпляра: без мастера
+// запись невозможна, недоступная реплика только снижает запас по чтению
+type instancesCheck struct {
+	cluster *Cluster
+}
+
+func (c *instancesCheck) Name() string {
+	return "instances"
+}
+
+func (c *instancesCheck) Check(ctx context.Context) domain.HealthCheck {
+	check := domain.HealthCheck{
+		Status:  domain.HealthOK,
+		Details: make(map[string]interface{}, len(c.cluster.pools)),
+	}
+
+	var master bool
+	var down []string
+	for _, pool := range c.cluster.pools {
+		available := pool.Available()
+		check.Details[pool.Name()] = map[string]interface{}{
+			"address":   pool.Address(),
+			"role":      roleName(pool.ReadOnly()),
+			"available": available,
+		}
+		if !available {
+			down = append(down, pool.Name())
+			continue
+		}
+		if !pool.ReadOnly() {
+			master = true
+		}
+	}
+
+	switch {
+	case !master:
+		check.Status = domain.HealthFail
+		check.Error = errNoMaster.Error()
+	case len(down) > 0:
+		check.Status = domain.HealthWarn
+		check.Error = fmt.Sprintf("unavailable instances: %s", strings.Join(down, ", "))
+	}
+	return check
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// Статусы Lua-функций kv_lock_* из init.lua
+const (
+	statusLocked   = "locked"
+	statusNotOwner = "not_owner"
+)
+
+// TarantoolLockRepository хранит аренды в space kv_locks и работает через тот же
+// пул соединений, что и TarantoolRepository
+type TarantoolLockRepository struct {
+	cluster *Cluster
+	logger  interfaces.Logger
+}
+
+func NewTarantoolLockRepository(cluster *Cluster, logger interfaces.Logger) interfaces.LockRepository {
+	return &TarantoolLockRepository{
+		cluster: cluster,
+		logger:  logger,
+	}
+}
+
+func (r *TarantoolLockRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (*domain.Lock, error) {
+	lock, current, err := r.callLock(ctx, "kv_lock_acquire", []interface{}{
+		name,
+		owner,
+		ttl.Milliseconds(),
+		time.Now().UnixMilli(),
+	})
+
+	switch {
+	case errors.Is(err, domain.ErrLockHeld):
+		r.logger.Debug("Lock is held by another owner", "name", name)
+		return current, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to acquire lock", "name", name)
+	}
+
+	r.logger.Info("Lock acquired", "name", name, "owner", owner, "token", lock.Token)
+	return lock, nil
+}
+
+func (r *TarantoolLockRepository) Renew(ctx context.Context, name, owner string, token uint64, ttl time.Duration) (*domain.Lock, error) {
+	lock, _, err := r.callLock(ctx, "kv_lock_renew", []interface{}{
+		name,
+		owner,
+		token,
+		ttl.Milliseconds(),
+		time.Now().UnixMilli(),
+	})
+
+	switch {
+	case errors.Is(err, domain.ErrLockNotFound), errors.Is(err, domain.ErrNotLockOwner):
+		r.logger.Debug("Lock was not renewed", "name", name, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to renew lock", "name", name)
+	}
+
+	return lock, nil
+}
+
+func (r *TarantoolLockRepository) Release(ctx context.Context, name, owner string, token uint64) (*domain.Lock, error) {
+	lock, _, err := r.callLock(ctx, "kv_lock_release", []interface{}{
+		name,
+		owner,
+		token,
+		time.Now().UnixMilli(),
+	})
+
+	switch {
+	case errors.Is(err, domain.ErrLockNotFound), errors.Is(err, domain.ErrNotLockOwner):
+		r.logger.Debug("Lock was not released", "name", name, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to release lock", "name", name)
+	}
+
+	r.logger.Info("Lock released", "name", name, "owner", owner, "token", token)
+	return lock, nil
+}
+
+func (r *TarantoolLockRepository) Get(ctx context.Context, name string) (*domain.Lock, error) {
+	var result []interface{}
+
+	err := r.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewSelectRequest("kv_locks").Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{name}).Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("select failed: %w", err)
+		}
+		result = resp
+		return nil
+	})
+
+	if err != nil {
+		return nil, databaseError(r.logger, err, "Failed to get lock", "name", name)
+	}
+
+	if len(result) == 0 {
+		return nil, domain.ErrLockNotFound
+	}
+
+	record, ok := result[0].([]interface{})
+	if !ok {
+		return nil, domain.ErrDatabaseError
+	}
+	lock, err := parseLock(record)
+	if err != nil {
+		r.logger.Error("Invalid lock record", "name", name, "error", err)
+		return nil, domain.ErrDatabaseError
+	}
+
+	// Истёкшая аренда, которую ещё не удалил reaper, считается свободной
+	if !lock.ExpiresAt.After(time.Now()) {
+		return nil, domain.ErrLockNotFound
+	}
+
+	return lock, nil
+}
+
+func (r *TarantoolLockRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	var purged int
+
+	err := r.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest("kv_lock_purge_expired").
+				Args([]interface{}{time.Now().UnixMilli(), limit}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("lock purge call failed: %w", err)
+		}
+		if len(resp) > 0 {
+			if n, ok := toInt64(resp[0]); ok {
+				purged = int(n)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, databaseError(r.logger, err, "Failed to purge expired locks")
+	}
+
+	return purged, nil
+}
+
+// callLock вызывает Lua-функцию kv_lock_*, которая возвращает кортеж аренды либо
+// статус и текущую аренду третьим значением
+func (r *TarantoolLockRepository) callLock(ctx context.Context, function string, args []interface{}) (*domain.Lock, *domain.Lock, error) {
+	var lock, current *domain.Lock
+
+	err := r.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest(function).Args(args).Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("%s call failed: %w", function, err)
+		}
+		if len(resp) > 2 {
+			if record, ok := resp[2].([]interface{}); ok {
+				if current, err = parseLock(record); err != nil {
+					return err
+				}
+			}
+		}
+		if len(resp) > 1 {
+			if status, ok := resp[1].(string); ok {
+				return lockStatusError(status)
+			}
+		}
+		if len(resp) == 0 {
+			return fmt.Errorf("no data returned from %s", function)
+		}
+		record, ok := resp[0].([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid lock record format")
+		}
+		lock, err = parseLock(record)
+		return err
+	})
+
+	return lock, current, err
+}
+
+func lockStatusError(status string) error {
+	switch status {
+	case statusLocked:
+		return domain.ErrLockHeld
+	case statusNotFound:
+		return domain.ErrLockNotFound
+	case statusNotOwner:
+		return domain.ErrNotLockOwner
+	default:
+		return fmt.Errorf("unexpected status %q", status)
+	}
+}
+
+// parseLock разбирает кортеж kv_locks: name, owner, token, acquired_at, expires_at
+func parseLock(record []interface{}) (*domain.Lock, error) {
+	if len(record) < 5 {
+		return nil, fmt.Errorf("lock record has %d fields", len(record))
+	}
+
+	name, _ := record[0].(string)
+	owner, _ := record[1].(string)
+	token, _ := toInt64(record[2])
+	acquiredAt, _ := toInt64(record[3])
+	expiresAt, _ := toInt64(record[4])
+
+	return &domain.Lock{
+		Name:       name,
+		Owner:      owner,
+		Token:      uint64(token),
+		AcquiredAt: time.UnixMilli(acquiredAt),
+		ExpiresAt:  time.UnixMilli(expiresAt),
+	}, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+// TarantoolNamespaceRepository управляет реестром namespace (space kv_namespaces)
+// и space каждого namespace через общий пул соединений
+type TarantoolNamespaceRepository struct {
+	cluster *Cluster
+	logger  interfaces.Logger
+}
+
+func NewTarantoolNamespaceRepository(cluster *Cluster, logger interfaces.Logger) interfaces.NamespaceRepository {
+	return &TarantoolNamespaceRepository{
+		cluster: cluster,
+		logger:  logger,
+	}
+}
+
+func (r *TarantoolNamespaceRepository) Create(ctx context.Context, name string) (*domain.Namespace, error) {
+	namespace, err := r.call(ctx, "kv_namespace_create", name)
+
+	switch {
+	case errors.Is(err, domain.ErrKeyAlreadyExists):
+		return nil, domain.ErrNamespaceExists
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to create namespace", "namespace", name)
+	}
+
+	r.logger.Info("Namespace created", "namespace", name)
+	return namespace, nil
+}
+
+func (r *TarantoolNamespaceRepository) Get(ctx context.Context, name string) (*domain.Namespace, error) {
+	namespace, err := r.call(ctx, "kv_namespace_get", name)
+
+	switch {
+	case errors.Is(err, domain.ErrKeyNotFound):
+		return nil, domain.ErrNamespaceNotFound
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to get namespace", "namespace", name)
+	}
+
+	return namespace, nil
+}
+
+func (r *TarantoolNamespaceRepository) List(ctx context.Context) ([]*domain.Namespace, error) {
+	var records []interface{}
+
+	err := r.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest("kv_namespace_list").
+				Args([]interface{}{uint32(time.Now().Unix())}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("kv_namespace_list call failed: %w", err)
+		}
+		if len(resp) > 0 {
+			records, _ = resp[0].([]interface{})
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, databaseError(r.logger, err, "Failed to list namespaces")
+	}
+
+	namespaces := make([]*domain.Namespace, 0, len(records))
+	for _, record := range records {
+		tuple, _ := record.([]interface{})
+		namespace, err := parseNamespace(tuple)
+		if err != nil {
+			r.logger.Error("Invalid namespace record", "error", err)
+			return nil, domain.ErrDatabaseError
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	return namespaces, nil
+}
+
+func (r *TarantoolNamespaceRepository) Drop(ctx context.Context, name string) (*domain.Namespace, error) {
+	namespace, err := r.call(ctx, "kv_namespace_drop", name)
+
+	switch {
+	case errors.Is(err, domain.ErrKeyNotFound):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrValidationError):
+		return nil, domain.ErrNamespaceProtected
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to drop namespace", "namespace", name)
+	}
+
+	r.logger.Info("Namespace dropped", "namespace", name, "keys", namespace.Total)
+	return namespace, nil
+}
+
+// call вызывает Lua-функцию kv_namespace_*, которая возвращает статистику
+// namespace либо пару (nil, статус)
+func (r *TarantoolNamespaceRepository) call(ctx context.Context, function, name string) (*domain.Namespace, error) {
+	var namespace *domain.Namespace
+
+	err := r.cluster.Execute(ctx, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest(function).
+				Args([]interface{}{name, uint32(time.Now().Unix())}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("%s call failed: %w", function, err)
+		}
+		if len(resp) > 1 {
+			if status, ok := resp[1].(string); ok {
+				return statusError(status)
+			}
+		}
+		if len(resp) == 0 {
+			return fmt.Errorf("no data returned from %s", function)
+		}
+		record, ok := resp[0].([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid namespace record format")
+		}
+		namespace, err = parseNamespace(record)
+		return err
+	})
+
+	return namespace, err
+}
+
+// parseNamespace разбирает результат kv_namespace_stats:
+// name, created_at, keys, total, bytes, revisions, changelog_entries
+func parseNamespace(record []interface{}) (*domain.Namespace, error) {
+	if len(record) < 7 {
+		return nil, fmt.Errorf("namespace record has %d fields", len(record))
+	}
+
+	name, _ := record[0].(string)
+	createdAt, _ := toInt64(record[1])
+	keys, _ := toInt64(record[2])
+	total, _ := toInt64(record[3])
+	bytes, _ := toInt64(record[4])
+	revisions, _ := toInt64(record[5])
+	changelog, _ := toInt64(record[6])
+
+	return &domain.Namespace{
+		Name:             name,
+		CreatedAt:        time.Unix(createdAt, 0),
+		Keys:             int(keys),
+		Total:            int(total),
+		Bytes:            bytes,
+		Revisions:        int(revisions),
+		ChangelogEntries: int(changelog),
+	}, nil
+}
@@ -2,50 +2,147 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/tracing"
 
 	"github.com/tarantool/go-tarantool/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	defaultPoolMinSize             = 2
+	defaultPoolMaxSize             = 10
+	defaultPoolIdleTimeout         = 5 * time.Minute
+	defaultPoolHealthCheckInterval = 10 * time.Second
+
+	dialTimeout = 5 * time.Second
+	pingTimeout = time.Second
+
+	// acquireTimeout ограничивает ожидание соединения, если у контекста нет своего срока
+	acquireTimeout = 5 * time.Second
+
+	// Пауза перед повторным подключением удваивается после каждой неудачи
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+var errPoolClosed = errors.New("connection pool is closed")
+
+type idleConn struct {
+	conn  *tarantool.Connection
+	since time.Time
+}
+
+// ConnectionPool держит от MinSize до MaxSize соединений с одним экземпляром Tarantool.
+// Фоновая горутина проверяет простаивающие соединения запросом box.info.ro, заодно
+// отслеживая роль экземпляра, закрывает лишние после IdleTimeout и восстанавливает
+// MinSize соединений с нарастающей паузой, пока экземпляр недоступен.
+// Закрытые соединения отбрасываются и при выдаче, и при возврате в пул.
 type ConnectionPool struct {
-	connections chan *tarantool.Connection
-	config      *config.Config
-	logger      interfaces.Logger
-	mu          sync.RWMutex
-	closed      bool
+	idle    chan idleConn
+	config  *config.Config
+	logger  interfaces.Logger
+	name    string
+	address string
+
+	// readOnly — последнее известное значение box.info.ro; до первой проверки
+	// берётся из роли экземпляра в конфигурации
+	readOnly  atomic.Bool
+	roleKnown atomic.Bool
+
+	minSize       int
+	maxSize       int
+	idleTimeout   time.Duration
+	checkInterval time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	open    int // простаивающие, выданные и открываемые соединения
+	dialErr error
+	backoff time.Duration
+	retryAt time.Time
+
+	refill chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	inUse        atomic.Int64
+	waitCount    atomic.Uint64
+	waitDuration atomic.Int64
+	timeouts     atomic.Uint64
+	dialFailures atomic.Uint64
 }
 
-func NewConnectionPool(cfg *config.Config, logger interfaces.Logger, poolSize int) (*ConnectionPool, error) {
+// NewConnectionPool не возвращает ошибку, если Tarantool недоступен: сервис запускается
+// в деградированном режиме, запросы к базе завершаются ошибкой, а пул подключается
+// в фоне, как только Tarantool поднимется
+func NewConnectionPool(cfg *config.Config, instance config.InstanceConfig, logger interfaces.Logger) *ConnectionPool {
+	poolCfg := cfg.Tarantool.Pool
+
+	maxSize := poolCfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultPoolMaxSize
+	}
+	minSize := poolCfg.MinSize
+	if minSize <= 0 {
+		minSize = defaultPoolMinSize
+	}
+	if minSize > maxSize {
+		minSize = maxSize
+	}
+	idleTimeout := poolCfg.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultPoolIdleTimeout
+	}
+	checkInterval := poolCfg.HealthCheckInterval
+	if checkInterval <= 0 {
+		checkInterval = defaultPoolHealthCheckInterval
+	}
+
 	pool := &ConnectionPool{
-		connections: make(chan *tarantool.Connection, poolSize),
-		config:      cfg,
-		logger:      logger,
+		idle:          make(chan idleConn, maxSize),
+		config:        cfg,
+		logger:        logger,
+		name:          instance.Name,
+		address:       fmt.Sprintf("%s:%d", instance.Host, instance.Port),
+		minSize:       minSize,
+		maxSize:       maxSize,
+		idleTimeout:   idleTimeout,
+		checkInterval: checkInterval,
+		refill:        make(chan struct{}, 1),
+		done:          make(chan struct{}),
 	}
 
-	for i := 0; i < poolSize; i++ {
-		conn, err := pool.createConnection()
-		if err != nil {
-			pool.Close()
-			return nil, fmt.Errorf("failed to create connection %d: %w", i, err)
-		}
-		pool.connections <- conn
+	pool.readOnly.Store(instance.Role == RoleReplica)
+
+	pool.fill()
+	if err := pool.unavailable(); err != nil {
+		logger.Warn("Tarantool is unavailable, starting in degraded mode", "instance", pool.name, "error", err)
+	} else {
+		logger.Info("Connection pool initialized", "instance", pool.name, "min_size", minSize, "max_size", maxSize)
 	}
 
-	logger.Info("Connection pool initialized", "size", poolSize)
-	return pool, nil
+	pool.wg.Add(1)
+	go pool.maintain()
+
+	return pool
 }
 
-func (p *ConnectionPool) createConnection() (*tarantool.Connection, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (p *ConnectionPool) createConnection(ctx context.Context) (*tarantool.Connection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
 	defer cancel()
 
 	dialer := tarantool.NetDialer{
-		Address:  fmt.Sprintf("%s:%d", p.config.Tarantool.Host, p.config.Tarantool.Port),
+		Address:  p.address,
 		User:     p.config.Tarantool.Username,
 		Password: p.config.Tarantool.Password,
 	}
@@ -63,68 +160,398 @@ func (p *ConnectionPool) createConnection() (*tarantool.Connection, error) {
 	return conn, nil
 }
 
-func (p *ConnectionPool) Get() (*tarantool.Connection, error) {
-	p.mu.RLock()
-	if p.closed {
-		p.mu.RUnlock()
-		return nil, fmt.Errorf("connection pool is closed")
+// Get выдаёт соединение из пула и ждёт свободное, пока не отменён ctx; ожидание
+// попадает в трассу отдельным span-ом pool.acquire
+func (p *ConnectionPool) Get(ctx context.Context) (*tarantool.Connection, error) {
+	ctx, span := tracer.Start(ctx, "pool.acquire")
+	conn, err := p.get(ctx, span)
+	tracing.End(span, err)
+	return conn, err
+}
+
+func (p *ConnectionPool) get(ctx context.Context, span trace.Span) (*tarantool.Connection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	p.mu.RUnlock()
 
-	select {
-	case conn := <-p.connections:
-		if conn == nil {
-			return nil, fmt.Errorf("connection pool is closed")
+	waiting := false
+
+	for {
+		select {
+		case c, ok := <-p.idle:
+			if !ok {
+				return nil, errPoolClosed
+			}
+			if conn := p.checkout(c); conn != nil {
+				return conn, nil
+			}
+			continue
+		default:
+		}
+
+		// Свободных нет: открываем новое соединение, если пул не достиг MaxSize
+		reserved, err := p.reserve(p.maxSize)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			conn, err := p.dial(ctx)
+			if err != nil {
+				return nil, err
+			}
+			p.inUse.Add(1)
+			return conn, nil
+		}
+
+		// Пул заполнен: время ожидания учитывается в статистике пула
+		if !waiting {
+			waiting = true
+			span.SetAttributes(attribute.Bool("pool.waited", true))
+			start := time.Now()
+			p.waitCount.Add(1)
+			defer func() {
+				p.waitDuration.Add(int64(time.Since(start)))
+			}()
+
+			if _, ok := ctx.Deadline(); !ok {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, acquireTimeout)
+				defer cancel()
+			}
+		}
+
+		select {
+		case c, ok := <-p.idle:
+			if !ok {
+				return nil, errPoolClosed
+			}
+			if conn := p.checkout(c); conn != nil {
+				return conn, nil
+			}
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				p.timeouts.Add(1)
+			}
+			return nil, fmt.Errorf("waiting for connection: %w", ctx.Err())
 		}
-		return conn, nil
-	case <-time.After(5 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for connection")
 	}
 }
 
+// checkout выдаёт простаивавшее соединение или отбрасывает его, если оно закрылось
+func (p *ConnectionPool) checkout(c idleConn) *tarantool.Connection {
+	if c.conn.ClosedNow() {
+		p.discard(c.conn)
+		return nil
+	}
+	p.inUse.Add(1)
+	return c.conn
+}
+
 func (p *ConnectionPool) Put(conn *tarantool.Connection) {
 	if conn == nil {
 		return
 	}
+	p.inUse.Add(-1)
 
-	p.mu.RLock()
-	if p.closed {
-		p.mu.RUnlock()
-		conn.Close()
+	if conn.ClosedNow() {
+		p.discard(conn)
 		return
 	}
-	p.mu.RUnlock()
+	p.release(idleConn{conn: conn, since: time.Now()})
+}
+
+// release возвращает соединение в очередь простаивающих
+func (p *ConnectionPool) release(c idleConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.closed {
+		select {
+		case p.idle <- c:
+			return
+		default:
+		}
+	}
+	p.open--
+	c.conn.Close()
+}
+
+// discard закрывает соединение, освобождает его место и будит фоновую горутину,
+// чтобы та восстановила MinSize соединений
+func (p *ConnectionPool) discard(conn *tarantool.Connection) {
+	conn.Close()
+
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
 
 	select {
-	case p.connections <- conn:
+	case p.refill <- struct{}{}:
 	default:
+	}
+}
+
+// reserve занимает место под новое соединение, если открыто меньше limit.
+// Пока действует пауза после неудачного подключения, сразу возвращает ошибку,
+// чтобы запросы не ждали таймаут подключения к недоступному Tarantool.
+func (p *ConnectionPool) reserve(limit int) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return false, errPoolClosed
+	}
+	if p.open >= limit {
+		return false, nil
+	}
+	if p.dialErr != nil && time.Now().Before(p.retryAt) {
+		return false, fmt.Errorf("tarantool is unavailable: %w", p.dialErr)
+	}
+	p.open++
+	return true, nil
+}
+
+// dial открывает соединение на месте, занятом reserve. Подключение, прерванное
+// отменой ctx, не считается неудачным и не включает паузу перед повторной попыткой.
+func (p *ConnectionPool) dial(ctx context.Context) (*tarantool.Connection, error) {
+	conn, err := p.createConnection(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		p.open--
+		return nil, err
+	}
+	if err != nil {
+		p.open--
+		p.dialFailures.Add(1)
+		p.backoff = nextBackoff(p.backoff)
+		p.retryAt = time.Now().Add(p.backoff)
+		if p.dialErr == nil {
+			p.logger.Warn("Tarantool connection failed, reconnecting", "instance", p.name, "error", err)
+		}
+		p.dialErr = err
+		return nil, err
+	}
+
+	if p.dialErr != nil {
+		p.logger.Info("Tarantool connection restored", "instance", p.name)
+		p.dialErr = nil
+	}
+	p.backoff = 0
+
+	if p.closed {
+		p.open--
 		conn.Close()
+		return nil, errPoolClosed
 	}
+	return conn, nil
 }
 
-func (p *ConnectionPool) Close() error {
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff < minReconnectBackoff {
+		return minReconnectBackoff
+	}
+	if backoff*2 > maxReconnectBackoff {
+		return maxReconnectBackoff
+	}
+	return backoff * 2
+}
+
+// unavailable возвращает последнюю ошибку подключения, пока Tarantool не доступен снова
+func (p *ConnectionPool) unavailable() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return p.dialErr
+}
+
+// fill открывает соединения, пока их меньше MinSize; останавливается на первой ошибке
+func (p *ConnectionPool) fill() {
+	for {
+		reserved, err := p.reserve(p.minSize)
+		if err != nil || !reserved {
+			return
+		}
+		conn, err := p.dial(context.Background())
+		if err != nil {
+			return
+		}
+		if err := p.checkRole(conn); err != nil {
+			p.discard(conn)
+			return
+		}
+		p.release(idleConn{conn: conn, since: time.Now()})
+	}
+}
+
+func (p *ConnectionPool) maintain() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		p.fill()
+
+		// Пока Tarantool недоступен, следующая попытка — по истечении паузы
+		var retry <-chan time.Time
+		p.mu.Lock()
+		if wait := time.Until(p.retryAt); p.dialErr != nil && wait > 0 {
+			retry = time.After(wait)
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.validate()
+		case <-p.refill:
+		case <-retry:
+		}
+	}
+}
+
+// validate проверяет простаивающие соединения: закрытые и не ответившие на запрос роли
+// отбрасываются, простаивающие дольше IdleTimeout закрываются сверх MinSize
+func (p *ConnectionPool) validate() {
+	for i := len(p.idle); i > 0; i-- {
+		var c idleConn
+		select {
+		case item, ok := <-p.idle:
+			if !ok {
+				return
+			}
+			c = item
+		default:
+			return
+		}
+
+		if c.conn.ClosedNow() || p.checkRole(c.conn) != nil {
+			p.discard(c.conn)
+			continue
+		}
+		if time.Since(c.since) > p.idleTimeout && p.closeIdle(c.conn) {
+			continue
+		}
+		p.release(c)
+	}
+}
+
+// closeIdle закрывает простаивающее соединение, если без него останется не меньше MinSize
+func (p *ConnectionPool) closeIdle(conn *tarantool.Connection) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.open <= p.minSize {
+		return false
+	}
+	p.open--
+	conn.Close()
+	return true
+}
+
+// checkRole запрашивает box.info.ro и запоминает роль экземпляра
+func (p *ConnectionPool) checkRole(conn *tarantool.Connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	var result []bool
+	err := conn.Do(tarantool.NewEvalRequest("return box.info.ro").Context(ctx)).GetTyped(&result)
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 {
+		return fmt.Errorf("box.info.ro returned no value")
+	}
+	p.setReadOnly(result[0])
+	return nil
+}
+
+func (p *ConnectionPool) setReadOnly(readOnly bool) {
+	previous := p.readOnly.Swap(readOnly)
+	if p.roleKnown.Swap(true) && previous == readOnly {
+		return
+	}
+	p.logger.Info("Tarantool instance role detected", "instance", p.name, "address", p.address, "role", roleName(readOnly))
+}
+
+// RefreshRole сразу перепроверяет роль экземпляра, не дожидаясь фоновой проверки
+func (p *ConnectionPool) RefreshRole(ctx context.Context) error {
+	return p.Execute(ctx, p.checkRole)
+}
+
+func (p *ConnectionPool) Name() string {
+	return p.name
+}
+
+func (p *ConnectionPool) Address() string {
+	return p.address
+}
+
+func (p *ConnectionPool) ReadOnly() bool {
+	return p.readOnly.Load()
+}
+
+// Available сообщает, что последнее подключение к экземпляру удалось
+func (p *ConnectionPool) Available() bool {
+	return p.unavailable() == nil
+}
 
+func (p *ConnectionPool) Close() error {
+	p.mu.Lock()
 	if p.closed {
+		p.mu.Unlock()
 		return nil
 	}
 
 	p.closed = true
-	close(p.connections)
+	close(p.done)
+	close(p.idle)
 
-	for conn := range p.connections {
-		if conn != nil {
-			conn.Close()
-		}
+	for c := range p.idle {
+		p.open--
+		c.conn.Close()
 	}
+	p.mu.Unlock()
 
-	p.logger.Info("Connection pool closed")
+	p.wg.Wait()
+
+	p.logger.Info("Connection pool closed", "instance", p.name)
 	return nil
 }
 
-func (p *ConnectionPool) Execute(fn func(*tarantool.Connection) error) error {
-	conn, err := p.Get()
+// Stats возвращает текущее состояние пула
+func (p *ConnectionPool) Stats() domain.PoolStats {
+	p.mu.Lock()
+	open := p.open
+	p.mu.Unlock()
+
+	return domain.PoolStats{
+		Size:         open,
+		MaxSize:      p.maxSize,
+		Idle:         len(p.idle),
+		InUse:        int(p.inUse.Load()),
+		WaitCount:    p.waitCount.Load(),
+		WaitDuration: time.Duration(p.waitDuration.Load()),
+		Timeouts:     p.timeouts.Load(),
+		DialFailures: p.dialFailures.Load(),
+	}
+}
+
+// Execute выполняет fn на соединении из пула. Если ctx отменён или истёк, пока запрос
+// ждал соединение или ответ Tarantool, возвращается domain.ErrCanceled или domain.ErrTimeout
+func (p *ConnectionPool) Execute(ctx context.Context, fn func(*tarantool.Connection) error) error {
+	err := p.execute(ctx, fn)
+	if err != nil && ctx.Err() != nil {
+		return contextError(ctx.Err())
+	}
+	return err
+}
+
+func (p *ConnectionPool) execute(ctx context.Context, fn func(*tarantool.Connection) error) error {
+	conn, err := p.Get(ctx)
 	if err != nil {
 		return err
 	}
@@ -132,3 +559,21 @@ func (p *ConnectionPool) Execute(fn func(*tarantool.Connection) error) error {
 
 	return fn(conn)
 }
+
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return domain.ErrTimeout
+	}
+	return domain.ErrCanceled
+}
+
+// databaseError логирует сбой запроса и возвращает domain.ErrDatabaseError;
+// прерванный запрос не считается сбоем и возвращается как есть
+func databaseError(logger interfaces.Logger, err error, message string, keysAndValues ...interface{}) error {
+	if errors.Is(err, domain.ErrTimeout) || errors.Is(err, domain.ErrCanceled) {
+		logger.Debug(message, append(keysAndValues, "error", err)...)
+		return err
+	}
+	logger.Error(message, append(keysAndValues, "error", err)...)
+	return domain.ErrDatabaseError
+}
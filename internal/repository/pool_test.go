@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+
+	"github.com/tarantool/go-tarantool/v2"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Fatal(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Sync() error                                    { return nil }
+
+func TestNextBackoff(t *testing.T) {
+	var backoff time.Duration
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+	}
+	for _, w := range want {
+		backoff = nextBackoff(backoff)
+		if backoff != w {
+			t.Fatalf("nextBackoff() = %v, want %v", backoff, w)
+		}
+	}
+
+	if got := nextBackoff(20 * time.Second); got != maxReconnectBackoff {
+		t.Errorf("nextBackoff(20s) = %v, want %v", got, maxReconnectBackoff)
+	}
+}
+
+// Tarantool недоступен: пул создаётся, запросы сразу получают ошибку, не дожидаясь
+// таймаута подключения, а неудачные подключения видны в статистике
+func TestConnectionPool_Degraded(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	cfg := &config.Config{Tarantool: config.TarantoolConfig{
+		Timeout: time.Second,
+		Pool:    config.PoolConfig{MinSize: 2, MaxSize: 4},
+	}}
+	instance := config.InstanceConfig{Name: "test", Host: "127.0.0.1", Port: port, Role: RoleMaster}
+	pool := NewConnectionPool(cfg, instance, nopLogger{})
+	defer pool.Close()
+
+	start := time.Now()
+	_, err = pool.Get(context.Background())
+	if err == nil {
+		t.Fatal("ConnectionPool.Get() succeeded without Tarantool")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ConnectionPool.Get() took %v in degraded mode", elapsed)
+	}
+
+	stats := pool.Stats()
+	if stats.Size != 0 || stats.MaxSize != 4 || stats.DialFailures == 0 {
+		t.Errorf("ConnectionPool.Stats() = %+v, want no connections and dial failures", stats)
+	}
+	if pool.Available() {
+		t.Error("ConnectionPool.Available() = true without Tarantool")
+	}
+
+	// Отменённый запрос не ждёт соединение и получает ошибку домена
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pool.Execute(ctx, func(*tarantool.Connection) error { return nil }); err != domain.ErrCanceled {
+		t.Errorf("ConnectionPool.Execute() with canceled context error = %v, want %v", err, domain.ErrCanceled)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Errorf("ConnectionPool.Close() error = %v", err)
+	}
+	if _, err := pool.Get(context.Background()); err != errPoolClosed {
+		t.Errorf("ConnectionPool.Get() after Close error = %v, want %v", err, errPoolClosed)
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+func TestParseRecord(t *testing.T) {
+	repo := &TarantoolRepository{}
+
+	// Кортеж текущего формата: key, value, created_at, updated_at, deleted_at, is_deleted,
+	// expires_at, version, content_type
+	kv, err := repo.parseRecord([]interface{}{
+		"user:1", []interface{}{int8(1), "a"}, uint32(100), uint32(200), uint32(0), false,
+		uint32(300), uint64(4), domain.ContentTypeJSON,
+	})
+	if err != nil {
+		t.Fatalf("parseRecord() error = %v", err)
+	}
+	if kv.Key != "user:1" || kv.Version != 4 || kv.IsDeleted || kv.DeletedAt != nil {
+		t.Errorf("parseRecord() = %+v", kv)
+	}
+	if !kv.CreatedAt.Equal(time.Unix(100, 0)) || !kv.UpdatedAt.Equal(time.Unix(200, 0)) {
+		t.Errorf("parseRecord() timestamps = %v, %v", kv.CreatedAt, kv.UpdatedAt)
+	}
+	if kv.ExpiresAt == nil || !kv.ExpiresAt.Equal(time.Unix(300, 0)) {
+		t.Errorf("parseRecord() ExpiresAt = %v, want %v", kv.ExpiresAt, time.Unix(300, 0))
+	}
+	if items, ok := kv.Value.([]interface{}); !ok || len(items) != 2 {
+		t.Errorf("parseRecord() Value = %#v", kv.Value)
+	}
+
+	// Кортеж старого формата без срока жизни, версии и типа содержимого
+	kv, err = repo.parseRecord([]interface{}{"user:2", []byte{1, 2}, uint32(100), uint32(100), uint32(150), true})
+	if err != nil {
+		t.Fatalf("parseRecord() old format error = %v", err)
+	}
+	if kv.Version != 1 || kv.ExpiresAt != nil || kv.ContentType != domain.ContentTypeBinary {
+		t.Errorf("parseRecord() old format = %+v", kv)
+	}
+	if !kv.IsDeleted || kv.DeletedAt == nil || !kv.DeletedAt.Equal(time.Unix(150, 0)) {
+		t.Errorf("parseRecord() deleted = %v, %v", kv.IsDeleted, kv.DeletedAt)
+	}
+
+	for _, record := range [][]interface{}{
+		{"user:3", "a", uint32(1), uint32(1), uint32(0)},
+		{uint64(3), "a", uint32(1), uint32(1), uint32(0), false},
+		{"user:3", "a", uint32(1), uint32(1), uint32(0), "no"},
+		{"user:3", struct{}{}, uint32(1), uint32(1), uint32(0), false},
+	} {
+		if _, err := repo.parseRecord(record); err == nil {
+			t.Errorf("parseRecord(%v) succeeded", record)
+		}
+	}
+}
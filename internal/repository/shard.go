@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// ringReplicas — число точек каждого шарда на кольце; чем их больше, тем ровнее
+// распределяются ключи
+const ringReplicas = 128
+
+// Shard — набор экземпляров Tarantool (мастер и реплики), хранящий часть ключей
+type Shard struct {
+	Name    string
+	Cluster *Cluster
+}
+
+type Shards []Shard
+
+// NewShards подключается к шардам из tarantool.shards; если список пуст, создаётся
+// единственный шард из tarantool.instances или tarantool.host:port
+func NewShards(cfg *config.Config, logger interfaces.Logger) (Shards, error) {
+	configs := cfg.Tarantool.Shards
+	if len(configs) == 0 {
+		configs = []config.ShardConfig{{
+			Name:      "default",
+			Host:      cfg.Tarantool.Host,
+			Port:      cfg.Tarantool.Port,
+			Instances: cfg.Tarantool.Instances,
+		}}
+	}
+
+	names := make(map[string]bool, len(configs))
+	for _, shard := range configs {
+		if shard.Name == "" {
+			return nil, fmt.Errorf("shard name is required")
+		}
+		if names[shard.Name] {
+			return nil, fmt.Errorf("duplicate shard name %q", shard.Name)
+		}
+		names[shard.Name] = true
+	}
+
+	shards := make(Shards, 0, len(configs))
+	for _, shard := range configs {
+		instances := shard.Instances
+		if len(instances) == 0 {
+			instances = []config.InstanceConfig{{
+				Name: shard.Name,
+				Host: shard.Host,
+				Port: shard.Port,
+				Role: RoleMaster,
+			}}
+		}
+		shards = append(shards, Shard{
+			Name:    shard.Name,
+			Cluster: NewCluster(cfg, instances, logger),
+		})
+	}
+
+	return shards, nil
+}
+
+// Stats суммирует состояние пулов всех шардов
+func (s Shards) Stats() domain.PoolStats {
+	var total domain.PoolStats
+	for _, shard := range s {
+		addPoolStats(&total, shard.Cluster.Stats())
+	}
+	return total
+}
+
+// hashRing — консистентное хеширование ключей по шардам: добавление шарда переносит
+// на него только около 1/N ключей, остальные остаются на месте
+type hashRing struct {
+	points []uint64
+	shards []int
+}
+
+func newHashRing(names []string) *hashRing {
+	type point struct {
+		hash  uint64
+		shard int
+	}
+
+	points := make([]point, 0, len(names)*ringReplicas)
+	for shard, name := range names {
+		for i := 0; i < ringReplicas; i++ {
+			points = append(points, point{hash: hashKey(name + "#" + strconv.Itoa(i)), shard: shard})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	ring := &hashRing{
+		points: make([]uint64, len(points)),
+		shards: make([]int, len(points)),
+	}
+	for i, p := range points {
+		ring.points[i] = p.hash
+		ring.shards[i] = p.shard
+	}
+	return ring
+}
+
+// shard возвращает индекс шарда, которому принадлежит key: первую точку кольца
+// не меньше хеша ключа
+func (r *hashRing) shard(key string) int {
+	hash := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.shards[i]
+}
+
+// hashKey — FNV-1a с финальным перемешиванием из MurmurHash3: у FNV ключи, различающиеся
+// последними символами, отличаются в основном младшими битами и ложатся на кольцо рядом
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// fanOut вызывает fn для всех шардов параллельно и возвращает первую по порядку шардов ошибку
+func fanOut[T any](shards []T, fn func(i int, shard T) error) error {
+	errs := make([]error, len(shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard T) {
+			defer wg.Done()
+			errs[i] = fn(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
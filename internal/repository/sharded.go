@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// ShardedRepository делит ключи между репозиториями шардов по кольцу консистентного
+// хеширования. Операции с одним ключом выполняет шард-владелец, списки и обходы
+// запрашиваются у всех шардов и сливаются в порядке ключей.
+type ShardedRepository struct {
+	names  []string
+	shards []interfaces.KVRepository
+	ring   *hashRing
+	logger interfaces.Logger
+}
+
+// NewShardedRepository принимает имена шардов и их репозитории в одном порядке;
+// имена определяют положение шардов на кольце
+func NewShardedRepository(names []string, shards []interfaces.KVRepository, logger interfaces.Logger) interfaces.KVRepository {
+	return &ShardedRepository{
+		names:  names,
+		shards: shards,
+		ring:   newHashRing(names),
+		logger: logger,
+	}
+}
+
+func (r *ShardedRepository) Namespace(name string) interfaces.KVRepository {
+	shards := make([]interfaces.KVRepository, len(r.shards))
+	for i, shard := range r.shards {
+		shards[i] = shard.Namespace(name)
+	}
+
+	scoped := *r
+	scoped.shards = shards
+	return &scoped
+}
+
+func (r *ShardedRepository) shard(key string) interfaces.KVRepository {
+	return r.shards[r.ring.shard(key)]
+}
+
+func (r *ShardedRepository) Create(ctx context.Context, kv *domain.KV) error {
+	return r.shard(kv.Key).Create(ctx, kv)
+}
+
+func (r *ShardedRepository) Get(ctx context.Context, key string) (*domain.KV, error) {
+	return r.shard(key).Get(ctx, key)
+}
+
+func (r *ShardedRepository) Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error) {
+	return r.shard(kv.Key).Update(ctx, kv, expectedVersion)
+}
+
+func (r *ShardedRepository) CompareAndSwap(ctx context.Context, kv *domain.KV, expectedValue interface{}, expectedVersion uint64) (*domain.KV, error) {
+	return r.shard(kv.Key).CompareAndSwap(ctx, kv, expectedValue, expectedVersion)
+}
+
+func (r *ShardedRepository) PutIfAbsent(ctx context.Context, kv *domain.KV) (*domain.KV, error) {
+	return r.shard(kv.Key).PutIfAbsent(ctx, kv)
+}
+
+func (r *ShardedRepository) Increment(ctx context.Context, key string, delta, initial int64, expiresAt *time.Time) (*domain.KV, *domain.KV, error) {
+	return r.shard(key).Increment(ctx, key, delta, initial, expiresAt)
+}
+
+func (r *ShardedRepository) Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	return r.shard(key).Delete(ctx, key, expectedVersion)
+}
+
+func (r *ShardedRepository) SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	return r.shard(key).SoftDelete(ctx, key, expectedVersion)
+}
+
+func (r *ShardedRepository) Restore(ctx context.Context, key string) (*domain.KV, error) {
+	return r.shard(key).Restore(ctx, key)
+}
+
+func (r *ShardedRepository) History(ctx context.Context, key string) ([]*domain.KV, error) {
+	return r.shard(key).History(ctx, key)
+}
+
+// List сливает живые записи шардов. Смещение применяется к общему порядку ключей,
+// поэтому каждый шард читается с начала; продолжение по After выполняется на всех
+// шардах с того же ключа.
+func (r *ShardedRepository) List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	return r.merge(ctx, opts, func(ctx context.Context, shard interfaces.KVRepository, shardOpts domain.ListOptions) (*domain.ListPage, error) {
+		return shard.List(ctx, shardOpts)
+	})
+}
+
+func (r *ShardedRepository) ListIncludingDeleted(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	return r.merge(ctx, opts, func(ctx context.Context, shard interfaces.KVRepository, shardOpts domain.ListOptions) (*domain.ListPage, error) {
+		return shard.ListIncludingDeleted(ctx, shardOpts)
+	})
+}
+
+func (r *ShardedRepository) Scan(ctx context.Context, opts domain.ScanOptions) (*domain.ListPage, error) {
+	listOpts := domain.ListOptions{Limit: opts.Limit, After: opts.After, SkipCount: true}
+	return r.merge(ctx, listOpts, func(ctx context.Context, shard interfaces.KVRepository, shardOpts domain.ListOptions) (*domain.ListPage, error) {
+		scanOpts := opts
+		scanOpts.Limit = shardOpts.Limit
+		scanOpts.After = shardOpts.After
+		return shard.Scan(ctx, scanOpts)
+	})
+}
+
+// shardCursor — позиция слияния в выборке одного шарда
+type shardCursor struct {
+	items   []*domain.KV
+	after   string
+	fetched bool
+	done    bool
+}
+
+// merge выбирает страницу из отсортированных по ключу выборок всех шардов. Шард
+// дочитывается, когда его записи в буфере кончились; страница шарда может оказаться
+// пустой (например, из одних истёкших записей), поэтому чтение повторяется до записи
+// или конца выборки.
+func (r *ShardedRepository) merge(ctx context.Context, opts domain.ListOptions, fetch func(context.Context, interfaces.KVRepository, domain.ListOptions) (*domain.ListPage, error)) (*domain.ListPage, error) {
+	skip := 0
+	if opts.After == "" {
+		skip = opts.Offset
+	}
+	pageSize := opts.Limit + skip
+
+	cursors := make([]*shardCursor, len(r.shards))
+	for i := range cursors {
+		cursors[i] = &shardCursor{after: opts.After}
+	}
+
+	var mu sync.Mutex
+	page := &domain.ListPage{Items: make([]*domain.KV, 0, opts.Limit)}
+
+	refill := func() error {
+		return fanOut(r.shards, func(i int, shard interfaces.KVRepository) error {
+			cursor := cursors[i]
+			for len(cursor.items) == 0 && !cursor.done {
+				// Общее число записей нужно только с первой страницы шарда
+				shardPage, err := fetch(ctx, shard, domain.ListOptions{
+					Limit:     pageSize,
+					After:     cursor.after,
+					SkipCount: opts.SkipCount || cursor.fetched,
+				})
+				if err != nil {
+					return err
+				}
+				if !cursor.fetched {
+					mu.Lock()
+					page.Total += shardPage.Total
+					mu.Unlock()
+				}
+
+				cursor.fetched = true
+				cursor.items = shardPage.Items
+				cursor.after = shardPage.NextKey
+				cursor.done = shardPage.NextKey == ""
+			}
+			return nil
+		})
+	}
+
+	for len(page.Items) < opts.Limit {
+		if needsRefill(cursors) {
+			if err := refill(); err != nil {
+				return nil, err
+			}
+		}
+
+		next := -1
+		for i, cursor := range cursors {
+			if len(cursor.items) == 0 {
+				continue
+			}
+			if next < 0 || cursor.items[0].Key < cursors[next].items[0].Key {
+				next = i
+			}
+		}
+		if next < 0 {
+			return page, nil
+		}
+
+		kv := cursors[next].items[0]
+		cursors[next].items = cursors[next].items[1:]
+		if skip > 0 {
+			skip--
+			continue
+		}
+		page.Items = append(page.Items, kv)
+	}
+
+	// Страница заполнена: продолжение возможно, если хоть один шард не дочитан
+	for _, cursor := range cursors {
+		if len(page.Items) > 0 && (len(cursor.items) > 0 || !cursor.done) {
+			page.NextKey = page.Items[len(page.Items)-1].Key
+			break
+		}
+	}
+	return page, nil
+}
+
+func needsRefill(cursors []*shardCursor) bool {
+	for _, cursor := range cursors {
+		if len(cursor.items) == 0 && !cursor.done {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch выполняет операции на шардах-владельцах ключей и возвращает результаты в порядке
+// операций. Атомарный пакет возможен, только если все ключи принадлежат одному шарду.
+// Если пакет не выполнился на части шардов, операции остальных уже применены, поэтому
+// результаты возвращаются с ошибкой у каждой операции неудачных шардов.
+func (r *ShardedRepository) Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchItemResult, error) {
+	groups := make(map[int][]int)
+	for i, op := range ops {
+		shard := r.ring.shard(op.Key)
+		groups[shard] = append(groups[shard], i)
+	}
+
+	if len(groups) == 1 {
+		for shard := range groups {
+			return r.shards[shard].Batch(ctx, ops, atomic)
+		}
+	}
+	if atomic {
+		return nil, domain.ErrCrossShard
+	}
+
+	results := make([]domain.BatchItemResult, len(ops))
+	errs := make([]error, len(r.shards))
+	fanOut(r.shards, func(i int, shard interfaces.KVRepository) error {
+		indexes, ok := groups[i]
+		if !ok {
+			return nil
+		}
+
+		shardOps := make([]domain.BatchOperation, len(indexes))
+		for j, index := range indexes {
+			shardOps[j] = ops[index]
+		}
+
+		shardResults, err := shard.Batch(ctx, shardOps, false)
+		if err != nil {
+			r.logger.Error("Batch failed on shard", "shard", r.names[i], "size", len(indexes), "error", err)
+			errs[i] = err
+			for _, index := range indexes {
+				results[index] = domain.BatchItemResult{Op: ops[index].Op, Key: ops[index].Key, Error: err.Error()}
+			}
+			return nil
+		}
+		for j, result := range shardResults {
+			results[indexes[j]] = result
+		}
+		return nil
+	})
+
+	// Ошибка без результатов допустима, только если ни один шард пакет не применил
+	var failed int
+	var firstErr error
+	for _, err := range errs {
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 && failed == len(groups) {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// PurgeExpired удаляет до limit истёкших записей на каждом шарде
+func (r *ShardedRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	return r.sum(func(shard interfaces.KVRepository) (int, error) {
+		return shard.PurgeExpired(ctx, limit)
+	})
+}
+
+// Changes читает журналы всех шардов. LSN выдаются каждым шардом независимо, поэтому
+// позиция хранит LSN каждого шарда и сдвигается только на отданные записи. Записи
+// сливаются по времени изменения, порядок записей одного шарда сохраняется.
+func (r *ShardedRepository) Changes(ctx context.Context, cursor domain.ChangesCursor, limit int) ([]domain.ChangeLogEntry, domain.ChangesCursor, error) {
+	if len(cursor) == 0 {
+		cursor = make(domain.ChangesCursor, len(r.shards))
+	}
+	if len(cursor) != len(r.shards) {
+		return nil, nil, domain.ErrInvalidCursor
+	}
+
+	logs := make([][]domain.ChangeLogEntry, len(r.shards))
+	err := fanOut(r.shards, func(i int, shard interfaces.KVRepository) error {
+		entries, _, err := shard.Changes(ctx, domain.ChangesCursor{cursor[i]}, limit)
+		logs[i] = entries
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := append(domain.ChangesCursor(nil), cursor...)
+	entries := make([]domain.ChangeLogEntry, 0, limit)
+	for len(entries) < limit {
+		shard := -1
+		for i, log := range logs {
+			if len(log) == 0 {
+				continue
+			}
+			if shard < 0 || log[0].Timestamp.Before(logs[shard][0].Timestamp) {
+				shard = i
+			}
+		}
+		if shard < 0 {
+			break
+		}
+
+		entry := logs[shard][0]
+		logs[shard] = logs[shard][1:]
+		entry.Shard = r.names[shard]
+		next[shard] = entry.LSN
+		entries = append(entries, entry)
+	}
+
+	return entries, next, nil
+}
+
+// TrimChangelog удаляет до limit старых записей журнала на каждом шарде
+func (r *ShardedRepository) TrimChangelog(ctx context.Context, before time.Time, limit int) (int, error) {
+	return r.sum(func(shard interfaces.KVRepository) (int, error) {
+		return shard.TrimChangelog(ctx, before, limit)
+	})
+}
+
+func (r *ShardedRepository) Close() error {
+	var firstErr error
+	for i, shard := range r.shards {
+		if err := shard.Close(); err != nil {
+			r.logger.Error("Failed to close shard", "shard", r.names[i], "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (r *ShardedRepository) sum(fn func(interfaces.KVRepository) (int, error)) (int, error) {
+	var mu sync.Mutex
+	var total int
+
+	err := fanOut(r.shards, func(i int, shard interfaces.KVRepository) error {
+		n, err := fn(shard)
+		mu.Lock()
+		total += n
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// ShardedLockRepository хранит аренду на шарде, которому её имя принадлежит по тому же
+// кольцу, что и ключи, поэтому сбой одного шарда затрагивает только его аренды.
+// Fencing token растёт в пределах шарда: после изменения списка шардов аренда может
+// переехать на шард с меньшими токенами, поэтому менять список нужно без активных аренд.
+type ShardedLockRepository struct {
+	shards []interfaces.LockRepository
+	ring   *hashRing
+}
+
+// NewShardedLockRepository принимает имена шардов и их репозитории в одном порядке
+func NewShardedLockRepository(names []string, shards []interfaces.LockRepository) interfaces.LockRepository {
+	return &ShardedLockRepository{
+		shards: shards,
+		ring:   newHashRing(names),
+	}
+}
+
+func (r *ShardedLockRepository) shard(name string) interfaces.LockRepository {
+	return r.shards[r.ring.shard(name)]
+}
+
+func (r *ShardedLockRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (*domain.Lock, error) {
+	return r.shard(name).Acquire(ctx, name, owner, ttl)
+}
+
+func (r *ShardedLockRepository) Renew(ctx context.Context, name, owner string, token uint64, ttl time.Duration) (*domain.Lock, error) {
+	return r.shard(name).Renew(ctx, name, owner, token, ttl)
+}
+
+func (r *ShardedLockRepository) Release(ctx context.Context, name, owner string, token uint64) (*domain.Lock, error) {
+	return r.shard(name).Release(ctx, name, owner, token)
+}
+
+func (r *ShardedLockRepository) Get(ctx context.Context, name string) (*domain.Lock, error) {
+	return r.shard(name).Get(ctx, name)
+}
+
+// PurgeExpired удаляет до limit истёкших аренд на каждом шарде
+func (r *ShardedLockRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	var mu sync.Mutex
+	var total int
+
+	err := fanOut(r.shards, func(i int, shard interfaces.LockRepository) error {
+		n, err := shard.PurgeExpired(ctx, limit)
+		mu.Lock()
+		total += n
+		mu.Unlock()
+		return err
+	})
+	return total, err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// ShardedNamespaceRepository создаёт и удаляет namespace на всех шардах и складывает
+// их статистику. Namespace, созданный не на всех шардах (например, из-за сбоя одного
+// из них), не находится, пока повторный Create не досоздаст его на оставшихся.
+type ShardedNamespaceRepository struct {
+	shards []interfaces.NamespaceRepository
+}
+
+func NewShardedNamespaceRepository(shards []interfaces.NamespaceRepository) interfaces.NamespaceRepository {
+	return &ShardedNamespaceRepository{shards: shards}
+}
+
+func (r *ShardedNamespaceRepository) Create(ctx context.Context, name string) (*domain.Namespace, error) {
+	existing := make([]bool, len(r.shards))
+	err := fanOut(r.shards, func(i int, shard interfaces.NamespaceRepository) error {
+		_, err := shard.Create(ctx, name)
+		if err == domain.ErrNamespaceExists {
+			existing[i] = true
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists := true
+	for _, e := range existing {
+		exists = exists && e
+	}
+	if exists {
+		return nil, domain.ErrNamespaceExists
+	}
+
+	return r.Get(ctx, name)
+}
+
+func (r *ShardedNamespaceRepository) Get(ctx context.Context, name string) (*domain.Namespace, error) {
+	namespaces := make([]*domain.Namespace, len(r.shards))
+	err := fanOut(r.shards, func(i int, shard interfaces.NamespaceRepository) error {
+		namespace, err := shard.Get(ctx, name)
+		namespaces[i] = namespace
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeNamespaces(namespaces), nil
+}
+
+// List возвращает namespace, созданные на всех шардах
+func (r *ShardedNamespaceRepository) List(ctx context.Context) ([]*domain.Namespace, error) {
+	lists := make([][]*domain.Namespace, len(r.shards))
+	err := fanOut(r.shards, func(i int, shard interfaces.NamespaceRepository) error {
+		namespaces, err := shard.List(ctx)
+		lists[i] = namespaces
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string][]*domain.Namespace)
+	for _, namespaces := range lists {
+		for _, namespace := range namespaces {
+			byName[namespace.Name] = append(byName[namespace.Name], namespace)
+		}
+	}
+
+	result := make([]*domain.Namespace, 0, len(byName))
+	for _, namespaces := range byName {
+		if len(namespaces) == len(r.shards) {
+			result = append(result, mergeNamespaces(namespaces))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// Drop удаляет namespace со всех шардов, где он есть
+func (r *ShardedNamespaceRepository) Drop(ctx context.Context, name string) (*domain.Namespace, error) {
+	namespaces := make([]*domain.Namespace, len(r.shards))
+	err := fanOut(r.shards, func(i int, shard interfaces.NamespaceRepository) error {
+		namespace, err := shard.Drop(ctx, name)
+		if err == domain.ErrNamespaceNotFound {
+			return nil
+		}
+		namespaces[i] = namespace
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := namespaces[:0]
+	for _, namespace := range namespaces {
+		if namespace != nil {
+			dropped = append(dropped, namespace)
+		}
+	}
+	if len(dropped) == 0 {
+		return nil, domain.ErrNamespaceNotFound
+	}
+
+	return mergeNamespaces(dropped), nil
+}
+
+// mergeNamespaces складывает статистику namespace с разных шардов
+func mergeNamespaces(namespaces []*domain.Namespace) *domain.Namespace {
+	merged := *namespaces[0]
+	for _, namespace := range namespaces[1:] {
+		if namespace.CreatedAt.Before(merged.CreatedAt) {
+			merged.CreatedAt = namespace.CreatedAt
+		}
+		merged.Keys += namespace.Keys
+		merged.Total += namespace.Total
+		merged.Bytes += namespace.Bytes
+		merged.Revisions += namespace.Revisions
+		merged.ChangelogEntries += namespace.ChangelogEntries
+	}
+	return &merged
+}
@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// memoryShard — шард в памяти с постраничной выдачей, как у TarantoolRepository:
+// NextKey равен последнему просмотренному ключу, даже если запись отфильтрована
+type memoryShard struct {
+	interfaces.KVRepository
+	records map[string]*domain.KV
+	// expired — ключи, которые List пропускает, как истёкшие записи
+	expired map[string]bool
+	// changelog — журнал шарда с собственной нумерацией LSN
+	changelog []domain.ChangeLogEntry
+	// batchErr — ошибка, которой завершается Batch, как при недоступном шарде
+	batchErr error
+}
+
+func newMemoryShard() *memoryShard {
+	return &memoryShard{records: map[string]*domain.KV{}, expired: map[string]bool{}}
+}
+
+func (s *memoryShard) Namespace(name string) interfaces.KVRepository {
+	return s
+}
+
+func (s *memoryShard) Create(ctx context.Context, kv *domain.KV) error {
+	s.records[kv.Key] = kv
+	return nil
+}
+
+func (s *memoryShard) Get(ctx context.Context, key string) (*domain.KV, error) {
+	kv, ok := s.records[key]
+	if !ok {
+		return nil, domain.ErrKeyNotFound
+	}
+	return kv, nil
+}
+
+func (s *memoryShard) List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	keys := make([]string, 0, len(s.records))
+	for key := range s.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	page := &domain.ListPage{}
+	if !opts.SkipCount {
+		page.Total = len(keys)
+	}
+
+	seen := 0
+	for _, key := range keys {
+		if opts.After != "" && key <= opts.After {
+			continue
+		}
+		if opts.After == "" && opts.Offset > 0 {
+			opts.Offset--
+			continue
+		}
+		seen++
+		if !s.expired[key] {
+			page.Items = append(page.Items, s.records[key])
+		}
+		if seen == opts.Limit {
+			page.NextKey = key
+			break
+		}
+	}
+	return page, nil
+}
+
+func (s *memoryShard) Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchItemResult, error) {
+	if s.batchErr != nil {
+		return nil, s.batchErr
+	}
+	results := make([]domain.BatchItemResult, len(ops))
+	for i, op := range ops {
+		results[i] = domain.BatchItemResult{Op: op.Op, Key: op.Key}
+	}
+	return results, nil
+}
+
+func (s *memoryShard) Changes(ctx context.Context, cursor domain.ChangesCursor, limit int) ([]domain.ChangeLogEntry, domain.ChangesCursor, error) {
+	since := cursor[0]
+	var entries []domain.ChangeLogEntry
+	for _, entry := range s.changelog {
+		if entry.LSN > since && len(entries) < limit {
+			entries = append(entries, entry)
+			since = entry.LSN
+		}
+	}
+	return entries, domain.ChangesCursor{since}, nil
+}
+
+func newTestShardedRepository(n int) (*ShardedRepository, []*memoryShard) {
+	names := make([]string, n)
+	shards := make([]interfaces.KVRepository, n)
+	memory := make([]*memoryShard, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("shard-%d", i+1)
+		memory[i] = newMemoryShard()
+		shards[i] = memory[i]
+	}
+	return NewShardedRepository(names, shards, nopLogger{}).(*ShardedRepository), memory
+}
+
+func TestHashRing_Distribution(t *testing.T) {
+	const keys = 30000
+
+	ring := newHashRing([]string{"shard-1", "shard-2", "shard-3"})
+	counts := make([]int, 3)
+	owners := make([]int, keys)
+	for i := 0; i < keys; i++ {
+		owners[i] = ring.shard(fmt.Sprintf("user:%d", i))
+		counts[owners[i]]++
+	}
+	for shard, count := range counts {
+		if share := float64(count) / keys; share < 0.25 || share > 0.42 {
+			t.Errorf("shard %d owns %.0f%% of keys", shard, share*100)
+		}
+	}
+
+	// Новый шард забирает ключи только у существующих, остальные не переезжают
+	grown := newHashRing([]string{"shard-1", "shard-2", "shard-3", "shard-4"})
+	moved := 0
+	for i := 0; i < keys; i++ {
+		owner := grown.shard(fmt.Sprintf("user:%d", i))
+		if owner == owners[i] {
+			continue
+		}
+		if owner != 3 {
+			t.Fatalf("key user:%d moved from shard %d to %d", i, owners[i], owner)
+		}
+		moved++
+	}
+	if share := float64(moved) / keys; share > 0.35 {
+		t.Errorf("%.0f%% of keys moved to the new shard", share*100)
+	}
+}
+
+func TestShardedRepository_SingleKey(t *testing.T) {
+	repo, memory := newTestShardedRepository(3)
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		if err := repo.Create(ctx, &domain.KV{Key: fmt.Sprintf("key:%02d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	used := 0
+	for i, shard := range memory {
+		for key := range shard.records {
+			if owner := repo.ring.shard(key); owner != i {
+				t.Errorf("key %s stored on shard %d, owner %d", key, i, owner)
+			}
+		}
+		if len(shard.records) > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("keys stored on %d shards, want them spread", used)
+	}
+
+	if _, err := repo.Get(ctx, "key:07"); err != nil {
+		t.Errorf("ShardedRepository.Get() error = %v", err)
+	}
+}
+
+func TestShardedRepository_ListMerge(t *testing.T) {
+	repo, memory := newTestShardedRepository(3)
+	ctx := context.Background()
+
+	var want []string
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key:%02d", i)
+		repo.Create(ctx, &domain.KV{Key: key})
+		want = append(want, key)
+	}
+
+	// Страницы по курсору дают все ключи по порядку и без повторов
+	var got []string
+	after := ""
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("List() did not finish")
+		}
+		page, err := repo.List(ctx, domain.ListOptions{Limit: 7, After: after, SkipCount: after != ""})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after == "" && page.Total != 50 {
+			t.Errorf("List() Total = %d, want 50", page.Total)
+		}
+		for _, kv := range page.Items {
+			got = append(got, kv.Key)
+		}
+		if page.NextKey == "" {
+			break
+		}
+		after = page.NextKey
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List() pages = %v, want %v", got, want)
+	}
+
+	// Смещение отсчитывается в общем порядке ключей
+	page, err := repo.List(ctx, domain.ListOptions{Limit: 5, Offset: 10, SkipCount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := pageKeys(page); keys != strings.Join(want[10:15], ",") {
+		t.Errorf("List(offset=10) = %s, want %v", keys, want[10:15])
+	}
+
+	// Страница шарда из одних отфильтрованных записей не обрывает выборку
+	for _, shard := range memory {
+		for key := range shard.records {
+			if key < "key:20" {
+				shard.expired[key] = true
+			}
+		}
+	}
+	page, err = repo.List(ctx, domain.ListOptions{Limit: 3, SkipCount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := pageKeys(page); keys != "key:20,key:21,key:22" {
+		t.Errorf("List() with expired records = %s, want key:20,key:21,key:22", keys)
+	}
+}
+
+func TestShardedRepository_Batch(t *testing.T) {
+	repo, _ := newTestShardedRepository(3)
+	ctx := context.Background()
+
+	var ops []domain.BatchOperation
+	for i := 0; i < 10; i++ {
+		ops = append(ops, domain.BatchOperation{Op: domain.BatchOpGet, Key: fmt.Sprintf("key:%d", i)})
+	}
+
+	results, err := repo.Batch(ctx, ops, false)
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	for i, result := range results {
+		if result.Key != ops[i].Key {
+			t.Errorf("Batch() result %d key = %s, want %s", i, result.Key, ops[i].Key)
+		}
+	}
+
+	if _, err := repo.Batch(ctx, ops, true); err != domain.ErrCrossShard {
+		t.Errorf("atomic Batch() across shards error = %v, want %v", err, domain.ErrCrossShard)
+	}
+
+	// Атомарный пакет с ключами одного шарда выполняется этим шардом
+	owner := repo.ring.shard("key:0")
+	single := []domain.BatchOperation{ops[0]}
+	for _, op := range ops[1:] {
+		if repo.ring.shard(op.Key) == owner {
+			single = append(single, op)
+		}
+	}
+	if _, err := repo.Batch(ctx, single, true); err != nil {
+		t.Errorf("atomic Batch() on one shard error = %v", err)
+	}
+}
+
+func TestShardedRepository_BatchShardFailure(t *testing.T) {
+	repo, shards := newTestShardedRepository(3)
+	ctx := context.Background()
+
+	var ops []domain.BatchOperation
+	for i := 0; i < 10; i++ {
+		ops = append(ops, domain.BatchOperation{Op: domain.BatchOpGet, Key: fmt.Sprintf("key:%d", i)})
+	}
+	failed := repo.ring.shard("key:0")
+	shards[failed].batchErr = domain.ErrDatabaseError
+
+	// Операции остальных шардов уже выполнены, поэтому ошибка возвращается по каждой операции
+	results, err := repo.Batch(ctx, ops, false)
+	if err != nil {
+		t.Fatalf("Batch() error = %v, want per-operation errors", err)
+	}
+	for i, result := range results {
+		wantErr := ""
+		if repo.ring.shard(ops[i].Key) == failed {
+			wantErr = domain.ErrDatabaseError.Error()
+		}
+		if result.Key != ops[i].Key || result.Op != ops[i].Op || result.Error != wantErr {
+			t.Errorf("Batch() result %d = %+v, want key %s with error %q", i, result, ops[i].Key, wantErr)
+		}
+	}
+
+	// Если не выполнился ни один шард, возвращается сама ошибка
+	for _, shard := range shards {
+		shard.batchErr = domain.ErrDatabaseError
+	}
+	if _, err := repo.Batch(ctx, ops, false); err != domain.ErrDatabaseError {
+		t.Errorf("Batch() on failed shards error = %v, want %v", err, domain.ErrDatabaseError)
+	}
+}
+
+func TestShardedRepository_Changes(t *testing.T) {
+	repo, memory := newTestShardedRepository(2)
+	ctx := context.Background()
+
+	// LSN шардов пересекаются, время изменений чередуется
+	start := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		memory[0].changelog = append(memory[0].changelog, domain.ChangeLogEntry{
+			LSN: uint64(i + 1), Key: fmt.Sprintf("a:%d", i), Timestamp: start.Add(time.Duration(2*i) * time.Second),
+		})
+		memory[1].changelog = append(memory[1].changelog, domain.ChangeLogEntry{
+			LSN: uint64(i + 1), Key: fmt.Sprintf("b:%d", i), Timestamp: start.Add(time.Duration(2*i+1) * time.Second),
+		})
+	}
+
+	var got []string
+	var cursor domain.ChangesCursor
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Changes() did not finish")
+		}
+		entries, next, err := repo.Changes(ctx, cursor, 3)
+		if err != nil {
+			t.Fatalf("Changes() error = %v", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		for _, entry := range entries {
+			got = append(got, entry.Shard+"/"+entry.Key)
+		}
+		cursor = next
+	}
+
+	want := "shard-1/a:0,shard-2/b:0,shard-1/a:1,shard-2/b:1,shard-1/a:2,shard-2/b:2," +
+		"shard-1/a:3,shard-2/b:3,shard-1/a:4,shard-2/b:4"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Changes() pages = %v, want %s", got, want)
+	}
+	if fmt.Sprint(cursor) != "[5 5]" {
+		t.Errorf("Changes() cursor = %v, want [5 5]", cursor)
+	}
+
+	if _, _, err := repo.Changes(ctx, domain.ChangesCursor{3}, 3); err != domain.ErrInvalidCursor {
+		t.Errorf("Changes() with single LSN error = %v, want %v", err, domain.ErrInvalidCursor)
+	}
+}
+
+// memoryLocks — аренды шарда в памяти
+type memoryLocks struct {
+	interfaces.LockRepository
+	locks map[string]*domain.Lock
+}
+
+func (l *memoryLocks) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (*domain.Lock, error) {
+	if lock, ok := l.locks[name]; ok && lock.Owner != owner {
+		return lock, domain.ErrLockHeld
+	}
+	l.locks[name] = &domain.Lock{Name: name, Owner: owner}
+	return l.locks[name], nil
+}
+
+func (l *memoryLocks) Get(ctx context.Context, name string) (*domain.Lock, error) {
+	lock, ok := l.locks[name]
+	if !ok {
+		return nil, domain.ErrLockNotFound
+	}
+	return lock, nil
+}
+
+func (l *memoryLocks) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	purged := len(l.locks)
+	l.locks = map[string]*domain.Lock{}
+	return purged, nil
+}
+
+func TestShardedLockRepository(t *testing.T) {
+	names := []string{"shard-1", "shard-2", "shard-3"}
+	shards := make([]interfaces.LockRepository, len(names))
+	memory := make([]*memoryLocks, len(names))
+	for i := range names {
+		memory[i] = &memoryLocks{locks: map[string]*domain.Lock{}}
+		shards[i] = memory[i]
+	}
+	repo := NewShardedLockRepository(names, shards)
+	ring := newHashRing(names)
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		if _, err := repo.Acquire(ctx, fmt.Sprintf("cron:%d", i), "worker-1", time.Minute); err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+	}
+
+	// Аренды распределяются по шардам тем же кольцом, что и ключи
+	used := 0
+	for i, shard := range memory {
+		for name := range shard.locks {
+			if owner := ring.shard(name); owner != i {
+				t.Errorf("lock %s stored on shard %d, owner %d", name, i, owner)
+			}
+		}
+		if len(shard.locks) > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Errorf("locks stored on %d shards, want them spread", used)
+	}
+
+	if _, err := repo.Acquire(ctx, "cron:7", "worker-2", time.Minute); err != domain.ErrLockHeld {
+		t.Errorf("Acquire() of held lock error = %v, want %v", err, domain.ErrLockHeld)
+	}
+	if lock, err := repo.Get(ctx, "cron:7"); err != nil || lock.Owner != "worker-1" {
+		t.Errorf("Get() = %+v, %v, want owner worker-1", lock, err)
+	}
+
+	if purged, err := repo.PurgeExpired(ctx, 100); err != nil || purged != 30 {
+		t.Errorf("PurgeExpired() = %d, %v, want 30", purged, err)
+	}
+}
+
+func pageKeys(page *domain.ListPage) string {
+	keys := make([]string, len(page.Items))
+	for i, kv := range page.Items {
+		keys[i] = kv.Key
+	}
+	return strings.Join(keys, ",")
+}
@@ -1,75 +1,87 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/tarantool/go-iproto"
 	"github.com/tarantool/go-tarantool/v2"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"kv-storage/internal/config"
 	"kv-storage/internal/domain"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/metrics"
+	"kv-storage/internal/tracing"
 	"time"
 )
 
+const defaultHistoryDepth = 10
+
+var (
+	tracer            = otel.Tracer("kv-storage/internal/repository")
+	dbSystemTarantool = semconv.DBSystemKey.String("tarantool")
+)
+
 type TarantoolRepository struct {
-	pool   *ConnectionPool
-	logger interfaces.Logger
-	config *config.Config
+	cluster   *Cluster
+	logger    interfaces.Logger
+	config    *config.Config
+	metrics   *metrics.Metrics
+	namespace string
 }
 
-func NewTarantoolRepository(cfg *config.Config, logger interfaces.Logger) (interfaces.KVRepository, error) {
-	pool, err := NewConnectionPool(cfg, logger, 10)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create connection pool: %w", err)
-	}
-
+// NewTarantoolRepository работает через переданный пул; пул закрывается в Close,
+// поэтому репозитории, которые делят его, должны быть остановлены раньше
+func NewTarantoolRepository(cluster *Cluster, cfg *config.Config, metrics *metrics.Metrics, logger interfaces.Logger) interfaces.KVRepository {
 	return &TarantoolRepository{
-		pool:   pool,
-		logger: logger,
-		config: cfg,
-	}, nil
+		cluster:   cluster,
+		logger:    logger,
+		config:    cfg,
+		metrics:   metrics,
+		namespace: domain.DefaultNamespace,
+	}
 }
 
-func (r *TarantoolRepository) Create(kv *domain.KV) error {
-	now := time.Now().Unix()
-
-	return r.pool.Execute(func(conn *tarantool.Connection) error {
-		_, err := conn.Do(
-			tarantool.NewInsertRequest("kv").Tuple([]interface{}{
-				kv.Key,
-				kv.Value,
-				uint32(now),
-				uint32(now),
-				uint32(0),
-				false,
-			}),
-		).Get()
+// Namespace возвращает репозиторий, работающий с space namespace name через тот же пул
+func (r *TarantoolRepository) Namespace(name string) interfaces.KVRepository {
+	scoped := *r
+	scoped.namespace = name
+	return &scoped
+}
 
-		if err != nil {
-			if terr, ok := err.(tarantool.Error); ok && terr.Code == 3 {
-				r.logger.Warn("Key already exists", "key", kv.Key)
-				return domain.ErrKeyAlreadyExists
-			}
-			r.logger.Error("Failed to create KV record", "key", kv.Key, "error", err)
-			return domain.ErrDatabaseError
-		}
+func (r *TarantoolRepository) Create(ctx context.Context, kv *domain.KV) error {
+	created, _, err := r.callMutation(ctx, "create", "kv_create", []interface{}{
+		kv.Key,
+		kv.Value,
+		kv.ContentType,
+		uint32(time.Now().Unix()),
+		expiresAtField(kv.ExpiresAt),
+	})
 
-		kv.CreatedAt = time.Unix(now, 0)
-		kv.UpdatedAt = time.Unix(now, 0)
-		kv.DeletedAt = nil
-		kv.IsDeleted = false
+	switch {
+	case isNamespaceMissing(err):
+		return domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyAlreadyExists):
+		r.logger.Warn("Key already exists", "key", kv.Key)
+		return err
+	case err != nil:
+		return databaseError(r.logger, err, "Failed to create KV record", "key", kv.Key)
+	}
 
-		r.logger.Info("KV record created", "key", kv.Key)
-		return nil
-	})
+	*kv = *created
+	r.logger.Info("KV record created", "key", kv.Key)
+	return nil
 }
 
-func (r *TarantoolRepository) Get(key string) (*domain.KV, error) {
+func (r *TarantoolRepository) Get(ctx context.Context, key string) (*domain.KV, error) {
 	var result []interface{}
 
-	err := r.pool.Execute(func(conn *tarantool.Connection) error {
+	err := r.read(ctx, "get", func(conn *tarantool.Connection) error {
 		resp, err := conn.Do(
-			tarantool.NewSelectRequest("kv").Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{key}),
+			tarantool.NewSelectRequest(r.space(spaceKV)).Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{key}).Context(ctx),
 		).Get()
 		if err != nil {
 			return fmt.Errorf("select failed: %w", err)
@@ -79,8 +91,10 @@ func (r *TarantoolRepository) Get(key string) (*domain.KV, error) {
 	})
 
 	if err != nil {
-		r.logger.Error("Failed to get KV record", "key", key, "error", err)
-		return nil, domain.ErrDatabaseError
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to get KV record", "key", key)
 	}
 
 	if len(result) == 0 {
@@ -91,165 +105,181 @@ func (r *TarantoolRepository) Get(key string) (*domain.KV, error) {
 	if !ok {
 		return nil, domain.ErrDatabaseError
 	}
-	kv := r.parseRecord(record)
+	kv, err := r.parseRecord(record)
+	if err != nil {
+		r.logger.Error("Invalid KV record", "key", key, "error", err)
+		return nil, domain.ErrDatabaseError
+	}
 
-	if kv.IsDeleted {
+	if kv.IsDeleted || kv.IsExpired(time.Now()) {
 		return nil, domain.ErrKeyNotFound
 	}
 
 	return kv, nil
 }
 
-func (r *TarantoolRepository) Update(kv *domain.KV) error {
+func (r *TarantoolRepository) Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error) {
 	now := time.Now().Unix()
 
-	return r.pool.Execute(func(conn *tarantool.Connection) error {
-		_, err := conn.Do(
-			tarantool.NewUpdateRequest("kv").
-				Key([]interface{}{kv.Key}).
-				Operations(
-					tarantool.NewOperations().
-						Assign(1, kv.Value).
-						Assign(3, uint32(now)),
-				),
-		).Get()
-
-		if err != nil {
-			r.logger.Error("Failed to update KV record", "key", kv.Key, "error", err)
-			return domain.ErrDatabaseError
-		}
+	var expiresAt interface{}
+	if kv.ExpiresAt != nil {
+		expiresAt = expiresAtField(kv.ExpiresAt)
+	}
 
-		kv.UpdatedAt = time.Unix(now, 0)
-		r.logger.Info("KV record updated", "key", kv.Key)
-		return nil
+	updated, previous, err := r.callMutation(ctx, "update", "kv_update", []interface{}{
+		kv.Key,
+		kv.Value,
+		kv.ContentType,
+		uint32(now),
+		expiresAt,
+		versionArg(expectedVersion),
+		r.historyDepth(),
 	})
+
+	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrVersionConflict):
+		r.logger.Debug("KV record was not updated", "key", kv.Key, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to update KV record", "key", kv.Key)
+	}
+
+	*kv = *updated
+	r.logger.Info("KV record updated", "key", kv.Key, "version", kv.Version)
+	return previous, nil
 }
 
-func (r *TarantoolRepository) Delete(key string) (*domain.KV, error) {
-	var result []interface{}
+func (r *TarantoolRepository) CompareAndSwap(ctx context.Context, kv *domain.KV, expectedValue interface{}, expectedVersion uint64) (*domain.KV, error) {
+	var expiresAt interface{}
+	if kv.ExpiresAt != nil {
+		expiresAt = expiresAtField(kv.ExpiresAt)
+	}
 
-	err := r.pool.Execute(func(conn *tarantool.Connection) error {
-		resp, err := conn.Do(
-			tarantool.NewSelectRequest("kv").Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{key}),
-		).Get()
-		if err != nil {
-			return fmt.Errorf("select before delete failed: %w", err)
-		}
-		if len(resp) == 0 {
-			return domain.ErrKeyNotFound
-		}
-		record, ok := resp[0].([]interface{})
-		if !ok {
-			return domain.ErrDatabaseError
-		}
-		resp, err = conn.Do(
-			tarantool.NewReplaceRequest("kv").Tuple([]interface{}{
-				key,
-				record[1],
-				record[2],
-				record[3],
-				uint32(time.Now().Unix()),
-				true,
-			}),
-		).Get()
-		if err != nil {
-			return fmt.Errorf("replace failed: %w", err)
-		}
-		if len(resp) == 0 {
-			return nil
-		}
-		result = resp
-		return nil
+	updated, previous, err := r.callMutation(ctx, "compare_and_swap", "kv_cas", []interface{}{
+		kv.Key,
+		expectedValue,
+		versionArg(expectedVersion),
+		kv.Value,
+		kv.ContentType,
+		uint32(time.Now().Unix()),
+		expiresAt,
+		r.historyDepth(),
 	})
 
-	if err != nil {
-		r.logger.Error("Failed to delete KV record", "key", key, "error", err)
-		return nil, domain.ErrDatabaseError
+	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrVersionConflict), errors.Is(err, domain.ErrValueMismatch):
+		r.logger.Debug("KV record was not swapped", "key", kv.Key, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to compare and swap KV record", "key", kv.Key)
 	}
 
-	if len(result) == 0 {
-		return nil, domain.ErrKeyNotFound
+	*kv = *updated
+	r.logger.Info("KV record swapped", "key", kv.Key, "version", kv.Version)
+	return previous, nil
+}
+
+func (r *TarantoolRepository) PutIfAbsent(ctx context.Context, kv *domain.KV) (*domain.KV, error) {
+	created, existing, err := r.callMutation(ctx, "put_if_absent", "kv_put_if_absent", []interface{}{
+		kv.Key,
+		kv.Value,
+		kv.ContentType,
+		uint32(time.Now().Unix()),
+		expiresAtField(kv.ExpiresAt),
+	})
+
+	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyAlreadyExists):
+		r.logger.Debug("Key already exists, nothing to put", "key", kv.Key)
+		return existing, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to put KV record if absent", "key", kv.Key)
 	}
 
-	record, ok := result[0].([]interface{})
-	if !ok {
-		return nil, domain.ErrDatabaseError
+	*kv = *created
+	r.logger.Info("KV record created", "key", kv.Key)
+	return nil, nil
+}
+
+func (r *TarantoolRepository) Increment(ctx context.Context, key string, delta, initial int64, expiresAt *time.Time) (*domain.KV, *domain.KV, error) {
+	kv, previous, err := r.callMutation(ctx, "increment", "kv_incr", []interface{}{
+		key,
+		delta,
+		initial,
+		uint32(time.Now().Unix()),
+		expiresAtField(expiresAt),
+	})
+
+	switch {
+	case isNamespaceMissing(err):
+		return nil, nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrNotNumeric):
+		r.logger.Debug("KV record was not incremented", "key", key, "reason", err)
+		return nil, nil, err
+	case err != nil:
+		return nil, nil, databaseError(r.logger, err, "Failed to increment KV record", "key", key)
+	}
+
+	return kv, previous, nil
+}
+
+func (r *TarantoolRepository) Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	kv, _, err := r.callMutation(ctx, "delete", "kv_soft_delete", []interface{}{
+		key,
+		uint32(time.Now().Unix()),
+		versionArg(expectedVersion),
+		r.historyDepth(),
+	})
+
+	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrVersionConflict):
+		r.logger.Debug("KV record was not deleted", "key", key, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, databaseError(r.logger, err, "Failed to delete KV record", "key", key)
 	}
-	kv := r.parseRecord(record)
 
 	r.logger.Info("KV record deleted", "key", key)
 	return kv, nil
 }
 
-func (r *TarantoolRepository) SoftDelete(key string) error {
-	now := time.Now().Unix()
-
-	ops := tarantool.NewOperations().
-		Assign(3, uint32(now)).
-		Assign(4, uint32(now)).
-		Assign(5, true)
-	return r.pool.Execute(func(conn *tarantool.Connection) error {
-		_, err := conn.Do(
-			tarantool.NewUpdateRequest("kv").
-				Key([]interface{}{key}).
-				Operations(ops),
-		).Get()
-		if err != nil {
-			return fmt.Errorf("soft delete failed: %w", err)
-		}
-		return nil
+func (r *TarantoolRepository) SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	kv, _, err := r.callMutation(ctx, "soft_delete", "kv_soft_delete", []interface{}{
+		key,
+		uint32(time.Now().Unix()),
+		versionArg(expectedVersion),
+		r.historyDepth(),
 	})
-}
 
-func (r *TarantoolRepository) Restore(key string) (*domain.KV, error) {
-	now := time.Now().Unix()
-	var kv *domain.KV
+	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrVersionConflict):
+		r.logger.Debug("KV record was not soft deleted", "key", key, "reason", err)
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("soft delete failed: %w", err)
+	}
 
-	err := r.pool.Execute(func(conn *tarantool.Connection) error {
-		resp, err := conn.Do(
-			tarantool.NewSelectRequest("kv").Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{key}),
-		).Get()
-		if err != nil {
-			return fmt.Errorf("select failed: %w", err)
-		}
-		if len(resp) == 0 {
-			return domain.ErrKeyNotFound
-		}
+	return kv, nil
+}
 
-		record, ok := resp[0].([]interface{})
-		if !ok {
-			return domain.ErrDatabaseError
-		}
-		if len(record) > 5 {
-			if isDeleted, ok := record[5].(bool); ok && !isDeleted {
-				return domain.ErrNotDeleted
-			}
-		}
+func (r *TarantoolRepository) Restore(ctx context.Context, key string) (*domain.KV, error) {
+	now := time.Now().Unix()
 
-		ops := tarantool.NewOperations().
-			Assign(3, uint32(now)).
-			Assign(4, uint32(0)).
-			Assign(5, false)
-		resp, err = conn.Do(
-			tarantool.NewUpdateRequest("kv").
-				Key([]interface{}{key}).
-				Operations(ops),
-		).Get()
-		if err != nil {
-			return fmt.Errorf("update failed: %w", err)
-		}
-		if len(resp) == 0 {
-			return fmt.Errorf("no data returned after update")
-		}
-		updatedRecord, ok := resp[0].([]interface{})
-		if !ok {
-			return fmt.Errorf("invalid record format")
-		}
-		kv = r.parseRecord(updatedRecord)
-		return nil
-	})
+	kv, _, err := r.callMutation(ctx, "restore", "kv_restore", []interface{}{key, uint32(now)})
 
 	switch {
+	case isNamespaceMissing(err):
+		return nil, domain.ErrNamespaceNotFound
 	case errors.Is(err, domain.ErrKeyNotFound):
 		r.logger.Debug("Key not found for restoration", "key", key)
 		return nil, domain.ErrKeyNotFound
@@ -272,142 +302,836 @@ func (r *TarantoolRepository) Restore(key string) (*domain.KV, error) {
 	}
 }
 
-func (r *TarantoolRepository) List(limit, offset int) ([]*domain.KV, int, error) {
-	var result []interface{}
-	var countResult []interface{}
+func (r *TarantoolRepository) History(ctx context.Context, key string) ([]*domain.KV, error) {
+	var current, revisions []interface{}
 
-	err := r.pool.Execute(func(conn *tarantool.Connection) error {
+	err := r.read(ctx, "history", func(conn *tarantool.Connection) error {
 		if err := conn.Do(
-			tarantool.NewSelectRequest("kv").
-				Index("deleted").
-				Offset(uint32(offset)).
-				Limit(uint32(limit)).
-				Iterator(tarantool.IterEq).
-				Key([]interface{}{false}),
-		).GetTyped(&result); err != nil {
+			tarantool.NewSelectRequest(r.space(spaceKV)).Limit(1).Iterator(tarantool.IterEq).Key([]interface{}{key}).Context(ctx),
+		).GetTyped(&current); err != nil {
 			return err
 		}
 		return conn.Do(
-			tarantool.NewSelectRequest("kv").
-				Index("deleted").
-				Offset(0).
-				Limit(1).
-				Iterator(tarantool.IterEq).
-				Key([]interface{}{false}),
-		).GetTyped(&countResult)
+			tarantool.NewSelectRequest(r.space(spaceHistory)).
+				Index("primary").
+				Iterator(tarantool.IterReq).
+				Key([]interface{}{key}).
+				Context(ctx),
+		).GetTyped(&revisions)
 	})
 
 	if err != nil {
-		r.logger.Error("Failed to list KV records", "error", err)
-		return nil, 0, domain.ErrDatabaseError
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to read KV history", "key", key)
 	}
 
-	total := len(result)
-	items := make([]*domain.KV, 0, len(result))
+	if len(current) == 0 && len(revisions) == 0 {
+		return nil, domain.ErrKeyNotFound
+	}
 
-	for _, record := range result {
-		recordData := record.([]interface{})
-		kv := r.parseRecord(recordData)
+	items, err := r.parseRecords(current)
+	if err != nil {
+		r.logger.Error("Invalid KV record", "key", key, "error", err)
+		return nil, domain.ErrDatabaseError
+	}
+	for _, record := range revisions {
+		tuple, _ := record.([]interface{})
+		revision, err := parseRevision(tuple)
+		if err != nil {
+			r.logger.Error("Invalid KV history record", "key", key, "error", err)
+			return nil, domain.ErrDatabaseError
+		}
+		items = append(items, revision)
+	}
+
+	return items, nil
+}
+
+func (r *TarantoolRepository) List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	var result []interface{}
+
+	// Продолжение по курсору идёт от позиции (false, after) индекса deleted,
+	// смещение в этом случае не используется
+	request := tarantool.NewSelectRequest(r.space(spaceKV)).
+		Index("deleted").
+		Limit(uint32(opts.Limit))
+	if opts.After != "" {
+		request = request.Iterator(tarantool.IterGt).Key([]interface{}{false, opts.After})
+	} else {
+		request = request.Offset(uint32(opts.Offset)).Iterator(tarantool.IterEq).Key([]interface{}{false})
+	}
+
+	var total int
+	err := r.read(ctx, "list", func(conn *tarantool.Connection) error {
+		if err := conn.Do(request.Context(ctx)).GetTyped(&result); err != nil {
+			return err
+		}
+		if opts.SkipCount {
+			return nil
+		}
+		var err error
+		total, err = r.count(ctx, conn, false)
+		return err
+	})
+
+	if err != nil {
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to list KV records")
+	}
+
+	records, err := r.parseRecords(result)
+	if err != nil {
+		r.logger.Error("Invalid KV record in list", "error", err)
+		return nil, domain.ErrDatabaseError
+	}
+
+	now := time.Now()
+	items := make([]*domain.KV, 0, len(records))
+	exhausted := len(records) < opts.Limit
+
+	for _, kv := range records {
+		// IterGt не ограничен префиксом: удалённые записи идут после всех живых
+		if kv.IsDeleted {
+			exhausted = true
+			break
+		}
+		if kv.IsExpired(now) {
+			continue
+		}
 		items = append(items, kv)
 	}
 
-	return items, total, nil
+	page := &domain.ListPage{
+		Items: items,
+		Total: total,
+	}
+	if !exhausted && len(records) > 0 {
+		page.NextKey = records[len(records)-1].Key
+	}
+
+	return page, nil
 }
 
-func (r *TarantoolRepository) ListIncludingDeleted(limit, offset int) ([]*domain.KV, int, error) {
+func (r *TarantoolRepository) ListIncludingDeleted(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
 	var result []interface{}
-	var countResult []interface{}
 
-	err := r.pool.Execute(func(conn *tarantool.Connection) error {
-		if err := conn.Do(
-			tarantool.NewSelectRequest("kv").
-				Index("primary").
-				Offset(uint32(offset)).
-				Limit(uint32(limit)).
-				Iterator(tarantool.IterAll).
-				Key([]interface{}{}),
-		).GetTyped(&result); err != nil {
+	request := tarantool.NewSelectRequest(r.space(spaceKV)).
+		Index("primary").
+		Limit(uint32(opts.Limit))
+	if opts.After != "" {
+		request = request.Iterator(tarantool.IterGt).Key([]interface{}{opts.After})
+	} else {
+		request = request.Offset(uint32(opts.Offset)).Iterator(tarantool.IterAll).Key([]interface{}{})
+	}
+
+	var total int
+	err := r.read(ctx, "list_including_deleted", func(conn *tarantool.Connection) error {
+		if err := conn.Do(request.Context(ctx)).GetTyped(&result); err != nil {
 			return err
 		}
+		if opts.SkipCount {
+			return nil
+		}
+		var err error
+		total, err = r.count(ctx, conn, true)
+		return err
+	})
+
+	if err != nil {
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to list KV records including deleted")
+	}
+
+	items, err := r.parseRecords(result)
+	if err != nil {
+		r.logger.Error("Invalid KV record in list", "error", err)
+		return nil, domain.ErrDatabaseError
+	}
+
+	page := &domain.ListPage{
+		Items: items,
+		Total: total,
+	}
+	if len(items) == opts.Limit {
+		page.NextKey = items[len(items)-1].Key
+	}
+
+	return page, nil
+}
+
+func (r *TarantoolRepository) Scan(ctx context.Context, opts domain.ScanOptions) (*domain.ListPage, error) {
+	// Обход начинается с большей из нижних границ: префикса или From
+	start, iterator := opts.From, tarantool.IterGe
+	if opts.Prefix > start {
+		start = opts.Prefix
+	}
+	if opts.After != "" && opts.After >= start {
+		start, iterator = opts.After, tarantool.IterGt
+	}
+
+	now := time.Now()
+	page := &domain.ListPage{Items: make([]*domain.KV, 0, opts.Limit)}
+
+	err := r.read(ctx, "scan", func(conn *tarantool.Connection) error {
+		for {
+			var result []interface{}
+			if err := conn.Do(
+				tarantool.NewSelectRequest(r.space(spaceKV)).
+					Index("primary").
+					Limit(uint32(opts.Limit)).
+					Iterator(iterator).
+					Key([]interface{}{start}).
+					Context(ctx),
+			).GetTyped(&result); err != nil {
+				return err
+			}
+
+			records, err := r.parseRecords(result)
+			if err != nil {
+				return err
+			}
+
+			for _, kv := range records {
+				// Ключи в индексе отсортированы, поэтому первый ключ за границей завершает обход
+				if !opts.InRange(kv.Key) {
+					return nil
+				}
+				start, iterator = kv.Key, tarantool.IterGt
+
+				if !opts.IncludeDeleted && (kv.IsDeleted || kv.IsExpired(now)) {
+					continue
+				}
+				page.Items = append(page.Items, kv)
+				if len(page.Items) == opts.Limit {
+					page.NextKey = kv.Key
+					return nil
+				}
+			}
+
+			if len(result) < opts.Limit {
+				return nil
+			}
+		}
+	})
+
+	if err != nil {
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to scan KV records", "prefix", opts.Prefix, "from", opts.From, "to", opts.To)
+	}
+
+	return page, nil
+}
+
+func (r *TarantoolRepository) Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchItemResult, error) {
+	args := make([]map[string]interface{}, 0, len(ops))
+	for _, op := range ops {
+		// Отсутствующие поля не передаются, чтобы в Lua они были nil, а не box.NULL
+		arg := map[string]interface{}{
+			"op":  string(op.Op),
+			"key": op.Key,
+		}
+		if op.Op == domain.BatchOpCreate || op.Op == domain.BatchOpUpdate {
+			arg["value"] = op.Value
+			arg["content_type"] = op.ContentType
+		}
+		if op.ExpiresAt != nil {
+			arg["expires_at"] = expiresAtField(op.ExpiresAt)
+		}
+		if op.Version != 0 {
+			arg["version"] = op.Version
+		}
+		args = append(args, arg)
+	}
+
+	var resp []interface{}
+	err := r.execute(ctx, "batch", func(conn *tarantool.Connection) error {
+		var err error
+		resp, err = conn.Do(
+			tarantool.NewCallRequest("kv_batch").
+				Args([]interface{}{r.namespace, args, uint32(time.Now().Unix()), atomic, r.historyDepth()}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("batch call failed: %w", err)
+		}
+		if len(resp) > 1 {
+			if status, ok := resp[1].(string); ok {
+				return statusError(status)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if isNamespaceMissing(err) {
+			return nil, domain.ErrNamespaceNotFound
+		}
+		return nil, databaseError(r.logger, err, "Failed to execute batch", "size", len(ops), "atomic", atomic)
+	}
+
+	if len(resp) < 2 {
+		r.logger.Error("Invalid batch response", "response", resp)
+		return nil, domain.ErrDatabaseError
+	}
+	committed, _ := resp[0].(bool)
+	items, _ := resp[1].([]interface{})
+
+	results := make([]domain.BatchItemResult, len(ops))
+	for i, op := range ops {
+		results[i] = domain.BatchItemResult{Op: op.Op, Key: op.Key}
+
+		if i >= len(items) {
+			// Операции после неудачной в откаченном пакете не выполнялись
+			results[i].Error = domain.ErrBatchAborted.Error()
+			continue
+		}
+		item, _ := items[i].([]interface{})
+		if len(item) > 1 {
+			if status, ok := item[1].(string); ok {
+				results[i].Error = statusError(status).Error()
+				continue
+			}
+		}
+		if !committed {
+			results[i].Error = domain.ErrBatchAborted.Error()
+			continue
+		}
+		if len(item) > 0 {
+			if record, ok := item[0].([]interface{}); ok {
+				kv, err := r.parseRecord(record)
+				if err != nil {
+					r.logger.Error("Invalid KV record in batch result", "key", op.Key, "error", err)
+					results[i].Error = domain.ErrDatabaseError.Error()
+					continue
+				}
+				results[i].KV = kv
+			}
+		}
+	}
+
+	if !committed {
+		r.logger.Debug("Atomic batch rolled back", "size", len(ops))
+		return results, domain.ErrBatchAborted
+	}
+
+	r.logger.Info("Batch executed", "size", len(ops), "atomic", atomic)
+	return results, nil
+}
+
+func (r *TarantoolRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	var purged int
+
+	err := r.execute(ctx, "purge_expired", func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest("kv_purge_expired").
+				Args([]interface{}{uint32(time.Now().Unix()), limit}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("purge call failed: %w", err)
+		}
+		if len(resp) > 0 {
+			if n, ok := toInt64(resp[0]); ok {
+				purged = int(n)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, databaseError(r.logger, err, "Failed to purge expired KV records")
+	}
+
+	return purged, nil
+}
+
+// Changes читает журнал этого экземпляра; позиция чтения — один LSN
+func (r *TarantoolRepository) Changes(ctx context.Context, cursor domain.ChangesCursor, limit int) ([]domain.ChangeLogEntry, domain.ChangesCursor, error) {
+	if len(cursor) > 1 {
+		return nil, nil, domain.ErrInvalidCursor
+	}
+	var since uint64
+	if len(cursor) == 1 {
+		since = cursor[0]
+	}
+
+	var result []interface{}
+
+	err := r.execute(ctx, "changes", func(conn *tarantool.Connection) error {
 		return conn.Do(
-			tarantool.NewSelectRequest("kv").
+			tarantool.NewSelectRequest(r.space(spaceChangelog)).
 				Index("primary").
-				Offset(0).
-				Limit(1).
-				Iterator(tarantool.IterAll).
-				Key([]interface{}{}),
-		).GetTyped(&countResult)
+				Limit(uint32(limit)).
+				Iterator(tarantool.IterGt).
+				Key([]interface{}{since}).
+				Context(ctx),
+		).GetTyped(&result)
 	})
 
 	if err != nil {
-		r.logger.Error("Failed to list KV records including deleted", "error", err)
-		return nil, 0, domain.ErrDatabaseError
+		if isNamespaceMissing(err) {
+			return nil, nil, domain.ErrNamespaceNotFound
+		}
+		return nil, nil, databaseError(r.logger, err, "Failed to read changelog", "since", since)
 	}
 
-	total := len(countResult)
-	items := make([]*domain.KV, 0, len(result))
-
+	entries := make([]domain.ChangeLogEntry, 0, len(result))
 	for _, record := range result {
-		recordData := record.([]interface{})
-		kv := r.parseRecord(recordData)
-		items = append(items, kv)
+		tuple, ok := record.([]interface{})
+		if !ok {
+			r.logger.Error("Invalid changelog record format", "record", record)
+			return nil, nil, domain.ErrDatabaseError
+		}
+		entry, err := parseChangeLogEntry(tuple)
+		if err != nil {
+			r.logger.Error("Invalid changelog record", "error", err)
+			return nil, nil, domain.ErrDatabaseError
+		}
+		entries = append(entries, entry)
 	}
 
-	return items, total, nil
+	if len(entries) > 0 {
+		since = entries[len(entries)-1].LSN
+	}
+	return entries, domain.ChangesCursor{since}, nil
 }
 
-func (r *TarantoolRepository) parseRecord(record []interface{}) *domain.KV {
+func (r *TarantoolRepository) TrimChangelog(ctx context.Context, before time.Time, limit int) (int, error) {
+	var trimmed int
+
+	err := r.execute(ctx, "trim_changelog", func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest("kv_changelog_trim").
+				Args([]interface{}{uint32(before.Unix()), limit}).
+				Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("changelog trim call failed: %w", err)
+		}
+		if len(resp) > 0 {
+			if n, ok := toInt64(resp[0]); ok {
+				trimmed = int(n)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, databaseError(r.logger, err, "Failed to trim changelog")
+	}
+
+	return trimmed, nil
+}
+
+// Статусы, которыми Lua-функции из init.lua сообщают о невыполненной операции
+const (
+	statusNotFound        = "not_found"
+	statusVersionConflict = "version_conflict"
+	statusNotDeleted      = "not_deleted"
+	statusAlreadyExists   = "already_exists"
+	statusNotNumeric      = "not_numeric"
+	statusValueMismatch   = "value_mismatch"
+	statusInvalidOp       = "invalid_operation"
+	statusNoNamespace     = "namespace_not_found"
+	statusFailed          = "error"
+)
+
+// callMutation вызывает Lua-функцию namespace репозитория, которая возвращает изменённый
+// кортеж (и, если есть, предыдущий третьим значением) либо пару (nil, статус),
+// и переводит статус в ошибку домена.
+func (r *TarantoolRepository) callMutation(ctx context.Context, operation, function string, args []interface{}) (*domain.KV, *domain.KV, error) {
+	var kv, previous *domain.KV
+
+	err := r.execute(ctx, operation, func(conn *tarantool.Connection) error {
+		resp, err := conn.Do(
+			tarantool.NewCallRequest(function).Args(append([]interface{}{r.namespace}, args...)).Context(ctx),
+		).Get()
+		if err != nil {
+			return fmt.Errorf("%s call failed: %w", function, err)
+		}
+		// Третье значение приходит и вместе со статусом, например существующая запись для put-if-absent
+		if len(resp) > 2 {
+			if record, ok := resp[2].([]interface{}); ok {
+				if previous, err = r.parseRecord(record); err != nil {
+					return err
+				}
+			}
+		}
+		if len(resp) > 1 {
+			if status, ok := resp[1].(string); ok {
+				return statusError(status)
+			}
+		}
+		if len(resp) == 0 {
+			return fmt.Errorf("no data returned from %s", function)
+		}
+		record, ok := resp[0].([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid record format")
+		}
+		kv, err = r.parseRecord(record)
+		return err
+	})
+
+	return kv, previous, err
+}
+
+// execute выполняет запрос на мастере и учитывает его длительность
+// в метриках под именем operation
+func (r *TarantoolRepository) execute(ctx context.Context, operation string, fn func(*tarantool.Connection) error) error {
+	return r.observe(ctx, operation, r.cluster.Execute, fn)
+}
+
+// read выполняет чтение на реплике, если согласованность запроса это допускает
+func (r *TarantoolRepository) read(ctx context.Context, operation string, fn func(*tarantool.Connection) error) error {
+	return r.observe(ctx, operation, r.cluster.ExecuteRead, fn)
+}
+
+func (r *TarantoolRepository) observe(ctx context.Context, operation string, execute func(context.Context, func(*tarantool.Connection) error) error, fn func(*tarantool.Connection) error) error {
+	start := time.Now()
+	err := execute(ctx, func(conn *tarantool.Connection) error {
+		// Span запроса начинается после получения соединения, ожидание пула — отдельный span
+		_, span := tracer.Start(ctx, "tarantool."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				dbSystemTarantool,
+				semconv.DBOperation(operation),
+				semconv.DBName(r.namespace),
+			),
+		)
+		err := fn(conn)
+		if isFailure(err) {
+			tracing.End(span, err)
+		} else {
+			span.End()
+		}
+		return err
+	})
+	r.metrics.ObserveRepository(operation, time.Since(start), isFailure(err))
+	return err
+}
+
+// isFailure отделяет сбои базы данных от ожидаемых результатов операций:
+// отсутствующего ключа, конфликта версий и других ошибок домена
+func isFailure(err error) bool {
+	if err == nil || isNamespaceMissing(err) {
+		return false
+	}
+	for _, expected := range []error{
+		domain.ErrKeyNotFound,
+		domain.ErrVersionConflict,
+		domain.ErrNotDeleted,
+		domain.ErrKeyAlreadyExists,
+		domain.ErrNotNumeric,
+		domain.ErrValueMismatch,
+		domain.ErrValidationError,
+		domain.ErrCanceled,
+	} {
+		if errors.Is(err, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func statusError(status string) error {
+	switch status {
+	case statusNotFound:
+		return domain.ErrKeyNotFound
+	case statusVersionConflict:
+		return domain.ErrVersionConflict
+	case statusNotDeleted:
+		return domain.ErrNotDeleted
+	case statusAlreadyExists:
+		return domain.ErrKeyAlreadyExists
+	case statusNotNumeric:
+		return domain.ErrNotNumeric
+	case statusValueMismatch:
+		return domain.ErrValueMismatch
+	case statusInvalidOp:
+		return domain.ErrValidationError
+	case statusNoNamespace:
+		return domain.ErrNamespaceNotFound
+	case statusFailed:
+		return domain.ErrDatabaseError
+	default:
+		return fmt.Errorf("unexpected status %q", status)
+	}
+}
+
+// Суффиксы имён space namespace; имена строятся так же, как kv_space_names в init.lua
+const (
+	spaceKV        = ""
+	spaceHistory   = "_history"
+	spaceChangelog = "_changelog"
+)
+
+func (r *TarantoolRepository) space(suffix string) string {
+	if r.namespace == domain.DefaultNamespace {
+		return "kv" + suffix
+	}
+	return "kv_ns_" + r.namespace + suffix
+}
+
+// isNamespaceMissing сообщает, что namespace не существует: Lua-функция вернула
+// статус namespace_not_found либо space уже удалён вместе с namespace
+func isNamespaceMissing(err error) bool {
+	var tntErr tarantool.Error
+	if errors.As(err, &tntErr) {
+		return tntErr.Code == iproto.ER_NO_SUCH_SPACE
+	}
+	return errors.Is(err, domain.ErrNamespaceNotFound)
+}
+
+// historyDepth — сколько предыдущих ревизий хранить для ключа
+func (r *TarantoolRepository) historyDepth() int {
+	if r.config.History.Depth <= 0 {
+		return defaultHistoryDepth
+	}
+	return r.config.History.Depth
+}
+
+// versionArg передаёт nil вместо нулевой версии, чтобы Lua-функция не проверяла её
+func versionArg(version uint64) interface{} {
+	if version == 0 {
+		return nil
+	}
+	return version
+}
+
+// count возвращает полное число записей: space:len() для листинга с удалёнными,
+// index:count() по индексу deleted без просроченных записей — для обычного
+func (r *TarantoolRepository) count(ctx context.Context, conn *tarantool.Connection, includeDeleted bool) (int, error) {
+	resp, err := conn.Do(
+		tarantool.NewCallRequest("kv_count").
+			Args([]interface{}{r.namespace, includeDeleted, uint32(time.Now().Unix())}).
+			Context(ctx),
+	).Get()
+	if err != nil {
+		return 0, fmt.Errorf("count call failed: %w", err)
+	}
+	if len(resp) > 1 {
+		if status, ok := resp[1].(string); ok {
+			return 0, statusError(status)
+		}
+	}
+	if len(resp) == 0 {
+		return 0, fmt.Errorf("no data returned from kv_count")
+	}
+	total, ok := toInt64(resp[0])
+	if !ok {
+		return 0, fmt.Errorf("invalid count format")
+	}
+	return int(total), nil
+}
+
+// parseRecord разбирает кортеж kv. Кортежи старого формата без последних полей
+// допустимы; ошибка возвращается, если ключ или служебные поля имеют неверный тип.
+func (r *TarantoolRepository) parseRecord(record []interface{}) (*domain.KV, error) {
+	if len(record) < 6 {
+		return nil, fmt.Errorf("kv record has %d fields", len(record))
+	}
+
+	key, ok := record[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type %T", record[0])
+	}
+	value, err := domain.DecodeStoredValue(record[1])
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+	isDeleted, ok := record[5].(bool)
+	if !ok && record[5] != nil {
+		return nil, fmt.Errorf("key %q: invalid is_deleted type %T", key, record[5])
+	}
+
 	kv := &domain.KV{
-		Key:   record[0].(string),
-		Value: record[1].(string),
+		Key:       key,
+		Value:     value,
+		IsDeleted: isDeleted,
 	}
 
-	switch v := record[2].(type) {
-	case int64:
+	if v, ok := toInt64(record[2]); ok {
 		kv.CreatedAt = time.Unix(v, 0)
-	case uint64:
-		kv.CreatedAt = time.Unix(int64(v), 0)
-	case uint32:
-		kv.CreatedAt = time.Unix(int64(v), 0)
 	}
-
-	switch v := record[3].(type) {
-	case int64:
+	if v, ok := toInt64(record[3]); ok {
 		kv.UpdatedAt = time.Unix(v, 0)
-	case uint64:
-		kv.UpdatedAt = time.Unix(int64(v), 0)
-	case uint32:
-		kv.UpdatedAt = time.Unix(int64(v), 0)
+	}
+	if v, ok := toInt64(record[4]); ok && v != 0 {
+		t := time.Unix(v, 0)
+		kv.DeletedAt = &t
 	}
 
-	var deletedAt *time.Time
-	switch v := record[4].(type) {
-	case int64:
-		if v != 0 {
+	if len(record) > 6 {
+		if v, ok := toInt64(record[6]); ok && v != 0 {
 			t := time.Unix(v, 0)
-			deletedAt = &t
+			kv.ExpiresAt = &t
 		}
-	case uint64:
-		if v != 0 {
-			t := time.Unix(int64(v), 0)
-			deletedAt = &t
+	}
+
+	// Записи без поля version созданы до его появления и считаются первой версией
+	kv.Version = 1
+	if len(record) > 7 {
+		if v, ok := toInt64(record[7]); ok {
+			kv.Version = uint64(v)
 		}
-	case uint32:
-		if v != 0 {
-			t := time.Unix(int64(v), 0)
-			deletedAt = &t
+	}
+
+	var contentType interface{}
+	if len(record) > 8 {
+		contentType = record[8]
+	}
+	kv.ContentType = resolveContentType(contentType, value)
+
+	return kv, nil
+}
+
+// parseRecords разбирает результат выборки из kv
+func (r *TarantoolRepository) parseRecords(result []interface{}) ([]*domain.KV, error) {
+	items := make([]*domain.KV, 0, len(result))
+	for _, record := range result {
+		tuple, ok := record.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid record format %T", record)
+		}
+		kv, err := r.parseRecord(tuple)
+		if err != nil {
+			return nil, err
 		}
+		items = append(items, kv)
+	}
+	return items, nil
+}
+
+// parseRevision разбирает кортеж kv_history:
+// key, version, value, created_at, updated_at, expires_at, archived_at, content_type
+func parseRevision(record []interface{}) (*domain.KV, error) {
+	if len(record) < 7 {
+		return nil, fmt.Errorf("history record has %d fields", len(record))
+	}
+
+	key, ok := record[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type %T", record[0])
+	}
+	value, err := domain.DecodeStoredValue(record[2])
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+
+	kv := &domain.KV{Key: key, Value: value}
+	if v, ok := toInt64(record[1]); ok {
+		kv.Version = uint64(v)
+	}
+	if v, ok := toInt64(record[3]); ok {
+		kv.CreatedAt = time.Unix(v, 0)
+	}
+	if v, ok := toInt64(record[4]); ok {
+		kv.UpdatedAt = time.Unix(v, 0)
+	}
+	if v, ok := toInt64(record[5]); ok && v != 0 {
+		t := time.Unix(v, 0)
+		kv.ExpiresAt = &t
 	}
-	kv.DeletedAt = deletedAt
 
-	if len(record) > 5 && record[5] != nil {
-		kv.IsDeleted = record[5].(bool)
+	var contentType interface{}
+	if len(record) > 7 {
+		contentType = record[7]
 	}
+	kv.ContentType = resolveContentType(contentType, value)
 
-	return kv
+	return kv, nil
+}
+
+// resolveContentType берёт сохранённый тип содержимого; для записей, созданных
+// до его появления, тип определяется по значению
+func resolveContentType(field interface{}, value interface{}) string {
+	if contentType, ok := field.(string); ok && contentType != "" {
+		return contentType
+	}
+	if _, ok := value.([]byte); ok {
+		return domain.ContentTypeBinary
+	}
+	return domain.ContentTypeJSON
+}
+
+// parseChangeLogEntry разбирает кортеж kv_changelog:
+// lsn, key, op, old_value, new_value, version, timestamp
+func parseChangeLogEntry(record []interface{}) (domain.ChangeLogEntry, error) {
+	var entry domain.ChangeLogEntry
+	if len(record) < 7 {
+		return entry, fmt.Errorf("changelog record has %d fields", len(record))
+	}
+
+	lsn, _ := toInt64(record[0])
+	version, _ := toInt64(record[5])
+	timestamp, _ := toInt64(record[6])
+	key, _ := record[1].(string)
+	op, _ := record[2].(string)
+
+	oldValue, err := domain.DecodeStoredValue(record[3])
+	if err != nil {
+		return entry, fmt.Errorf("lsn %d: %w", lsn, err)
+	}
+	newValue, err := domain.DecodeStoredValue(record[4])
+	if err != nil {
+		return entry, fmt.Errorf("lsn %d: %w", lsn, err)
+	}
+
+	entry = domain.ChangeLogEntry{
+		LSN:       uint64(lsn),
+		Key:       key,
+		Op:        domain.ChangeOp(op),
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		Version:   uint64(version),
+		Timestamp: time.Unix(timestamp, 0),
+	}
+
+	return entry, nil
+}
+
+func expiresAtField(expiresAt *time.Time) uint32 {
+	if expiresAt == nil {
+		return 0
+	}
+	return uint32(expiresAt.Unix())
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case uint:
+		return int64(n), true
+	}
+	return 0, false
 }
 
 func (r *TarantoolRepository) Close() error {
-	r.logger.Info("Closing tarantool connection pool")
-	return r.pool.Close()
+	r.logger.Info("Closing tarantool connection pools")
+	return r.cluster.Close()
 }
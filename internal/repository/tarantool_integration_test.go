@@ -0,0 +1,165 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// Integration-тесты выполняются против Tarantool с загруженным init.lua:
+//
+//	TARANTOOL_HOST=localhost TARANTOOL_PORT=3301 go test -tags integration ./internal/...
+func newIntegrationRepository(t *testing.T) (interfaces.KVRepository, string) {
+	t.Helper()
+
+	port, err := strconv.Atoi(integrationEnv("TARANTOOL_PORT", "3301"))
+	if err != nil {
+		t.Fatalf("invalid TARANTOOL_PORT: %v", err)
+	}
+	cfg := &config.Config{Tarantool: config.TarantoolConfig{
+		Username: integrationEnv("TARANTOOL_USERNAME", "admin"),
+		Password: integrationEnv("TARANTOOL_PASSWORD", "admin"),
+		Timeout:  5 * time.Second,
+		Pool:     config.PoolConfig{MinSize: 1, MaxSize: 4},
+	}}
+	cluster := NewCluster(cfg, []config.InstanceConfig{{
+		Host: integrationEnv("TARANTOOL_HOST", "localhost"),
+		Port: port,
+		Role: RoleMaster,
+	}}, nopLogger{})
+
+	repo := NewTarantoolRepository(cluster, cfg, nil, nopLogger{})
+	t.Cleanup(func() { repo.Close() })
+
+	// Ключи каждого запуска не пересекаются с данными предыдущих
+	return repo, fmt.Sprintf("it:%s:%d:", t.Name(), time.Now().UnixNano())
+}
+
+func integrationEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func TestIntegration_Batch(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+
+	existing := &domain.KV{Key: prefix + "existing", Value: "a", ContentType: domain.ContentTypeJSON}
+	if err := repo.Create(ctx, existing); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	results, err := repo.Batch(ctx, []domain.BatchOperation{
+		{Op: domain.BatchOpCreate, Key: prefix + "new", Value: "b", ContentType: domain.ContentTypeJSON},
+		{Op: domain.BatchOpUpdate, Key: existing.Key, Value: "c", ContentType: domain.ContentTypeJSON, Version: 1},
+		{Op: domain.BatchOpGet, Key: existing.Key},
+	}, true)
+	if err != nil {
+		t.Fatalf("atomic Batch() error = %v", err)
+	}
+	if results[0].KV == nil || results[0].KV.Value != "b" || results[2].KV == nil || results[2].KV.Value != "c" {
+		t.Errorf("atomic Batch() results = %+v", results)
+	}
+
+	// get отсутствующего ключа — неудачная операция: атомарный пакет откатывается целиком
+	results, err = repo.Batch(ctx, []domain.BatchOperation{
+		{Op: domain.BatchOpUpdate, Key: existing.Key, Value: "d", ContentType: domain.ContentTypeJSON},
+		{Op: domain.BatchOpGet, Key: prefix + "missing"},
+		{Op: domain.BatchOpDelete, Key: existing.Key},
+	}, true)
+	if !errors.Is(err, domain.ErrBatchAborted) {
+		t.Fatalf("atomic Batch() with missing key error = %v, want %v", err, domain.ErrBatchAborted)
+	}
+	if results[0].Error != domain.ErrBatchAborted.Error() ||
+		results[1].Error != domain.ErrKeyNotFound.Error() ||
+		results[2].Error != domain.ErrBatchAborted.Error() {
+		t.Errorf("rolled back Batch() results = %+v", results)
+	}
+	if kv, err := repo.Get(ctx, existing.Key); err != nil || kv.Value != "c" {
+		t.Errorf("Get() after rollback = %+v, %v, want value c", kv, err)
+	}
+
+	// В режиме best_effort операции независимы
+	results, err = repo.Batch(ctx, []domain.BatchOperation{
+		{Op: domain.BatchOpGet, Key: prefix + "missing"},
+		{Op: domain.BatchOpUpdate, Key: existing.Key, Value: "e", ContentType: domain.ContentTypeJSON},
+	}, false)
+	if err != nil {
+		t.Fatalf("best effort Batch() error = %v", err)
+	}
+	if results[0].Error != domain.ErrKeyNotFound.Error() || results[1].KV == nil || results[1].KV.Value != "e" {
+		t.Errorf("best effort Batch() results = %+v", results)
+	}
+}
+
+// Удалённый ключ для записи считается отсутствующим: его можно создать заново.
+// Версии 1 и 2 получают создание и обновление, 3 — удаление, новая запись начинается с 4.
+func TestIntegration_WriteAfterDelete(t *testing.T) {
+	repo, prefix := newIntegrationRepository(t)
+	ctx := context.Background()
+
+	deleted := func(key string, value interface{}) {
+		t.Helper()
+		kv := &domain.KV{Key: key, Value: value, ContentType: domain.ContentTypeJSON}
+		if err := repo.Create(ctx, kv); err != nil {
+			t.Fatalf("Create(%s) error = %v", key, err)
+		}
+		if _, err := repo.Update(ctx, &domain.KV{Key: key, Value: value, ContentType: domain.ContentTypeJSON}, 0); err != nil {
+			t.Fatalf("Update(%s) error = %v", key, err)
+		}
+		if _, err := repo.Delete(ctx, key, 0); err != nil {
+			t.Fatalf("Delete(%s) error = %v", key, err)
+		}
+	}
+
+	deleted(prefix+"create", "a")
+	kv := &domain.KV{Key: prefix + "create", Value: "b", ContentType: domain.ContentTypeJSON}
+	if err := repo.Create(ctx, kv); err != nil {
+		t.Fatalf("Create(deleted key) error = %v", err)
+	}
+	if kv.Value != "b" || kv.Version != 4 {
+		t.Errorf("Create(deleted key) = %+v, want value b version 4", kv)
+	}
+	// Ревизии удалённой записи остаются в истории под своими версиями
+	history, err := repo.History(ctx, kv.Key)
+	if err != nil {
+		t.Fatalf("History() after recreate error = %v", err)
+	}
+	var versions []uint64
+	for _, revision := range history {
+		versions = append(versions, revision.Version)
+	}
+	if fmt.Sprint(versions) != "[4 2 1]" || history[2].Value != "a" {
+		t.Errorf("History() after recreate versions = %v, want [4 2 1] with the old value", versions)
+	}
+
+	deleted(prefix+"put", "a")
+	kv = &domain.KV{Key: prefix + "put", Value: "b", ContentType: domain.ContentTypeJSON}
+	if existing, err := repo.PutIfAbsent(ctx, kv); err != nil || existing != nil {
+		t.Fatalf("PutIfAbsent(deleted key) = %v, %v", existing, err)
+	}
+	if kv.Value != "b" || kv.Version != 4 {
+		t.Errorf("PutIfAbsent(deleted key) = %+v, want value b version 4", kv)
+	}
+
+	deleted(prefix+"counter", int64(10))
+	kv, previous, err := repo.Increment(ctx, prefix+"counter", 1, 0, nil)
+	if err != nil {
+		t.Fatalf("Increment(deleted key) error = %v", err)
+	}
+	if n, _ := toInt64(kv.Value); n != 1 || kv.Version != 4 || previous != nil {
+		t.Errorf("Increment(deleted key) = %v version %d, previous %v, want 1 version 4", kv.Value, kv.Version, previous)
+	}
+}
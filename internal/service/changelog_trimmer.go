@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/interfaces"
+)
+
+const (
+	defaultTrimInterval = time.Minute
+	defaultTrimBatch    = 1000
+)
+
+// ChangelogTrimmer периодически удаляет записи журнала изменений старше retention.
+// При нулевом retention журнал хранится бессрочно и Start ничего не запускает.
+type ChangelogTrimmer struct {
+	repo      interfaces.KVRepository
+	logger    interfaces.Logger
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func NewChangelogTrimmer(repo interfaces.KVRepository, logger interfaces.Logger, retention, interval time.Duration, batchSize int) *ChangelogTrimmer {
+	if interval <= 0 {
+		interval = defaultTrimInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultTrimBatch
+	}
+
+	return &ChangelogTrimmer{
+		repo:      repo,
+		logger:    logger,
+		retention: retention,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (t *ChangelogTrimmer) Start() {
+	if t.retention <= 0 {
+		t.logger.Info("Changelog retention is disabled, entries are kept forever")
+		return
+	}
+
+	t.wg.Add(1)
+	go t.run()
+
+	t.logger.Info("Changelog trimmer started", "retention", t.retention, "interval", t.interval)
+}
+
+func (t *ChangelogTrimmer) Stop() {
+	t.once.Do(func() {
+		close(t.stop)
+	})
+	t.wg.Wait()
+}
+
+func (t *ChangelogTrimmer) run() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.trim()
+		}
+	}
+}
+
+func (t *ChangelogTrimmer) trim() {
+	before := time.Now().Add(-t.retention)
+
+	total := 0
+	for {
+		trimmed, err := t.repo.TrimChangelog(context.Background(), before, t.batchSize)
+		if err != nil {
+			t.logger.Error("Failed to trim changelog", "error", err)
+			return
+		}
+		total += trimmed
+
+		if trimmed < t.batchSize {
+			break
+		}
+
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		t.logger.Debug("Changelog entries trimmed", "count", total)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/interfaces"
+)
+
+const (
+	defaultReapInterval = 10 * time.Second
+	defaultReapBatch    = 500
+)
+
+// ExpiryReaper периодически удаляет записи с истёкшим TTL пачками по batchSize.
+type ExpiryReaper struct {
+	repo      interfaces.KVRepository
+	logger    interfaces.Logger
+	interval  time.Duration
+	batchSize int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func NewExpiryReaper(repo interfaces.KVRepository, logger interfaces.Logger, interval time.Duration, batchSize int) *ExpiryReaper {
+	if interval <= 0 {
+		interval = defaultReapInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultReapBatch
+	}
+
+	return &ExpiryReaper{
+		repo:      repo,
+		logger:    logger,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (r *ExpiryReaper) Start() {
+	r.wg.Add(1)
+	go r.run()
+
+	r.logger.Info("Expiry reaper started", "interval", r.interval, "batch_size", r.batchSize)
+}
+
+func (r *ExpiryReaper) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	r.wg.Wait()
+
+	r.logger.Info("Expiry reaper stopped")
+}
+
+func (r *ExpiryReaper) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reap()
+		}
+	}
+}
+
+func (r *ExpiryReaper) reap() {
+	total := 0
+	for {
+		purged, err := r.repo.PurgeExpired(context.Background(), r.batchSize)
+		if err != nil {
+			r.logger.Error("Failed to purge expired records", "error", err)
+			return
+		}
+		total += purged
+
+		// Неполная пачка означает, что просроченных записей больше нет
+		if purged < r.batchSize {
+			break
+		}
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		r.logger.Debug("Expired records purged", "count", total)
+	}
+}
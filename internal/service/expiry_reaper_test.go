@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+// countingRepository считает вызовы PurgeExpired
+type countingRepository struct {
+	*MockRepository
+	calls []int
+}
+
+func (r *countingRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	purged, err := r.MockRepository.PurgeExpired(ctx, limit)
+	r.calls = append(r.calls, purged)
+	return purged, err
+}
+
+func TestExpiryReaper_PurgesInBatches(t *testing.T) {
+	repo := &countingRepository{MockRepository: NewMockRepository()}
+
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		repo.Create(context.Background(), &domain.KV{Key: fmt.Sprintf("expired:%d", i), Value: "a", ExpiresAt: &past})
+	}
+	repo.Create(context.Background(), &domain.KV{Key: "live", Value: "a", ExpiresAt: &future})
+	repo.Create(context.Background(), &domain.KV{Key: "forever", Value: "a"})
+
+	reaper := NewExpiryReaper(repo, &MockLogger{}, time.Hour, 2)
+	reaper.reap()
+
+	// Полные пачки 2 и 2, затем неполная пачка 1 завершает проход
+	if want := []int{2, 2, 1}; fmt.Sprint(repo.calls) != fmt.Sprint(want) {
+		t.Errorf("PurgeExpired() batches = %v, want %v", repo.calls, want)
+	}
+	if len(repo.store) != 2 || repo.store["live"] == nil || repo.store["forever"] == nil {
+		t.Errorf("records after reap = %v, want live and forever", repo.store)
+	}
+
+	// Проход завершается и при числе просроченных, кратном размеру пачки
+	repo.calls = nil
+	for i := 0; i < 2; i++ {
+		repo.Create(context.Background(), &domain.KV{Key: fmt.Sprintf("expired:%d", i), Value: "a", ExpiresAt: &past})
+	}
+	reaper.reap()
+	if want := []int{2, 0}; fmt.Sprint(repo.calls) != fmt.Sprint(want) {
+		t.Errorf("PurgeExpired() batches = %v, want %v", repo.calls, want)
+	}
+}
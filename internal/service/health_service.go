@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+// HealthService выполняет проверки готовности параллельно и собирает их в отчёт.
+// Проверка, не уложившаяся в timeout, считается проваленной.
+type HealthService struct {
+	checks  []interfaces.HealthChecker
+	timeout time.Duration
+	logger  interfaces.Logger
+}
+
+func NewHealthService(checks []interfaces.HealthChecker, timeout time.Duration, logger interfaces.Logger) *HealthService {
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	return &HealthService{
+		checks:  checks,
+		timeout: timeout,
+		logger:  logger,
+	}
+}
+
+func (s *HealthService) Ready(ctx context.Context) *domain.HealthReport {
+	report := &domain.HealthReport{
+		Status: domain.HealthOK,
+		Checks: make([]domain.HealthCheck, len(s.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, checker := range s.checks {
+		wg.Add(1)
+		go func(i int, checker interfaces.HealthChecker) {
+			defer wg.Done()
+			report.Checks[i] = s.run(ctx, checker)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status == domain.HealthFail {
+			s.logger.Warn("Readiness check failed", "check", check.Name, "error", check.Error)
+		}
+		report.Status = worse(report.Status, check.Status)
+	}
+
+	return report
+}
+
+func (s *HealthService) run(ctx context.Context, checker interfaces.HealthChecker) domain.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	// Проверка может не следить за контекстом (например, ждать соединение из пула),
+	// поэтому ответ не ждёт её дольше timeout
+	done := make(chan domain.HealthCheck, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	var check domain.HealthCheck
+	select {
+	case check = <-done:
+	case <-ctx.Done():
+		check = domain.HealthCheck{Status: domain.HealthFail, Error: ctx.Err().Error()}
+	}
+
+	check.Name = checker.Name()
+	check.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	return check
+}
+
+func worse(a, b domain.HealthStatus) domain.HealthStatus {
+	rank := map[domain.HealthStatus]int{
+		domain.HealthOK:   0,
+		domain.HealthWarn: 1,
+		domain.HealthFail: 2,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
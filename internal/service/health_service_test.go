@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+// MockHealthChecker возвращает заданный статус; block заставляет проверку зависнуть
+type MockHealthChecker struct {
+	name   string
+	status domain.HealthStatus
+	block  chan struct{}
+}
+
+func (m *MockHealthChecker) Name() string {
+	return m.name
+}
+
+func (m *MockHealthChecker) Check(ctx context.Context) domain.HealthCheck {
+	if m.block != nil {
+		<-m.block
+	}
+	return domain.HealthCheck{Status: m.status}
+}
+
+func TestHealthService_Ready(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []domain.HealthStatus
+		want     domain.HealthStatus
+	}{
+		{"all ok", []domain.HealthStatus{domain.HealthOK, domain.HealthOK}, domain.HealthOK},
+		{"warn", []domain.HealthStatus{domain.HealthOK, domain.HealthWarn}, domain.HealthWarn},
+		{"fail wins", []domain.HealthStatus{domain.HealthFail, domain.HealthWarn}, domain.HealthFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := make([]MockHealthChecker, len(tt.statuses))
+			service := &HealthService{timeout: time.Second, logger: &MockLogger{}}
+			for i, status := range tt.statuses {
+				checks[i] = MockHealthChecker{name: string(status), status: status}
+				service.checks = append(service.checks, &checks[i])
+			}
+
+			report := service.Ready(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("HealthService.Ready() status = %s, want %s", report.Status, tt.want)
+			}
+			for i, check := range report.Checks {
+				if check.Name != string(tt.statuses[i]) || check.Status != tt.statuses[i] {
+					t.Errorf("HealthService.Ready() check %d = %+v, want %s", i, check, tt.statuses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHealthService_ReadyTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	service := NewHealthService(nil, 20*time.Millisecond, &MockLogger{})
+	service.checks = append(service.checks,
+		&MockHealthChecker{name: "tarantool", status: domain.HealthOK, block: block},
+		&MockHealthChecker{name: "pool", status: domain.HealthOK},
+	)
+
+	report := service.Ready(context.Background())
+	if report.Status != domain.HealthFail {
+		t.Fatalf("HealthService.Ready() status = %s, want %s", report.Status, domain.HealthFail)
+	}
+	if check := report.Checks[0]; check.Status != domain.HealthFail || check.Error == "" {
+		t.Errorf("HealthService.Ready() hung check = %+v, want fail with error", check)
+	}
+	if check := report.Checks[1]; check.Status != domain.HealthOK {
+		t.Errorf("HealthService.Ready() pool check = %+v, want ok", check)
+	}
+}
@@ -1,136 +1,711 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
 	"kv-storage/internal/domain"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxBatchSize    = 1000
+	maxChangesLimit = 1000
 )
 
+var tracer = otel.Tracer("kv-storage/internal/service")
+
 type KVService struct {
-	repo   interfaces.KVRepository
-	logger interfaces.Logger
+	repo      interfaces.KVRepository
+	logger    interfaces.Logger
+	publisher interfaces.EventPublisher
+	namespace string
 }
 
-func NewKVService(repo interfaces.KVRepository, logger interfaces.Logger) *KVService {
+// NewKVService создаёт сервис; publisher может быть nil, тогда события изменений не публикуются
+func NewKVService(repo interfaces.KVRepository, logger interfaces.Logger, publisher interfaces.EventPublisher) *KVService {
 	return &KVService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		logger:    logger,
+		publisher: publisher,
+		namespace: domain.DefaultNamespace,
 	}
 }
 
-func (s *KVService) Create(req *domain.CreateKVRequest) (*domain.KV, error) {
+// Namespace возвращает сервис, все операции которого выполняются в namespace name
+// и не видят ключей других namespace
+func (s *KVService) Namespace(name string) *KVService {
+	if name == s.namespace {
+		return s
+	}
+	return &KVService{
+		repo:      s.repo.Namespace(name),
+		logger:    s.logger,
+		publisher: s.publisher,
+		namespace: name,
+	}
+}
+
+func (s *KVService) Create(ctx context.Context, req *domain.CreateKVRequest) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Create", req.Key)
+	defer func() { tracing.End(span, err) }()
+
 	if req.Key == "" {
 		return nil, domain.ErrInvalidKey
 	}
 
+	value, contentType, err := domain.NormalizeValue(req.Value, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := resolveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := &domain.KV{
+		Key:         req.Key,
+		Value:       value,
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := s.repo.Create(ctx, kv); err != nil {
+		return nil, err
+	}
+
+	s.publish(domain.ChangeOpCreate, kv, nil)
+	return kv, nil
+}
+
+func (s *KVService) Get(ctx context.Context, key string) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Get", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	return s.repo.Get(ctx, key)
+}
+
+func (s *KVService) Update(ctx context.Context, key string, req *domain.UpdateKVRequest) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Update", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	value, contentType, err := domain.NormalizeValue(req.Value, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := resolveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if req.Persist {
+		if expiresAt != nil {
+			return nil, domain.ErrInvalidTTL
+		}
+		noExpiry := domain.NoExpiry
+		expiresAt = &noExpiry
+	}
+
 	kv := &domain.KV{
-		Key:   req.Key,
-		Value: req.Value,
+		Key:         key,
+		Value:       value,
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
 	}
 
-	if err := s.repo.Create(kv); err != nil {
+	previous, err := s.repo.Update(ctx, kv, req.ExpectedVersion)
+	if err != nil {
 		return nil, err
 	}
 
+	s.publish(domain.ChangeOpUpdate, kv, previous)
 	return kv, nil
 }
 
-func (s *KVService) Get(key string) (*domain.KV, error) {
+// CompareAndSwap записывает новое значение, только если текущее совпадает с ожидаемым
+func (s *KVService) CompareAndSwap(ctx context.Context, key string, req *domain.CASRequest) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "CompareAndSwap", key)
+	defer func() { tracing.End(span, err) }()
+
 	if key == "" {
 		return nil, domain.ErrInvalidKey
 	}
+	if req.ExpectedValue == nil && req.ExpectedVersion == 0 {
+		return nil, domain.ErrValidationError
+	}
+
+	value, contentType, err := domain.NormalizeValue(req.NewValue, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var expectedValue interface{}
+	if req.ExpectedValue != nil {
+		if expectedValue, _, err = domain.NormalizeValue(req.ExpectedValue, req.ContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	expiresAt, err := resolveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	kv := &domain.KV{
+		Key:         key,
+		Value:       value,
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
+	}
 
-	return s.repo.Get(key)
+	previous, err := s.repo.CompareAndSwap(ctx, kv, expectedValue, req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(domain.ChangeOpUpdate, kv, previous)
+	return kv, nil
 }
 
-func (s *KVService) Update(key string, req *domain.UpdateKVRequest) (*domain.KV, error) {
+// PutIfAbsent создаёт запись, только если ключа ещё нет. Если он есть,
+// возвращает существующую запись вместе с domain.ErrKeyAlreadyExists.
+func (s *KVService) PutIfAbsent(ctx context.Context, key string, req *domain.UpdateKVRequest) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "PutIfAbsent", key)
+	defer func() { tracing.End(span, err) }()
+
 	if key == "" {
 		return nil, domain.ErrInvalidKey
 	}
 
+	value, contentType, err := domain.NormalizeValue(req.Value, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, err := resolveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
 	kv := &domain.KV{
-		Key:   key,
-		Value: req.Value,
+		Key:         key,
+		Value:       value,
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
+	}
+
+	existing, err := s.repo.PutIfAbsent(ctx, kv)
+	if err != nil {
+		return existing, err
+	}
+
+	s.publish(domain.ChangeOpCreate, kv, nil)
+	return kv, nil
+}
+
+// Increment атомарно изменяет счётчик и возвращает запись с новым значением
+func (s *KVService) Increment(ctx context.Context, key string, req *domain.IncrementRequest) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Increment", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
 	}
 
-	if err := s.repo.Update(kv); err != nil {
+	delta := int64(1)
+	if req.Delta != nil {
+		delta = *req.Delta
+	}
+
+	expiresAt, err := resolveExpiry(req.TTL, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	kv, previous, err := s.repo.Increment(ctx, key, delta, req.Initial, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous == nil {
+		s.publish(domain.ChangeOpCreate, kv, nil)
+	} else {
+		s.publish(domain.ChangeOpUpdate, kv, previous)
+	}
+	return kv, nil
+}
+
+func (s *KVService) Delete(ctx context.Context, key string, expectedVersion uint64) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Delete", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	kv, err := s.repo.Delete(ctx, key, expectedVersion)
+	if err != nil {
 		return nil, err
 	}
 
+	s.publish(domain.ChangeOpDelete, nil, kv)
 	return kv, nil
 }
 
-func (s *KVService) Delete(key string) (*domain.KV, error) {
+func (s *KVService) SoftDelete(ctx context.Context, key string, expectedVersion uint64) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "SoftDelete", key)
+	defer func() { tracing.End(span, err) }()
+
 	if key == "" {
 		return nil, domain.ErrInvalidKey
 	}
 
-	return s.repo.Delete(key)
+	kv, err := s.repo.SoftDelete(ctx, key, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(domain.ChangeOpSoftDelete, nil, kv)
+	return kv, nil
 }
 
-func (s *KVService) SoftDelete(key string) (*domain.KV, error) {
+func (s *KVService) Restore(ctx context.Context, key string) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Restore", key)
+	defer func() { tracing.End(span, err) }()
+
 	if key == "" {
 		return nil, domain.ErrInvalidKey
 	}
 
-	err := s.repo.SoftDelete(key)
+	restored, err := s.repo.Restore(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.Get(key)
+	// Повторное чтение могло бы уйти на отстающую реплику и вернуть запись удалённой
+	s.publish(domain.ChangeOpRestore, restored, nil)
+	return restored, nil
 }
 
-func (s *KVService) Restore(key string) (*domain.KV, error) {
+func (s *KVService) History(ctx context.Context, key string) (_ *domain.HistoryResponse, err error) {
+	ctx, span := s.startSpan(ctx, "History", key)
+	defer func() { tracing.End(span, err) }()
+
 	if key == "" {
 		return nil, domain.ErrInvalidKey
 	}
 
-	_, err := s.repo.Restore(key)
+	items, err := s.repo.History(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.Get(key)
+	return &domain.HistoryResponse{Key: key, Items: items}, nil
 }
 
-func (s *KVService) List(limit, offset int) (*domain.ListKVResponse, error) {
-	if limit <= 0 {
-		limit = 10
+// GetVersion возвращает ревизию записи с указанной версией
+func (s *KVService) GetVersion(ctx context.Context, key string, version uint64) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "GetVersion", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	items, err := s.repo.History(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kv := range items {
+		if kv.Version == version && !kv.IsDeleted {
+			return kv, nil
+		}
+	}
+	return nil, domain.ErrRevisionNotFound
+}
+
+// GetAt возвращает значение, которое запись имела в момент at
+func (s *KVService) GetAt(ctx context.Context, key string, at time.Time) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "GetAt", key)
+	defer func() { tracing.End(span, err) }()
+
+	if key == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	items, err := s.repo.History(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ревизия действует с момента своего updated_at до появления следующей
+	for _, kv := range items {
+		if kv.UpdatedAt.After(at) {
+			continue
+		}
+		if kv.IsDeleted {
+			return nil, domain.ErrRevisionNotFound
+		}
+		return kv, nil
+	}
+	return nil, domain.ErrRevisionNotFound
+}
+
+// Revert записывает значение старой ревизии обычным обновлением,
+// поэтому откат сам попадает в историю, журнал и поток изменений
+func (s *KVService) Revert(ctx context.Context, key string, version, expectedVersion uint64) (_ *domain.KV, err error) {
+	ctx, span := s.startSpan(ctx, "Revert", key)
+	defer func() { tracing.End(span, err) }()
+
+	revision, err := s.GetVersion(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Update(ctx, key, &domain.UpdateKVRequest{
+		Value:           revision.Value,
+		ContentType:     revision.ContentType,
+		ExpectedVersion: expectedVersion,
+	})
+}
+
+func (s *KVService) List(ctx context.Context, req *domain.ListKVRequest) (_ *domain.ListKVResponse, err error) {
+	ctx, span := s.startSpan(ctx, "List", "")
+	defer func() { tracing.End(span, err) }()
+
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return listResponse(page, opts), nil
+}
+
+func (s *KVService) ListIncludingDeleted(ctx context.Context, req *domain.ListKVRequest) (_ *domain.ListKVResponse, err error) {
+	ctx, span := s.startSpan(ctx, "ListIncludingDeleted", "")
+	defer func() { tracing.End(span, err) }()
+
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := s.repo.ListIncludingDeleted(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
-	if offset < 0 {
-		offset = 0
+
+	return listResponse(page, opts), nil
+}
+
+func (s *KVService) Scan(ctx context.Context, req *domain.ScanKVRequest) (_ *domain.ListKVResponse, err error) {
+	ctx, span := s.startSpan(ctx, "Scan", "")
+	defer func() { tracing.End(span, err) }()
+
+	if req.From != "" && req.To != "" && req.From >= req.To {
+		return nil, domain.ErrValidationError
 	}
 
-	items, total, err := s.repo.List(limit, offset)
+	opts := domain.ScanOptions{
+		Prefix:         req.Prefix,
+		From:           req.From,
+		To:             req.To,
+		Limit:          req.Limit,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		opts.After = after
+	}
+
+	page, err := s.repo.Scan(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return &domain.ListKVResponse{
-		Items:  items,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	}, nil
+	// Общее число записей в диапазоне не считается: это потребовало бы полного обхода
+	return listResponse(page, domain.ListOptions{Limit: opts.Limit, SkipCount: true}), nil
+}
+
+func (s *KVService) Batch(ctx context.Context, req *domain.BatchRequest) (_ *domain.BatchResponse, err error) {
+	ctx, span := s.startSpan(ctx, "Batch", "")
+	defer func() { tracing.End(span, err) }()
+
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSize {
+		return nil, domain.ErrValidationError
+	}
+
+	mode := req.Mode
+	switch mode {
+	case "":
+		mode = domain.BatchModeAtomic
+	case domain.BatchModeAtomic, domain.BatchModeBestEffort:
+	default:
+		return nil, domain.ErrValidationError
+	}
+
+	ops := make([]domain.BatchOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		if op.Key == "" {
+			return nil, domain.ErrInvalidKey
+		}
+
+		switch op.Op {
+		case domain.BatchOpGet, domain.BatchOpDelete:
+		case domain.BatchOpCreate, domain.BatchOpUpdate:
+			value, contentType, err := domain.NormalizeValue(op.Value, op.ContentType)
+			if err != nil {
+				return nil, err
+			}
+			op.Value = value
+			op.ContentType = contentType
+
+			expiresAt, err := resolveExpiry(op.TTL, op.ExpiresAt)
+			if err != nil {
+				return nil, err
+			}
+			op.TTL = 0
+			op.ExpiresAt = expiresAt
+		default:
+			return nil, domain.ErrValidationError
+		}
+
+		ops[i] = op
+	}
+
+	results, err := s.repo.Batch(ctx, ops, mode == domain.BatchModeAtomic)
+	if results == nil {
+		return nil, err
+	}
+	if err == nil {
+		s.publishBatch(results)
+	}
+
+	return &domain.BatchResponse{
+		Mode:    mode,
+		Results: results,
+	}, err
 }
 
-func (s *KVService) ListIncludingDeleted(limit, offset int) (*domain.ListKVResponse, error) {
+// Changes отдаёт записи журнала изменений после курсора или, без него, после LSN since
+func (s *KVService) Changes(ctx context.Context, req *domain.ChangesRequest) (_ *domain.ChangesResponse, err error) {
+	ctx, span := s.startSpan(ctx, "Changes", "")
+	defer func() { tracing.End(span, err) }()
+
+	limit := req.Limit
 	if limit <= 0 {
-		limit = 10
+		limit = 100
+	}
+	if limit > maxChangesLimit {
+		limit = maxChangesLimit
 	}
-	if offset < 0 {
-		offset = 0
+
+	var cursor domain.ChangesCursor
+	switch {
+	case req.Cursor != "":
+		cursor, err = decodeChangesCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	case req.Since > 0:
+		cursor = domain.ChangesCursor{req.Since}
 	}
 
-	items, total, err := s.repo.ListIncludingDeleted(limit, offset)
+	entries, next, err := s.repo.Changes(ctx, cursor, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	return &domain.ListKVResponse{
-		Items:  items,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	}, nil
+	response := &domain.ChangesResponse{
+		Items:      entries,
+		NextCursor: encodeChangesCursor(next),
+	}
+	// Одним LSN позицию можно выразить, только если журнал один
+	if len(next) == 1 {
+		response.NextSince = next[0]
+	}
+	return response, nil
+}
+
+// startSpan начинает span метода сервиса; пустой key не записывается
+func (s *KVService) startSpan(ctx context.Context, method, key string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{attribute.String("kv.namespace", s.namespace)}
+	if key != "" {
+		attributes = append(attributes, attribute.String("kv.key", key))
+	}
+	return tracer.Start(ctx, "KVService."+method, trace.WithAttributes(attributes...))
+}
+
+// publish отправляет событие изменения. current — состояние после операции,
+// previous — до неё; для удаления current не передаётся, для создания — previous.
+func (s *KVService) publish(op domain.ChangeOp, current, previous *domain.KV) {
+	if s.publisher == nil {
+		return
+	}
+
+	event := domain.ChangeEvent{
+		Namespace: s.namespace,
+		Op:        op,
+		Timestamp: time.Now(),
+	}
+	if previous != nil {
+		event.Key = previous.Key
+		event.OldValue = previous.Value
+		event.Version = previous.Version
+	}
+	if current != nil {
+		event.Key = current.Key
+		event.NewValue = current.Value
+		event.Version = current.Version
+	}
+
+	s.publisher.Publish(event)
+}
+
+// publishBatch публикует изменения из пакета. Предыдущее значение для update
+// в пакете неизвестно, поэтому old_value у таких событий не заполняется.
+func (s *KVService) publishBatch(results []domain.BatchItemResult) {
+	for _, result := range results {
+		if result.Error != "" || result.KV == nil {
+			continue
+		}
+		switch result.Op {
+		case domain.BatchOpCreate:
+			s.publish(domain.ChangeOpCreate, result.KV, nil)
+		case domain.BatchOpUpdate:
+			s.publish(domain.ChangeOpUpdate, result.KV, nil)
+		case domain.BatchOpDelete:
+			s.publish(domain.ChangeOpSoftDelete, nil, result.KV)
+		}
+	}
+}
+
+// resolveExpiry переводит TTL в секундах или явный момент истечения в абсолютное время.
+// nil означает, что запись живёт бессрочно (или, для Update, что срок не меняется).
+func resolveExpiry(ttl int64, at *time.Time) (*time.Time, error) {
+	switch {
+	case ttl < 0:
+		return nil, domain.ErrInvalidTTL
+	case ttl > 0 && at != nil:
+		return nil, domain.ErrInvalidTTL
+	case ttl > 0:
+		t := time.Now().Add(time.Duration(ttl) * time.Second)
+		return &t, nil
+	case at != nil:
+		if !at.After(time.Now()) {
+			return nil, domain.ErrInvalidTTL
+		}
+		return at, nil
+	}
+	return nil, nil
+}
+
+func listOptions(req *domain.ListKVRequest) (domain.ListOptions, error) {
+	opts := domain.ListOptions{
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+		SkipCount: req.SkipCount,
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return opts, err
+		}
+		opts.After = after
+		opts.Offset = 0
+	}
+
+	return opts, nil
+}
+
+func listResponse(page *domain.ListPage, opts domain.ListOptions) *domain.ListKVResponse {
+	response := &domain.ListKVResponse{
+		Items:  page.Items,
+		Limit:  opts.Limit,
+		Offset: opts.Offset,
+	}
+	if !opts.SkipCount {
+		total := page.Total
+		response.Total = &total
+	}
+	if page.NextKey != "" {
+		response.Cursor = encodeCursor(page.NextKey)
+	}
+	return response
+}
+
+// Курсор непрозрачен для клиентов: внутри лежит ключ последней записи страницы
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(key) == 0 {
+		return "", domain.ErrInvalidCursor
+	}
+	return string(key), nil
+}
+
+// Курсор журнала — LSN каждого шарда через точку
+func encodeChangesCursor(cursor domain.ChangesCursor) string {
+	lsns := make([]string, len(cursor))
+	for i, lsn := range cursor {
+		lsns[i] = strconv.FormatUint(lsn, 10)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(lsns, ".")))
+}
+
+func decodeChangesCursor(cursor string) (domain.ChangesCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(data) == 0 {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(data), ".")
+	decoded := make(domain.ChangesCursor, len(parts))
+	for i, part := range parts {
+		lsn, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		decoded[i] = lsn
+	}
+	return decoded, nil
 }
@@ -1,28 +1,50 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
 	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
 )
 
 // MockRepository мок репозитория для тестирования
 type MockRepository struct {
 	store map[string]*domain.KV
-	mu    sync.Mutex
+	// history хранит предыдущие ревизии ключа, от старых к новым
+	history   map[string][]*domain.KV
+	changelog []domain.ChangeLogEntry
+	// namespaces — отдельные хранилища для namespace, кроме default
+	namespaces map[string]*MockRepository
+	mu         sync.Mutex
 }
 
 func NewMockRepository() *MockRepository {
 	return &MockRepository{
-		store: make(map[string]*domain.KV),
-		mu:    sync.Mutex{},
+		store:      make(map[string]*domain.KV),
+		history:    make(map[string][]*domain.KV),
+		namespaces: make(map[string]*MockRepository),
+		mu:         sync.Mutex{},
 	}
 }
 
-func (m *MockRepository) Create(kv *domain.KV) error {
+func (m *MockRepository) Namespace(name string) interfaces.KVRepository {
+	if name == domain.DefaultNamespace {
+		return m
+	}
+	if _, exists := m.namespaces[name]; !exists {
+		m.namespaces[name] = NewMockRepository()
+	}
+	return m.namespaces[name]
+}
+
+func (m *MockRepository) Create(ctx context.Context, kv *domain.KV) error {
 	if _, exists := m.store[kv.Key]; exists {
 		return domain.ErrKeyExists
 	}
@@ -30,61 +52,182 @@ func (m *MockRepository) Create(kv *domain.KV) error {
 	return nil
 }
 
-func (m *MockRepository) Get(key string) (*domain.KV, error) {
-	if kv, exists := m.store[key]; exists {
+func (m *MockRepository) Get(ctx context.Context, key string) (*domain.KV, error) {
+	// Запись с истёкшим TTL не видна до удаления reaper'ом, как в Tarantool
+	if kv, exists := m.store[key]; exists && !kv.IsExpired(time.Now()) {
 		return kv, nil
 	}
 	return nil, domain.ErrKeyNotFound
 }
 
-func (m *MockRepository) Update(kv *domain.KV) error {
-	if _, exists := m.store[kv.Key]; !exists {
-		return domain.ErrKeyNotFound
+func (m *MockRepository) Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error) {
+	current, exists := m.store[kv.Key]
+	if !exists {
+		return nil, domain.ErrKeyNotFound
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	kv.Version = current.Version + 1
+	m.store[kv.Key] = kv
+	m.history[kv.Key] = append(m.history[kv.Key], current)
+	return current, nil
+}
+
+func (m *MockRepository) CompareAndSwap(ctx context.Context, kv *domain.KV, expectedValue interface{}, expectedVersion uint64) (*domain.KV, error) {
+	current, exists := m.store[kv.Key]
+	if !exists || current.IsDeleted {
+		return nil, domain.ErrKeyNotFound
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
 	}
+	if expectedValue != nil && !reflect.DeepEqual(current.Value, expectedValue) {
+		return nil, domain.ErrValueMismatch
+	}
+	kv.Version = current.Version + 1
 	m.store[kv.Key] = kv
-	return nil
+	return current, nil
 }
 
-func (m *MockRepository) Delete(key string) (*domain.KV, error) {
+func (m *MockRepository) PutIfAbsent(ctx context.Context, kv *domain.KV) (*domain.KV, error) {
+	if existing, exists := m.store[kv.Key]; exists {
+		return existing, domain.ErrKeyAlreadyExists
+	}
+	kv.Version = 1
+	m.store[kv.Key] = kv
+	return nil, nil
+}
+
+func (m *MockRepository) Increment(ctx context.Context, key string, delta, initial int64, expiresAt *time.Time) (*domain.KV, *domain.KV, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current, exists := m.store[key]
+	if !exists {
+		kv := &domain.KV{Key: key, Value: initial + delta, ContentType: domain.ContentTypeJSON, ExpiresAt: expiresAt, Version: 1}
+		m.store[key] = kv
+		return kv, nil, nil
+	}
+	value, ok := current.Value.(int64)
+	if !ok {
+		return nil, nil, domain.ErrNotNumeric
+	}
+	kv := *current
+	kv.Value = value + delta
+	kv.Version++
+	m.store[key] = &kv
+	return &kv, current, nil
+}
+
+func (m *MockRepository) Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
 	if kv, exists := m.store[key]; exists {
+		if expectedVersion != 0 && kv.Version != expectedVersion {
+			return nil, domain.ErrVersionConflict
+		}
 		delete(m.store, key)
 		return kv, nil
 	}
 	return nil, domain.ErrKeyNotFound
 }
 
-func (m *MockRepository) List(limit, offset int) ([]*domain.KV, int, error) {
-	items := make([]*domain.KV, 0)
-	count := 0
+func (m *MockRepository) List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	return m.page(opts, false), nil
+}
 
-	for _, kv := range m.store {
-		if count >= offset && len(items) < limit {
-			items = append(items, kv)
+// page отдаёт записи в порядке ключей, как индексы Tarantool
+func (m *MockRepository) page(opts domain.ListOptions, includeDeleted bool) *domain.ListPage {
+	now := time.Now()
+	live := func(kv *domain.KV) bool {
+		return includeDeleted || (!kv.IsDeleted && !kv.IsExpired(now))
+	}
+
+	keys := make([]string, 0, len(m.store))
+	for key, kv := range m.store {
+		if !live(kv) {
+			continue
+		}
+		if opts.After != "" && key <= opts.After {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if opts.After == "" {
+		if opts.Offset >= len(keys) {
+			keys = nil
+		} else {
+			keys = keys[opts.Offset:]
+		}
+	}
+
+	page := &domain.ListPage{}
+	if !opts.SkipCount {
+		for _, kv := range m.store {
+			if live(kv) {
+				page.Total++
+			}
+		}
+	}
+	for _, key := range keys {
+		if len(page.Items) == opts.Limit {
+			page.NextKey = page.Items[len(page.Items)-1].Key
+			break
 		}
-		count++
+		page.Items = append(page.Items, m.store[key])
 	}
 
-	return items, len(m.store), nil
+	return page
 }
 
-func (m *MockRepository) SoftDelete(key string) error {
+func (m *MockRepository) Scan(ctx context.Context, opts domain.ScanOptions) (*domain.ListPage, error) {
+	keys := make([]string, 0, len(m.store))
+	for key, kv := range m.store {
+		if !opts.InRange(key) || (opts.After != "" && key <= opts.After) {
+			continue
+		}
+		if kv.IsDeleted && !opts.IncludeDeleted {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	page := &domain.ListPage{}
+	for _, key := range keys {
+		page.Items = append(page.Items, m.store[key])
+		if len(page.Items) == opts.Limit {
+			page.NextKey = key
+			break
+		}
+	}
+
+	return page, nil
+}
+
+func (m *MockRepository) SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	kv, exists := m.store[key]
 	if !exists {
-		return domain.ErrKeyNotFound
+		return nil, domain.ErrKeyNotFound
+	}
+	if expectedVersion != 0 && kv.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
 	}
 
 	now := time.Now()
 	kv.UpdatedAt = now
 	kv.DeletedAt = &now
 	kv.IsDeleted = true
+	kv.Version++
 
-	return nil
+	return kv, nil
 }
 
-func (m *MockRepository) Restore(key string) (*domain.KV, error) {
+func (m *MockRepository) Restore(ctx context.Context, key string) (*domain.KV, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -105,21 +248,112 @@ func (m *MockRepository) Restore(key string) (*domain.KV, error) {
 	return kv, nil
 }
 
-func (m *MockRepository) ListIncludingDeleted(limit, offset int) ([]*domain.KV, int, error) {
+func (m *MockRepository) ListIncludingDeleted(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.page(opts, true), nil
+}
+
+func (m *MockRepository) Batch(ctx context.Context, ops []domain.BatchOperation, atomic bool) ([]domain.BatchItemResult, error) {
+	results := make([]domain.BatchItemResult, len(ops))
+	for i, op := range ops {
+		results[i] = domain.BatchItemResult{Op: op.Op, Key: op.Key}
+
+		var kv *domain.KV
+		var err error
+		switch op.Op {
+		case domain.BatchOpGet:
+			kv, err = m.Get(ctx, op.Key)
+		case domain.BatchOpCreate:
+			kv = &domain.KV{Key: op.Key, Value: op.Value, ExpiresAt: op.ExpiresAt}
+			err = m.Create(ctx, kv)
+		case domain.BatchOpUpdate:
+			kv = &domain.KV{Key: op.Key, Value: op.Value, ExpiresAt: op.ExpiresAt}
+			_, err = m.Update(ctx, kv, op.Version)
+		case domain.BatchOpDelete:
+			kv, err = m.SoftDelete(ctx, op.Key, op.Version)
+		}
+
+		if err != nil {
+			results[i].Error = err.Error()
+			if atomic {
+				return results, domain.ErrBatchAborted
+			}
+			continue
+		}
+		results[i].KV = kv
+	}
+
+	return results, nil
+}
+
+func (m *MockRepository) History(ctx context.Context, key string) ([]*domain.KV, error) {
+	var items []*domain.KV
+	if kv, exists := m.store[key]; exists {
+		items = append(items, kv)
+	}
+	revisions := m.history[key]
+	for i := len(revisions) - 1; i >= 0; i-- {
+		items = append(items, revisions[i])
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrKeyNotFound
+	}
+	return items, nil
+}
+
+func (m *MockRepository) Changes(ctx context.Context, cursor domain.ChangesCursor, limit int) ([]domain.ChangeLogEntry, domain.ChangesCursor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(cursor) > 1 {
+		return nil, nil, domain.ErrInvalidCursor
+	}
+	var since uint64
+	if len(cursor) == 1 {
+		since = cursor[0]
+	}
+
+	entries := []domain.ChangeLogEntry{}
+	for _, entry := range m.changelog {
+		if entry.LSN > since && len(entries) < limit {
+			entries = append(entries, entry)
+			since = entry.LSN
+		}
+	}
+	return entries, domain.ChangesCursor{since}, nil
+}
+
+func (m *MockRepository) TrimChangelog(ctx context.Context, before time.Time, limit int) (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	items := make([]*domain.KV, 0)
-	count := 0
+	trimmed := 0
+	for trimmed < len(m.changelog) && trimmed < limit && m.changelog[trimmed].Timestamp.Before(before) {
+		trimmed++
+	}
+	m.changelog = m.changelog[trimmed:]
+	return trimmed, nil
+}
+
+func (m *MockRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	for _, kv := range m.store {
-		if count >= offset && len(items) < limit {
-			items = append(items, kv)
+	purged := 0
+	now := time.Now()
+	for key, kv := range m.store {
+		if purged >= limit {
+			break
+		}
+		if kv.IsExpired(now) {
+			delete(m.store, key)
+			purged++
 		}
-		count++
 	}
 
-	return items, len(m.store), nil
+	return purged, nil
 }
 
 func (m *MockRepository) Close() error {
@@ -139,7 +373,7 @@ func (m *MockLogger) Sync() error                                    { return ni
 func TestKVService_Create(t *testing.T) {
 	repo := NewMockRepository()
 	logger := &MockLogger{}
-	service := NewKVService(repo, logger)
+	service := NewKVService(repo, logger, nil)
 
 	tests := []struct {
 		name    string
@@ -166,15 +400,81 @@ func TestKVService_Create(t *testing.T) {
 			name: "empty value",
 			req: &domain.CreateKVRequest{
 				Key:   "test-key",
+				Value: "",
+			},
+			wantErr: domain.ErrInvalidValue,
+		},
+		{
+			name: "missing value",
+			req: &domain.CreateKVRequest{
+				Key: "test-key",
+			},
+			wantErr: domain.ErrInvalidValue,
+		},
+		{
+			name: "empty object",
+			req: &domain.CreateKVRequest{
+				Key:   "empty-object",
 				Value: map[string]interface{}{},
 			},
+			wantErr: nil,
+		},
+		{
+			name: "empty array",
+			req: &domain.CreateKVRequest{
+				Key:   "empty-array",
+				Value: []interface{}{},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "zero counter",
+			req: &domain.CreateKVRequest{
+				Key:   "counter",
+				Value: json.Number("0"),
+			},
+			wantErr: nil,
+		},
+		{
+			name: "boolean",
+			req: &domain.CreateKVRequest{
+				Key:   "flag",
+				Value: false,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "binary as base64",
+			req: &domain.CreateKVRequest{
+				Key:         "blob",
+				Value:       "AAEC",
+				ContentType: domain.ContentTypeBinary,
+			},
+			wantErr: nil,
+		},
+		{
+			name: "invalid base64",
+			req: &domain.CreateKVRequest{
+				Key:         "broken-blob",
+				Value:       "not base64!",
+				ContentType: domain.ContentTypeBinary,
+			},
 			wantErr: domain.ErrInvalidValue,
 		},
+		{
+			name: "unsupported content type",
+			req: &domain.CreateKVRequest{
+				Key:         "xml",
+				Value:       "<a/>",
+				ContentType: "application/xml",
+			},
+			wantErr: domain.ErrUnsupportedContentType,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := service.Create(tt.req)
+			_, err := service.Create(context.Background(), tt.req)
 			if err != tt.wantErr {
 				t.Errorf("KVService.Create() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -185,14 +485,14 @@ func TestKVService_Create(t *testing.T) {
 func TestKVService_Get(t *testing.T) {
 	repo := NewMockRepository()
 	logger := &MockLogger{}
-	service := NewKVService(repo, logger)
+	service := NewKVService(repo, logger, nil)
 
 	// Создаем тестовую запись
 	testKV := &domain.KV{
 		Key:   "test-key",
 		Value: map[string]interface{}{"test": "value"},
 	}
-	err := repo.Create(testKV)
+	err := repo.Create(context.Background(), testKV)
 	if err != nil {
 		fmt.Printf("create err: %v", err)
 	}
@@ -221,7 +521,7 @@ func TestKVService_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := service.Get(tt.key)
+			_, err := service.Get(context.Background(), tt.key)
 			if err != tt.wantErr {
 				t.Errorf("KVService.Get() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -232,14 +532,14 @@ func TestKVService_Get(t *testing.T) {
 func TestKVService_Update(t *testing.T) {
 	repo := NewMockRepository()
 	logger := &MockLogger{}
-	service := NewKVService(repo, logger)
+	service := NewKVService(repo, logger, nil)
 
 	// Создаем тестовую запись
 	testKV := &domain.KV{
 		Key:   "test-key",
 		Value: map[string]interface{}{"name": "old"},
 	}
-	repo.Create(testKV)
+	repo.Create(context.Background(), testKV)
 
 	tests := []struct {
 		name    string
@@ -267,7 +567,7 @@ func TestKVService_Update(t *testing.T) {
 			name: "empty value",
 			key:  "test-key",
 			req: &domain.UpdateKVRequest{
-				Value: map[string]interface{}{},
+				Value: "",
 			},
 			wantErr: domain.ErrInvalidValue,
 		},
@@ -275,7 +575,7 @@ func TestKVService_Update(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := service.Update(tt.key, tt.req)
+			_, err := service.Update(context.Background(), tt.key, tt.req)
 			if err != tt.wantErr {
 				t.Errorf("KVService.Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -286,14 +586,14 @@ func TestKVService_Update(t *testing.T) {
 func TestKVService_Delete(t *testing.T) {
 	repo := NewMockRepository()
 	logger := &MockLogger{}
-	service := NewKVService(repo, logger)
+	service := NewKVService(repo, logger, nil)
 
 	// Создаем тестовую запись
 	testKV := &domain.KV{
 		Key:   "test-key",
 		Value: map[string]interface{}{"test": "value"},
 	}
-	repo.Create(testKV)
+	repo.Create(context.Background(), testKV)
 
 	tests := []struct {
 		name    string
@@ -319,10 +619,805 @@ func TestKVService_Delete(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := service.Delete(tt.key)
+			_, err := service.Delete(context.Background(), tt.key, 0)
 			if err != tt.wantErr {
 				t.Errorf("KVService.Delete() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+// laggingReplica отдаёт чтения с реплики, которая ещё не получила восстановление
+func TestKVService_ExpectedVersion(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+	ctx := context.Background()
+
+	for _, key := range []string{"update", "soft", "hard"} {
+		repo.Create(ctx, &domain.KV{Key: key, Value: "a", Version: 1})
+	}
+
+	if _, err := service.Update(ctx, "update", &domain.UpdateKVRequest{Value: "b", ExpectedVersion: 2}); err != domain.ErrVersionConflict {
+		t.Errorf("KVService.Update(stale version) error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+	kv, err := service.Update(ctx, "update", &domain.UpdateKVRequest{Value: "b", ExpectedVersion: 1})
+	if err != nil || kv.Version != 2 {
+		t.Fatalf("KVService.Update(current version) = %+v, %v, want version 2", kv, err)
+	}
+	if _, err := service.Update(ctx, "update", &domain.UpdateKVRequest{Value: "c", ExpectedVersion: 1}); err != domain.ErrVersionConflict {
+		t.Errorf("KVService.Update(replaced version) error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+
+	if _, err := service.SoftDelete(ctx, "soft", 2); err != domain.ErrVersionConflict {
+		t.Errorf("KVService.SoftDelete(stale version) error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+	if _, err := service.Delete(ctx, "hard", 2); err != domain.ErrVersionConflict {
+		t.Errorf("KVService.Delete(stale version) error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+	// Неудачная проверка версии не меняет запись
+	for _, key := range []string{"soft", "hard"} {
+		if kv, err := repo.Get(ctx, key); err != nil || kv.IsDeleted || kv.Version != 1 {
+			t.Errorf("record %s after conflict = %+v, %v, want version 1", key, kv, err)
+		}
+	}
+
+	if _, err := service.SoftDelete(ctx, "soft", 1); err != nil {
+		t.Errorf("KVService.SoftDelete(current version) error = %v", err)
+	}
+	if _, err := service.Delete(ctx, "hard", 1); err != nil {
+		t.Errorf("KVService.Delete(current version) error = %v", err)
+	}
+}
+
+type laggingReplica struct {
+	*MockRepository
+}
+
+func (r laggingReplica) Get(ctx context.Context, key string) (*domain.KV, error) {
+	return nil, domain.ErrKeyNotFound
+}
+
+func TestKVService_Restore(t *testing.T) {
+	repo := NewMockRepository()
+	service := NewKVService(laggingReplica{repo}, &MockLogger{}, nil)
+	ctx := context.Background()
+
+	repo.Create(ctx, &domain.KV{Key: "test-key", Value: "value"})
+	if _, err := repo.SoftDelete(ctx, "test-key", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	kv, err := service.Restore(ctx, "test-key")
+	if err != nil {
+		t.Fatalf("KVService.Restore() error = %v", err)
+	}
+	if kv.IsDeleted || kv.Value != "value" {
+		t.Errorf("KVService.Restore() = %+v, want restored record", kv)
+	}
+
+	if _, err := service.Restore(ctx, "test-key"); err != domain.ErrNotDeleted {
+		t.Errorf("KVService.Restore() of live record error = %v, want %v", err, domain.ErrNotDeleted)
+	}
+}
+
+func TestKVService_Batch(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	repo.Create(context.Background(), &domain.KV{Key: "existing", Value: "value"})
+
+	tests := []struct {
+		name    string
+		req     *domain.BatchRequest
+		wantErr error
+	}{
+		{
+			name: "valid batch",
+			req: &domain.BatchRequest{
+				Operations: []domain.BatchOperation{
+					{Op: domain.BatchOpCreate, Key: "new-key", Value: "value"},
+					{Op: domain.BatchOpGet, Key: "existing"},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "empty batch",
+			req:     &domain.BatchRequest{},
+			wantErr: domain.ErrValidationError,
+		},
+		{
+			name: "unknown mode",
+			req: &domain.BatchRequest{
+				Mode:       "eventually",
+				Operations: []domain.BatchOperation{{Op: domain.BatchOpGet, Key: "existing"}},
+			},
+			wantErr: domain.ErrValidationError,
+		},
+		{
+			name: "unknown operation",
+			req: &domain.BatchRequest{
+				Operations: []domain.BatchOperation{{Op: "merge", Key: "existing"}},
+			},
+			wantErr: domain.ErrValidationError,
+		},
+		{
+			name: "create without value",
+			req: &domain.BatchRequest{
+				Operations: []domain.BatchOperation{{Op: domain.BatchOpCreate, Key: "other-key"}},
+			},
+			wantErr: domain.ErrInvalidValue,
+		},
+		{
+			name: "atomic batch with failing operation",
+			req: &domain.BatchRequest{
+				Mode: domain.BatchModeAtomic,
+				Operations: []domain.BatchOperation{
+					{Op: domain.BatchOpGet, Key: "existing"},
+					{Op: domain.BatchOpGet, Key: "non-existing"},
+				},
+			},
+			wantErr: domain.ErrBatchAborted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Batch(context.Background(), tt.req)
+			if err != tt.wantErr {
+				t.Errorf("KVService.Batch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKVService_ListCursor(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	for _, key := range []string{"e", "a", "d", "b", "c"} {
+		repo.Create(context.Background(), &domain.KV{Key: key, Value: "value"})
+	}
+
+	var keys []string
+	req := &domain.ListKVRequest{Limit: 2}
+	for {
+		resp, err := service.List(context.Background(), req)
+		if err != nil {
+			t.Fatalf("KVService.List() error = %v", err)
+		}
+		for _, kv := range resp.Items {
+			keys = append(keys, kv.Key)
+		}
+		if resp.Cursor == "" {
+			break
+		}
+		req = &domain.ListKVRequest{Limit: 2, Cursor: resp.Cursor}
+	}
+
+	if got := fmt.Sprint(keys); got != "[a b c d e]" {
+		t.Errorf("KVService.List() pages = %v, want [a b c d e]", got)
+	}
+
+	if _, err := service.List(context.Background(), &domain.ListKVRequest{Limit: 2, Cursor: "!!!"}); err != domain.ErrInvalidCursor {
+		t.Errorf("KVService.List() error = %v, wantErr %v", err, domain.ErrInvalidCursor)
+	}
+}
+
+func TestKVService_ListTotal(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	for _, key := range []string{"a", "b", "c"} {
+		repo.Create(context.Background(), &domain.KV{Key: key, Value: "value"})
+	}
+	repo.SoftDelete(context.Background(), "c", 0)
+
+	resp, err := service.List(context.Background(), &domain.ListKVRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("KVService.List() error = %v", err)
+	}
+	if resp.Total == nil || *resp.Total != 2 {
+		t.Errorf("KVService.List() total = %v, want 2", resp.Total)
+	}
+
+	resp, err = service.ListIncludingDeleted(context.Background(), &domain.ListKVRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("KVService.ListIncludingDeleted() error = %v", err)
+	}
+	if resp.Total == nil || *resp.Total != 3 {
+		t.Errorf("KVService.ListIncludingDeleted() total = %v, want 3", resp.Total)
+	}
+
+	resp, err = service.List(context.Background(), &domain.ListKVRequest{Limit: 1, SkipCount: true})
+	if err != nil {
+		t.Fatalf("KVService.List() error = %v", err)
+	}
+	if resp.Total != nil {
+		t.Errorf("KVService.List() total = %v, want omitted", *resp.Total)
+	}
+}
+
+func TestKVService_Scan(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	for _, key := range []string{"tenant/user/1", "tenant/user/2", "tenant/user/3", "tenant/group/1", "other/1"} {
+		repo.Create(context.Background(), &domain.KV{Key: key, Value: "value"})
+	}
+	repo.SoftDelete(context.Background(), "tenant/user/2", 0)
+
+	tests := []struct {
+		name    string
+		req     *domain.ScanKVRequest
+		want    string
+		wantErr error
+	}{
+		{
+			name: "prefix",
+			req:  &domain.ScanKVRequest{Prefix: "tenant/user/", Limit: 1},
+			want: "[tenant/user/1 tenant/user/3]",
+		},
+		{
+			name: "prefix including deleted",
+			req:  &domain.ScanKVRequest{Prefix: "tenant/user/", Limit: 10, IncludeDeleted: true},
+			want: "[tenant/user/1 tenant/user/2 tenant/user/3]",
+		},
+		{
+			name: "range",
+			req:  &domain.ScanKVRequest{From: "tenant/group/", To: "tenant/user/3", Limit: 10},
+			want: "[tenant/group/1 tenant/user/1]",
+		},
+		{
+			name:    "empty range",
+			req:     &domain.ScanKVRequest{From: "b", To: "a"},
+			wantErr: domain.ErrValidationError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			req := tt.req
+			for {
+				resp, err := service.Scan(context.Background(), req)
+				if err != tt.wantErr {
+					t.Fatalf("KVService.Scan() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if err != nil {
+					return
+				}
+				for _, kv := range resp.Items {
+					keys = append(keys, kv.Key)
+				}
+				if resp.Cursor == "" {
+					break
+				}
+				next := *req
+				next.Cursor = resp.Cursor
+				req = &next
+			}
+
+			if got := fmt.Sprint(keys); got != tt.want {
+				t.Errorf("KVService.Scan() keys = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// RecordingPublisher запоминает опубликованные события
+type RecordingPublisher struct {
+	events []domain.ChangeEvent
+}
+
+func (p *RecordingPublisher) Publish(event domain.ChangeEvent) {
+	p.events = append(p.events, event)
+}
+
+func TestKVService_PublishesChanges(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	publisher := &RecordingPublisher{}
+	service := NewKVService(repo, logger, publisher)
+
+	if _, err := service.Create(context.Background(), &domain.CreateKVRequest{Key: "key", Value: "v1"}); err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+	if _, err := service.Update(context.Background(), "key", &domain.UpdateKVRequest{Value: "v2"}); err != nil {
+		t.Fatalf("KVService.Update() error = %v", err)
+	}
+	if _, err := service.SoftDelete(context.Background(), "key", 0); err != nil {
+		t.Fatalf("KVService.SoftDelete() error = %v", err)
+	}
+	if _, err := service.Update(context.Background(), "missing", &domain.UpdateKVRequest{Value: "v"}); err == nil {
+		t.Fatalf("KVService.Update() expected error for missing key")
+	}
+
+	var got []string
+	for _, event := range publisher.events {
+		got = append(got, fmt.Sprintf("%s:%s:%v->%v", event.Op, event.Key, event.OldValue, event.NewValue))
+	}
+	want := "[create:key:<nil>->v1 update:key:v1->v2 soft_delete:key:v2-><nil>]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("published events = %v, want %v", got, want)
+	}
+}
+
+func TestKVService_Changes(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	for lsn := uint64(1); lsn <= 5; lsn++ {
+		repo.changelog = append(repo.changelog, domain.ChangeLogEntry{
+			LSN: lsn,
+			Key: fmt.Sprintf("key%d", lsn),
+			Op:  domain.ChangeOpCreate,
+		})
+	}
+
+	tests := []struct {
+		name          string
+		req           *domain.ChangesRequest
+		wantCount     int
+		wantNextSince uint64
+	}{
+		{
+			name:          "from the beginning",
+			req:           &domain.ChangesRequest{Since: 0, Limit: 2},
+			wantCount:     2,
+			wantNextSince: 2,
+		},
+		{
+			name:          "tail",
+			req:           &domain.ChangesRequest{Since: 3},
+			wantCount:     2,
+			wantNextSince: 5,
+		},
+		{
+			name:          "caught up",
+			req:           &domain.ChangesRequest{Since: 5, Limit: 10},
+			wantCount:     0,
+			wantNextSince: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.Changes(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("KVService.Changes() error = %v", err)
+			}
+			if len(resp.Items) != tt.wantCount {
+				t.Errorf("KVService.Changes() items = %d, want %d", len(resp.Items), tt.wantCount)
+			}
+			if resp.NextSince != tt.wantNextSince {
+				t.Errorf("KVService.Changes() next_since = %d, want %d", resp.NextSince, tt.wantNextSince)
+			}
+		})
+	}
+
+	// Курсор продолжает чтение так же, как next_since
+	first, err := service.Changes(context.Background(), &domain.ChangesRequest{Limit: 3})
+	if err != nil {
+		t.Fatalf("KVService.Changes() error = %v", err)
+	}
+	rest, err := service.Changes(context.Background(), &domain.ChangesRequest{Cursor: first.NextCursor, Limit: 10})
+	if err != nil {
+		t.Fatalf("KVService.Changes(cursor) error = %v", err)
+	}
+	if len(rest.Items) != 2 || rest.Items[0].LSN != 4 || rest.NextSince != 5 {
+		t.Errorf("KVService.Changes(cursor) = %+v, want LSN 4 and 5", rest)
+	}
+
+	for _, cursor := range []string{"!", encodeCursor("1.x"), encodeChangesCursor(domain.ChangesCursor{1, 2})} {
+		if _, err := service.Changes(context.Background(), &domain.ChangesRequest{Cursor: cursor}); err != domain.ErrInvalidCursor {
+			t.Errorf("KVService.Changes(cursor %q) error = %v, want %v", cursor, err, domain.ErrInvalidCursor)
+		}
+	}
+}
+
+func TestKVService_History(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.history["config"] = []*domain.KV{
+		{Key: "config", Value: "v1", Version: 1, UpdatedAt: base},
+		{Key: "config", Value: "v2", Version: 2, UpdatedAt: base.Add(time.Hour)},
+	}
+	repo.store["config"] = &domain.KV{Key: "config", Value: "v3", Version: 3, UpdatedAt: base.Add(2 * time.Hour)}
+
+	versionTests := []struct {
+		version uint64
+		want    string
+		wantErr error
+	}{
+		{version: 1, want: "v1"},
+		{version: 3, want: "v3"},
+		{version: 7, wantErr: domain.ErrRevisionNotFound},
+	}
+	for _, tt := range versionTests {
+		kv, err := service.GetVersion(context.Background(), "config", tt.version)
+		if err != tt.wantErr {
+			t.Fatalf("KVService.GetVersion(%d) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+		if err == nil && kv.Value != tt.want {
+			t.Errorf("KVService.GetVersion(%d) = %v, want %v", tt.version, kv.Value, tt.want)
+		}
+	}
+
+	atTests := []struct {
+		at      time.Time
+		want    string
+		wantErr error
+	}{
+		{at: base.Add(-time.Minute), wantErr: domain.ErrRevisionNotFound},
+		{at: base.Add(30 * time.Minute), want: "v1"},
+		{at: base.Add(time.Hour), want: "v2"},
+		{at: base.Add(3 * time.Hour), want: "v3"},
+	}
+	for _, tt := range atTests {
+		kv, err := service.GetAt(context.Background(), "config", tt.at)
+		if err != tt.wantErr {
+			t.Fatalf("KVService.GetAt(%v) error = %v, wantErr %v", tt.at, err, tt.wantErr)
+		}
+		if err == nil && kv.Value != tt.want {
+			t.Errorf("KVService.GetAt(%v) = %v, want %v", tt.at, kv.Value, tt.want)
+		}
+	}
+
+	reverted, err := service.Revert(context.Background(), "config", 1, 3)
+	if err != nil {
+		t.Fatalf("KVService.Revert() error = %v", err)
+	}
+	if reverted.Value != "v1" || reverted.Version != 4 {
+		t.Errorf("KVService.Revert() = %v (version %d), want v1 (version 4)", reverted.Value, reverted.Version)
+	}
+
+	history, err := service.History(context.Background(), "config")
+	if err != nil {
+		t.Fatalf("KVService.History() error = %v", err)
+	}
+	var versions []uint64
+	for _, kv := range history.Items {
+		versions = append(versions, kv.Version)
+	}
+	if got := fmt.Sprint(versions); got != "[4 3 2 1]" {
+		t.Errorf("KVService.History() versions = %v, want [4 3 2 1]", got)
+	}
+
+	if _, err := service.Revert(context.Background(), "config", 1, 3); err != domain.ErrVersionConflict {
+		t.Errorf("KVService.Revert() with stale version error = %v, want %v", err, domain.ErrVersionConflict)
+	}
+}
+
+func TestKVService_CreateNormalizesValues(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	kv, err := service.Create(context.Background(), &domain.CreateKVRequest{
+		Key:   "doc",
+		Value: map[string]interface{}{"count": json.Number("42"), "ratio": json.Number("0.5")},
+	})
+	if err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+	if kv.ContentType != domain.ContentTypeJSON {
+		t.Errorf("KVService.Create() content_type = %v, want %v", kv.ContentType, domain.ContentTypeJSON)
+	}
+	doc := kv.Value.(map[string]interface{})
+	if doc["count"] != int64(42) || doc["ratio"] != 0.5 {
+		t.Errorf("KVService.Create() value = %#v, want count int64(42) and ratio 0.5", doc)
+	}
+
+	blob, err := service.Create(context.Background(), &domain.CreateKVRequest{
+		Key:         "blob",
+		Value:       []byte{0, 1, 2},
+		ContentType: domain.ContentTypeBinary,
+	})
+	if err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+	if data, ok := blob.Value.([]byte); !ok || len(data) != 3 {
+		t.Errorf("KVService.Create() binary value = %#v, want 3 bytes", blob.Value)
+	}
+}
+
+func TestKVService_Increment(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	repo.Create(context.Background(), &domain.KV{Key: "name", Value: "text"})
+
+	five := int64(5)
+	minusTwo := int64(-2)
+
+	tests := []struct {
+		name    string
+		key     string
+		req     *domain.IncrementRequest
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "creates with initial value",
+			key:  "counter",
+			req:  &domain.IncrementRequest{Delta: &five, Initial: 10},
+			want: 15,
+		},
+		{
+			name: "default delta",
+			key:  "counter",
+			req:  &domain.IncrementRequest{},
+			want: 16,
+		},
+		{
+			name: "decrement",
+			key:  "counter",
+			req:  &domain.IncrementRequest{Delta: &minusTwo},
+			want: 14,
+		},
+		{
+			name:    "not a number",
+			key:     "name",
+			req:     &domain.IncrementRequest{},
+			wantErr: domain.ErrNotNumeric,
+		},
+		{
+			name:    "negative ttl",
+			key:     "other",
+			req:     &domain.IncrementRequest{TTL: -1},
+			wantErr: domain.ErrInvalidTTL,
+		},
+		{
+			name:    "empty key",
+			key:     "",
+			req:     &domain.IncrementRequest{},
+			wantErr: domain.ErrInvalidKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv, err := service.Increment(context.Background(), tt.key, tt.req)
+			if err != tt.wantErr {
+				t.Fatalf("KVService.Increment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && kv.Value != tt.want {
+				t.Errorf("KVService.Increment() value = %v, want %v", kv.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestKVService_CompareAndSwap(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	if _, err := service.Create(context.Background(), &domain.CreateKVRequest{Key: "leader", Value: "node-1"}); err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		req     *domain.CASRequest
+		wantErr error
+	}{
+		{
+			name:    "no condition",
+			key:     "leader",
+			req:     &domain.CASRequest{NewValue: "node-2"},
+			wantErr: domain.ErrValidationError,
+		},
+		{
+			name:    "value mismatch",
+			key:     "leader",
+			req:     &domain.CASRequest{ExpectedValue: "node-3", NewValue: "node-2"},
+			wantErr: domain.ErrValueMismatch,
+		},
+		{
+			name:    "version mismatch",
+			key:     "leader",
+			req:     &domain.CASRequest{ExpectedVersion: 5, NewValue: "node-2"},
+			wantErr: domain.ErrVersionConflict,
+		},
+		{
+			name: "swap by value",
+			key:  "leader",
+			req:  &domain.CASRequest{ExpectedValue: "node-1", NewValue: "node-2"},
+		},
+		{
+			name: "swap by version",
+			key:  "leader",
+			req:  &domain.CASRequest{ExpectedVersion: 1, NewValue: "node-3"},
+		},
+		{
+			name:    "missing key",
+			key:     "follower",
+			req:     &domain.CASRequest{ExpectedValue: "node-1", NewValue: "node-2"},
+			wantErr: domain.ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CompareAndSwap(context.Background(), tt.key, tt.req)
+			if err != tt.wantErr {
+				t.Errorf("KVService.CompareAndSwap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if kv, _ := service.Get(context.Background(), "leader"); kv.Value != "node-3" {
+		t.Errorf("value after swaps = %v, want node-3", kv.Value)
+	}
+}
+
+func TestKVService_PutIfAbsent(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	kv, err := service.PutIfAbsent(context.Background(), "idempotency:1", &domain.UpdateKVRequest{Value: "first"})
+	if err != nil {
+		t.Fatalf("KVService.PutIfAbsent() error = %v", err)
+	}
+	if kv.Value != "first" {
+		t.Errorf("KVService.PutIfAbsent() value = %v, want first", kv.Value)
+	}
+
+	existing, err := service.PutIfAbsent(context.Background(), "idempotency:1", &domain.UpdateKVRequest{Value: "second"})
+	if err != domain.ErrKeyAlreadyExists {
+		t.Fatalf("KVService.PutIfAbsent() error = %v, want %v", err, domain.ErrKeyAlreadyExists)
+	}
+	if existing == nil || existing.Value != "first" {
+		t.Errorf("KVService.PutIfAbsent() existing = %v, want first", existing)
+	}
+}
+
+func TestKVService_NamespaceIsolation(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	publisher := &RecordingPublisher{}
+	service := NewKVService(repo, logger, publisher)
+
+	billing := service.Namespace("billing")
+	search := service.Namespace("search")
+
+	if _, err := billing.Create(context.Background(), &domain.CreateKVRequest{Key: "config", Value: "billing"}); err != nil {
+		t.Fatalf("billing Create() error = %v", err)
+	}
+	if _, err := search.Create(context.Background(), &domain.CreateKVRequest{Key: "config", Value: "search"}); err != nil {
+		t.Fatalf("search Create() error = %v, same key must not collide across namespaces", err)
+	}
+
+	if _, err := service.Get(context.Background(), "config"); err != domain.ErrKeyNotFound {
+		t.Errorf("default Get() error = %v, want %v", err, domain.ErrKeyNotFound)
+	}
+	kv, err := billing.Get(context.Background(), "config")
+	if err != nil || kv.Value != "billing" {
+		t.Errorf("billing Get() = %v, %v; want billing", kv, err)
+	}
+
+	page, err := search.List(context.Background(), &domain.ListKVRequest{})
+	if err != nil {
+		t.Fatalf("search List() error = %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Value != "search" {
+		t.Errorf("search List() = %v, want only its own key", page.Items)
+	}
+
+	namespaces := make([]string, 0, len(publisher.events))
+	for _, event := range publisher.events {
+		namespaces = append(namespaces, event.Namespace)
+	}
+	if want := []string{"billing", "search"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("event namespaces = %v, want %v", namespaces, want)
+	}
+}
+
+func TestResolveExpiry(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		ttl     int64
+		at      *time.Time
+		want    *time.Time
+		wantErr error
+	}{
+		{name: "no expiry"},
+		{name: "ttl", ttl: 60, want: &future},
+		{name: "expires_at", at: &future, want: &future},
+		{name: "negative ttl", ttl: -1, wantErr: domain.ErrInvalidTTL},
+		{name: "ttl with expires_at", ttl: 60, at: &future, wantErr: domain.ErrInvalidTTL},
+		{name: "expires_at in the past", at: &past, wantErr: domain.ErrInvalidTTL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveExpiry(tt.ttl, tt.at)
+			if err != tt.wantErr {
+				t.Fatalf("resolveExpiry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("resolveExpiry() = %v, want %v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if tt.ttl > 0 {
+				if d := time.Until(*got); d <= 59*time.Second || d > 60*time.Second {
+					t.Errorf("resolveExpiry() expires in %v, want 60s", d)
+				}
+			} else if !got.Equal(*tt.want) {
+				t.Errorf("resolveExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKVService_Expired(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	past := time.Now().Add(-time.Second)
+	repo.Create(context.Background(), &domain.KV{Key: "expired", Value: "a", ExpiresAt: &past})
+	if _, err := service.Create(context.Background(), &domain.CreateKVRequest{Key: "live", Value: "b", TTL: 60}); err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+
+	if _, err := service.Get(context.Background(), "expired"); err != domain.ErrKeyNotFound {
+		t.Errorf("KVService.Get(expired) error = %v, want %v", err, domain.ErrKeyNotFound)
+	}
+	if kv, err := service.Get(context.Background(), "live"); err != nil || kv.ExpiresAt == nil {
+		t.Errorf("KVService.Get(live) = %+v, %v, want record with expires_at", kv, err)
+	}
+
+	resp, err := service.List(context.Background(), &domain.ListKVRequest{Limit: 10})
+	if err != nil {
+		t.Fatalf("KVService.List() error = %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Total == nil || *resp.Total != 1 {
+		t.Errorf("KVService.List() = %d items, total %v, want only the live record", len(resp.Items), resp.Total)
+	}
+}
+
+func TestKVService_Persist(t *testing.T) {
+	repo := NewMockRepository()
+	logger := &MockLogger{}
+	service := NewKVService(repo, logger, nil)
+
+	if _, err := service.Create(context.Background(), &domain.CreateKVRequest{Key: "session", Value: "a", TTL: 60}); err != nil {
+		t.Fatalf("KVService.Create() error = %v", err)
+	}
+
+	_, err := service.Update(context.Background(), "session", &domain.UpdateKVRequest{Value: "b", TTL: 60, Persist: true})
+	if err != domain.ErrInvalidTTL {
+		t.Errorf("KVService.Update(persist with ttl) error = %v, want %v", err, domain.ErrInvalidTTL)
+	}
+
+	kv, err := service.Update(context.Background(), "session", &domain.UpdateKVRequest{Value: "b", Persist: true})
+	if err != nil {
+		t.Fatalf("KVService.Update(persist) error = %v", err)
+	}
+	if kv.ExpiresAt == nil || !kv.ExpiresAt.Equal(domain.NoExpiry) {
+		t.Errorf("KVService.Update(persist) expires_at = %v, want %v", kv.ExpiresAt, domain.NoExpiry)
+	}
+	if kv.IsExpired(time.Now()) {
+		t.Error("record without TTL is reported as expired")
+	}
+}
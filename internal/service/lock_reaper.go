@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kv-storage/internal/interfaces"
+)
+
+const (
+	defaultLockReapInterval = 5 * time.Second
+	defaultLockReapBatch    = 500
+)
+
+// LockReaper периодически удаляет истёкшие аренды. Истёкшая аренда и без него
+// считается свободной, reaper только не даёт space kv_locks расти.
+type LockReaper struct {
+	repo      interfaces.LockRepository
+	logger    interfaces.Logger
+	interval  time.Duration
+	batchSize int
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func NewLockReaper(repo interfaces.LockRepository, logger interfaces.Logger, interval time.Duration, batchSize int) *LockReaper {
+	if interval <= 0 {
+		interval = defaultLockReapInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultLockReapBatch
+	}
+
+	return &LockReaper{
+		repo:      repo,
+		logger:    logger,
+		interval:  interval,
+		batchSize: batchSize,
+		stop:      make(chan struct{}),
+	}
+}
+
+func (r *LockReaper) Start() {
+	r.wg.Add(1)
+	go r.run()
+
+	r.logger.Info("Lock reaper started", "interval", r.interval, "batch_size", r.batchSize)
+}
+
+func (r *LockReaper) Stop() {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	r.wg.Wait()
+
+	r.logger.Info("Lock reaper stopped")
+}
+
+func (r *LockReaper) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reap()
+		}
+	}
+}
+
+func (r *LockReaper) reap() {
+	total := 0
+	for {
+		purged, err := r.repo.PurgeExpired(context.Background(), r.batchSize)
+		if err != nil {
+			r.logger.Error("Failed to purge expired locks", "error", err)
+			return
+		}
+		total += purged
+
+		if purged < r.batchSize {
+			break
+		}
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+	}
+
+	if total > 0 {
+		r.logger.Debug("Expired locks purged", "count", total)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+const (
+	defaultLockTTL    = 30 * time.Second
+	defaultMaxLockTTL = time.Hour
+)
+
+// LockService выдаёт аренды поверх хранилища: захват, продление и освобождение
+// с проверкой владельца и fencing token
+type LockService struct {
+	repo       interfaces.LockRepository
+	logger     interfaces.Logger
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func NewLockService(repo interfaces.LockRepository, logger interfaces.Logger, defaultTTL, maxTTL time.Duration) *LockService {
+	if defaultTTL <= 0 {
+		defaultTTL = defaultLockTTL
+	}
+	if maxTTL <= 0 {
+		maxTTL = defaultMaxLockTTL
+	}
+	if defaultTTL > maxTTL {
+		defaultTTL = maxTTL
+	}
+
+	return &LockService{
+		repo:       repo,
+		logger:     logger,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}
+}
+
+// Acquire при занятой аренде возвращает её текущее состояние вместе с domain.ErrLockHeld
+func (s *LockService) Acquire(ctx context.Context, name string, req *domain.AcquireLockRequest) (*domain.Lock, error) {
+	if name == "" {
+		return nil, domain.ErrInvalidKey
+	}
+	if req.Owner == "" {
+		return nil, domain.ErrValidationError
+	}
+
+	ttl, err := s.leaseTTL(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Acquire(ctx, name, req.Owner, ttl)
+}
+
+func (s *LockService) Renew(ctx context.Context, name string, req *domain.RenewLockRequest) (*domain.Lock, error) {
+	if name == "" {
+		return nil, domain.ErrInvalidKey
+	}
+	if req.Owner == "" || req.Token == 0 {
+		return nil, domain.ErrValidationError
+	}
+
+	ttl, err := s.leaseTTL(req.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.Renew(ctx, name, req.Owner, req.Token, ttl)
+}
+
+func (s *LockService) Release(ctx context.Context, name string, req *domain.ReleaseLockRequest) (*domain.Lock, error) {
+	if name == "" {
+		return nil, domain.ErrInvalidKey
+	}
+	if req.Owner == "" || req.Token == 0 {
+		return nil, domain.ErrValidationError
+	}
+
+	return s.repo.Release(ctx, name, req.Owner, req.Token)
+}
+
+func (s *LockService) Get(ctx context.Context, name string) (*domain.Lock, error) {
+	if name == "" {
+		return nil, domain.ErrInvalidKey
+	}
+
+	return s.repo.Get(ctx, name)
+}
+
+// leaseTTL переводит TTL из секунд; 0 — TTL по умолчанию, больше maxTTL — ошибка
+func (s *LockService) leaseTTL(seconds int64) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, domain.ErrInvalidTTL
+	}
+	if seconds == 0 {
+		return s.defaultTTL, nil
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	if ttl > s.maxTTL {
+		return 0, domain.ErrInvalidTTL
+	}
+	return ttl, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+// MockLockRepository — аренды в памяти с той же логикой владельца и токена, что в init.lua
+type MockLockRepository struct {
+	locks     map[string]*domain.Lock
+	lastToken uint64
+	now       time.Time
+}
+
+func NewMockLockRepository() *MockLockRepository {
+	return &MockLockRepository{
+		locks: make(map[string]*domain.Lock),
+		now:   time.Now(),
+	}
+}
+
+func (m *MockLockRepository) live(name string) *domain.Lock {
+	if lock, ok := m.locks[name]; ok && lock.ExpiresAt.After(m.now) {
+		return lock
+	}
+	return nil
+}
+
+func (m *MockLockRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (*domain.Lock, error) {
+	if lock := m.live(name); lock != nil {
+		if lock.Owner != owner {
+			return lock, domain.ErrLockHeld
+		}
+		lock.ExpiresAt = m.now.Add(ttl)
+		return lock, nil
+	}
+	m.lastToken++
+	lock := &domain.Lock{Name: name, Owner: owner, Token: m.lastToken, AcquiredAt: m.now, ExpiresAt: m.now.Add(ttl)}
+	m.locks[name] = lock
+	return lock, nil
+}
+
+func (m *MockLockRepository) Renew(ctx context.Context, name, owner string, token uint64, ttl time.Duration) (*domain.Lock, error) {
+	lock := m.live(name)
+	if lock == nil {
+		return nil, domain.ErrLockNotFound
+	}
+	if lock.Owner != owner || lock.Token != token {
+		return nil, domain.ErrNotLockOwner
+	}
+	lock.ExpiresAt = m.now.Add(ttl)
+	return lock, nil
+}
+
+func (m *MockLockRepository) Release(ctx context.Context, name, owner string, token uint64) (*domain.Lock, error) {
+	lock := m.live(name)
+	if lock == nil {
+		return nil, domain.ErrLockNotFound
+	}
+	if lock.Owner != owner || lock.Token != token {
+		return nil, domain.ErrNotLockOwner
+	}
+	delete(m.locks, name)
+	return lock, nil
+}
+
+func (m *MockLockRepository) Get(ctx context.Context, name string) (*domain.Lock, error) {
+	if lock := m.live(name); lock != nil {
+		return lock, nil
+	}
+	return nil, domain.ErrLockNotFound
+}
+
+func (m *MockLockRepository) PurgeExpired(ctx context.Context, limit int) (int, error) {
+	purged := 0
+	for name, lock := range m.locks {
+		if purged >= limit {
+			break
+		}
+		if !lock.ExpiresAt.After(m.now) {
+			delete(m.locks, name)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func TestLockService_Lifecycle(t *testing.T) {
+	repo := NewMockLockRepository()
+	service := NewLockService(repo, &MockLogger{}, 10*time.Second, time.Minute)
+
+	lock, err := service.Acquire(context.Background(), "cron:cleanup", &domain.AcquireLockRequest{Owner: "worker-1"})
+	if err != nil {
+		t.Fatalf("LockService.Acquire() error = %v", err)
+	}
+	if want := repo.now.Add(10 * time.Second); !lock.ExpiresAt.Equal(want) {
+		t.Errorf("LockService.Acquire() expires_at = %v, want default TTL %v", lock.ExpiresAt, want)
+	}
+
+	holder, err := service.Acquire(context.Background(), "cron:cleanup", &domain.AcquireLockRequest{Owner: "worker-2"})
+	if err != domain.ErrLockHeld {
+		t.Fatalf("LockService.Acquire() error = %v, want %v", err, domain.ErrLockHeld)
+	}
+	if holder.Owner != "worker-1" {
+		t.Errorf("LockService.Acquire() holder = %s, want worker-1", holder.Owner)
+	}
+
+	if _, err := service.Renew(context.Background(), "cron:cleanup", &domain.RenewLockRequest{Owner: "worker-2", Token: lock.Token}); err != domain.ErrNotLockOwner {
+		t.Errorf("LockService.Renew() by other owner error = %v, want %v", err, domain.ErrNotLockOwner)
+	}
+	if _, err := service.Renew(context.Background(), "cron:cleanup", &domain.RenewLockRequest{Owner: "worker-1", Token: lock.Token, TTL: 30}); err != nil {
+		t.Errorf("LockService.Renew() error = %v", err)
+	}
+
+	// Аренда истекла: следующий захват получает больший fencing token,
+	// а старый владелец больше не может её продлить
+	repo.now = repo.now.Add(time.Minute)
+
+	next, err := service.Acquire(context.Background(), "cron:cleanup", &domain.AcquireLockRequest{Owner: "worker-2"})
+	if err != nil {
+		t.Fatalf("LockService.Acquire() after expiry error = %v", err)
+	}
+	if next.Token <= lock.Token {
+		t.Errorf("fencing token = %d, want greater than %d", next.Token, lock.Token)
+	}
+	if _, err := service.Release(context.Background(), "cron:cleanup", &domain.ReleaseLockRequest{Owner: "worker-1", Token: lock.Token}); err != domain.ErrNotLockOwner {
+		t.Errorf("LockService.Release() with stale token error = %v, want %v", err, domain.ErrNotLockOwner)
+	}
+	if _, err := service.Release(context.Background(), "cron:cleanup", &domain.ReleaseLockRequest{Owner: "worker-2", Token: next.Token}); err != nil {
+		t.Errorf("LockService.Release() error = %v", err)
+	}
+	if _, err := service.Get(context.Background(), "cron:cleanup"); err != domain.ErrLockNotFound {
+		t.Errorf("LockService.Get() after release error = %v, want %v", err, domain.ErrLockNotFound)
+	}
+}
+
+func TestLockService_Validation(t *testing.T) {
+	service := NewLockService(NewMockLockRepository(), &MockLogger{}, 0, time.Minute)
+
+	tests := []struct {
+		name    string
+		lock    string
+		req     *domain.AcquireLockRequest
+		wantErr error
+	}{
+		{name: "empty name", lock: "", req: &domain.AcquireLockRequest{Owner: "w"}, wantErr: domain.ErrInvalidKey},
+		{name: "empty owner", lock: "job", req: &domain.AcquireLockRequest{}, wantErr: domain.ErrValidationError},
+		{name: "negative ttl", lock: "job", req: &domain.AcquireLockRequest{Owner: "w", TTL: -1}, wantErr: domain.ErrInvalidTTL},
+		{name: "ttl above max", lock: "job", req: &domain.AcquireLockRequest{Owner: "w", TTL: 3600}, wantErr: domain.ErrInvalidTTL},
+		{name: "valid", lock: "job", req: &domain.AcquireLockRequest{Owner: "w", TTL: 60}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Acquire(context.Background(), tt.lock, tt.req); err != tt.wantErr {
+				t.Errorf("LockService.Acquire() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
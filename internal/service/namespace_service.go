@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+)
+
+// NamespaceService управляет namespace: создание, статистика и удаление
+// вместе со всеми записями
+type NamespaceService struct {
+	repo   interfaces.NamespaceRepository
+	logger interfaces.Logger
+}
+
+func NewNamespaceService(repo interfaces.NamespaceRepository, logger interfaces.Logger) *NamespaceService {
+	return &NamespaceService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *NamespaceService) Create(ctx context.Context, req *domain.CreateNamespaceRequest) (*domain.Namespace, error) {
+	if !domain.ValidNamespace(req.Name) {
+		return nil, domain.ErrInvalidNamespace
+	}
+
+	return s.repo.Create(ctx, req.Name)
+}
+
+func (s *NamespaceService) Get(ctx context.Context, name string) (*domain.Namespace, error) {
+	if !domain.ValidNamespace(name) {
+		return nil, domain.ErrNamespaceNotFound
+	}
+
+	return s.repo.Get(ctx, name)
+}
+
+func (s *NamespaceService) List(ctx context.Context) (*domain.ListNamespacesResponse, error) {
+	namespaces, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListNamespacesResponse{Items: namespaces}, nil
+}
+
+func (s *NamespaceService) Drop(ctx context.Context, name string) (*domain.Namespace, error) {
+	if name == domain.DefaultNamespace {
+		return nil, domain.ErrNamespaceProtected
+	}
+	if !domain.ValidNamespace(name) {
+		return nil, domain.ErrNamespaceNotFound
+	}
+
+	return s.repo.Drop(ctx, name)
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+
+	"kv-storage/internal/domain"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// consistencyUnary и consistencyStream переносят метаданные x-consistency в контекст
+// вызова так же, как HTTP-параметр ?consistency
+func consistencyUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := withConsistency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func consistencyStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := withConsistency(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+func withConsistency(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	value := firstValue(md, "x-consistency")
+	if value == "" {
+		return ctx, nil
+	}
+
+	consistency := domain.Consistency(value)
+	if !domain.ValidConsistency(consistency) {
+		return nil, status.Error(codes.InvalidArgument, "invalid consistency")
+	}
+	return domain.WithConsistency(ctx, consistency), nil
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/transport/grpc/kvpb"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// decodeValue переводит байты значения из запроса в вид, который принимает KVService:
+// JSON-документ разбирается (числа как json.Number), бинарное значение передаётся как есть
+func decodeValue(value []byte, contentType string) (interface{}, error) {
+	switch contentType {
+	case "", domain.ContentTypeJSON:
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+
+		var decoded interface{}
+		if err := decoder.Decode(&decoded); err != nil {
+			return nil, domain.ErrInvalidValue
+		}
+		return decoded, nil
+	case domain.ContentTypeBinary:
+		return value, nil
+	default:
+		return nil, domain.ErrUnsupportedContentType
+	}
+}
+
+func toRecord(kv *domain.KV) (*kvpb.Record, error) {
+	value, ok := kv.Value.([]byte)
+	if !ok {
+		encoded, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		value = encoded
+	}
+
+	return &kvpb.Record{
+		Key:         kv.Key,
+		Value:       value,
+		ContentType: kv.ContentType,
+		CreatedAt:   timestamp(&kv.CreatedAt),
+		UpdatedAt:   timestamp(&kv.UpdatedAt),
+		DeletedAt:   timestamp(kv.DeletedAt),
+		IsDeleted:   kv.IsDeleted,
+		ExpiresAt:   timestamp(kv.ExpiresAt),
+		Version:     kv.Version,
+	}, nil
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
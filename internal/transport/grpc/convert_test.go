@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"kv-storage/internal/domain"
+)
+
+func TestDecodeValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		contentType string
+		want        interface{}
+		wantErr     error
+	}{
+		{"object", `{"name": "John", "age": 42}`, "", map[string]interface{}{"name": "John", "age": json.Number("42")}, nil},
+		{"large integer", `9007199254740993`, domain.ContentTypeJSON, json.Number("9007199254740993"), nil},
+		{"string", `"a"`, domain.ContentTypeJSON, "a", nil},
+		{"empty array", `[]`, domain.ContentTypeJSON, []interface{}{}, nil},
+		{"binary", "\x00\xff", domain.ContentTypeBinary, []byte{0, 0xff}, nil},
+		{"invalid json", `{"name":`, domain.ContentTypeJSON, nil, domain.ErrInvalidValue},
+		{"empty json", ``, "", nil, domain.ErrInvalidValue},
+		{"unknown content type", `a`, "text/plain", nil, domain.ErrUnsupportedContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeValue([]byte(tt.value), tt.contentType)
+			if err != tt.wantErr {
+				t.Fatalf("decodeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToRecord(t *testing.T) {
+	created := time.Unix(100, 0)
+	deleted := time.Unix(300, 0)
+
+	record, err := toRecord(&domain.KV{
+		Key:         "user:1",
+		Value:       map[string]interface{}{"id": int64(9007199254740993)},
+		ContentType: domain.ContentTypeJSON,
+		CreatedAt:   created,
+		UpdatedAt:   time.Unix(200, 0),
+		DeletedAt:   &deleted,
+		IsDeleted:   true,
+		Version:     3,
+	})
+	if err != nil {
+		t.Fatalf("toRecord() error = %v", err)
+	}
+	if string(record.GetValue()) != `{"id":9007199254740993}` {
+		t.Errorf("toRecord() value = %s", record.GetValue())
+	}
+	if record.GetKey() != "user:1" || record.GetVersion() != 3 || !record.GetIsDeleted() ||
+		record.GetContentType() != domain.ContentTypeJSON {
+		t.Errorf("toRecord() = %v", record)
+	}
+	if !record.GetCreatedAt().AsTime().Equal(created) || !record.GetDeletedAt().AsTime().Equal(deleted) {
+		t.Errorf("toRecord() timestamps = %v, %v", record.GetCreatedAt(), record.GetDeletedAt())
+	}
+	// Отсутствующий срок жизни не передаётся нулевым Timestamp
+	if record.GetExpiresAt() != nil {
+		t.Errorf("toRecord() expires_at = %v, want nil", record.GetExpiresAt())
+	}
+
+	record, err = toRecord(&domain.KV{Key: "avatar", Value: []byte{1, 2}, ContentType: domain.ContentTypeBinary})
+	if err != nil {
+		t.Fatalf("toRecord() error = %v", err)
+	}
+	if !reflect.DeepEqual(record.GetValue(), []byte{1, 2}) || record.GetCreatedAt() != nil {
+		t.Errorf("toRecord() binary = %v", record)
+	}
+
+	if _, err := toRecord(&domain.KV{Key: "bad", Value: func() {}}); err == nil {
+		t.Error("toRecord() of unencodable value succeeded")
+	}
+}
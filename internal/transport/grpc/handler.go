@@ -0,0 +1,290 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+	"kv-storage/internal/transport/grpc/kvpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultListLimit = 10
+	maxListLimit     = 100
+)
+
+// Handler реализует kvpb.KVServer поверх KVService с той же проверкой доступа, что и HTTP API
+type Handler struct {
+	kvpb.UnimplementedKVServer
+
+	service *service.KVService
+	policy  *rbac.Policy
+	logger  interfaces.Logger
+}
+
+func NewHandler(service *service.KVService, policy *rbac.Policy, logger interfaces.Logger) *Handler {
+	return &Handler{
+		service: service,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) Get(ctx context.Context, req *kvpb.GetRequest) (*kvpb.Record, error) {
+	if err := h.authorize(ctx, rbac.ActionRead, req.GetNamespace(), req.GetKey(), false); err != nil {
+		return nil, err
+	}
+
+	kv, err := h.kv(req.GetNamespace()).Get(ctx, req.GetKey())
+	return h.record(kv, err, "Failed to get KV", req.GetKey())
+}
+
+func (h *Handler) Create(ctx context.Context, req *kvpb.CreateRequest) (*kvpb.Record, error) {
+	if err := h.authorize(ctx, rbac.ActionWrite, req.GetNamespace(), req.GetKey(), false); err != nil {
+		return nil, err
+	}
+
+	value, err := decodeValue(req.GetValue(), req.GetContentType())
+	if err != nil {
+		return nil, h.statusError(err, "Failed to create KV", req.GetKey())
+	}
+
+	kv, err := h.kv(req.GetNamespace()).Create(ctx, &domain.CreateKVRequest{
+		Key:         req.GetKey(),
+		Value:       value,
+		ContentType: req.GetContentType(),
+		TTL:         req.GetTtl(),
+	})
+	return h.record(kv, err, "Failed to create KV", req.GetKey())
+}
+
+func (h *Handler) Update(ctx context.Context, req *kvpb.UpdateRequest) (*kvpb.Record, error) {
+	if err := h.authorize(ctx, rbac.ActionWrite, req.GetNamespace(), req.GetKey(), false); err != nil {
+		return nil, err
+	}
+
+	value, err := decodeValue(req.GetValue(), req.GetContentType())
+	if err != nil {
+		return nil, h.statusError(err, "Failed to update KV", req.GetKey())
+	}
+
+	kv, err := h.kv(req.GetNamespace()).Update(ctx, req.GetKey(), &domain.UpdateKVRequest{
+		Value:           value,
+		ContentType:     req.GetContentType(),
+		TTL:             req.GetTtl(),
+		ExpectedVersion: req.GetExpectedVersion(),
+	})
+	return h.record(kv, err, "Failed to update KV", req.GetKey())
+}
+
+func (h *Handler) Delete(ctx context.Context, req *kvpb.DeleteRequest) (*kvpb.Record, error) {
+	action := rbac.ActionHardDelete
+	if req.GetSoft() {
+		action = rbac.ActionDelete
+	}
+	if err := h.authorize(ctx, action, req.GetNamespace(), req.GetKey(), false); err != nil {
+		return nil, err
+	}
+
+	var kv *domain.KV
+	var err error
+
+	if req.GetSoft() {
+		kv, err = h.kv(req.GetNamespace()).SoftDelete(ctx, req.GetKey(), req.GetExpectedVersion())
+	} else {
+		kv, err = h.kv(req.GetNamespace()).Delete(ctx, req.GetKey(), req.GetExpectedVersion())
+	}
+	return h.record(kv, err, "Failed to delete KV", req.GetKey())
+}
+
+func (h *Handler) Restore(ctx context.Context, req *kvpb.RestoreRequest) (*kvpb.Record, error) {
+	if err := h.authorize(ctx, rbac.ActionWrite, req.GetNamespace(), req.GetKey(), false); err != nil {
+		return nil, err
+	}
+
+	kv, err := h.kv(req.GetNamespace()).Restore(ctx, req.GetKey())
+	return h.record(kv, err, "Failed to restore KV", req.GetKey())
+}
+
+func (h *Handler) List(ctx context.Context, req *kvpb.ListRequest) (*kvpb.ListResponse, error) {
+	if err := h.authorizeList(ctx, req); err != nil {
+		return nil, err
+	}
+
+	page, err := h.list(ctx, req, req.GetCursor(), req.GetSkipCount())
+	if err != nil {
+		return nil, h.statusError(err, "Failed to list KV", "")
+	}
+
+	response := &kvpb.ListResponse{
+		Items:  make([]*kvpb.Record, 0, len(page.Items)),
+		Cursor: page.Cursor,
+	}
+	if page.Total != nil {
+		total := int64(*page.Total)
+		response.Total = &total
+	}
+	for _, kv := range page.Items {
+		record, err := toRecord(kv)
+		if err != nil {
+			return nil, h.statusError(err, "Failed to encode KV", kv.Key)
+		}
+		response.Items = append(response.Items, record)
+	}
+
+	return response, nil
+}
+
+// StreamList читает выборку страницами по limit записей и отправляет записи по одной,
+// пока страницы не закончатся или клиент не отменит вызов
+func (h *Handler) StreamList(req *kvpb.ListRequest, stream kvpb.KV_StreamListServer) error {
+	ctx := stream.Context()
+	if err := h.authorizeList(ctx, req); err != nil {
+		return err
+	}
+
+	cursor := req.GetCursor()
+	for {
+		page, err := h.list(ctx, req, cursor, true)
+		if err != nil {
+			return h.statusError(err, "Failed to list KV", "")
+		}
+
+		for _, kv := range page.Items {
+			record, err := toRecord(kv)
+			if err != nil {
+				return h.statusError(err, "Failed to encode KV", kv.Key)
+			}
+			if err := stream.Send(record); err != nil {
+				return err
+			}
+		}
+
+		if page.Cursor == "" {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		cursor = page.Cursor
+	}
+}
+
+// list выбирает страницу так же, как GET /kv и /kv/all: обходом диапазона,
+// если заданы prefix, from или to, иначе постраничным списком
+func (h *Handler) list(ctx context.Context, req *kvpb.ListRequest, cursor string, skipCount bool) (*domain.ListKVResponse, error) {
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultListLimit
+	}
+	if limit < 0 || limit > maxListLimit {
+		return nil, domain.ErrValidationError
+	}
+
+	kv := h.kv(req.GetNamespace())
+
+	if req.GetPrefix() != "" || req.GetFrom() != "" || req.GetTo() != "" {
+		return kv.Scan(ctx, &domain.ScanKVRequest{
+			Prefix:         req.GetPrefix(),
+			From:           req.GetFrom(),
+			To:             req.GetTo(),
+			Limit:          limit,
+			Cursor:         cursor,
+			IncludeDeleted: req.GetIncludeDeleted(),
+		})
+	}
+
+	listReq := &domain.ListKVRequest{
+		Limit:     limit,
+		Cursor:    cursor,
+		SkipCount: skipCount,
+	}
+	if req.GetIncludeDeleted() {
+		return kv.ListIncludingDeleted(ctx, listReq)
+	}
+	return kv.List(ctx, listReq)
+}
+
+func (h *Handler) authorizeList(ctx context.Context, req *kvpb.ListRequest) error {
+	action := rbac.ActionRead
+	if req.GetIncludeDeleted() {
+		action = rbac.ActionListDeleted
+	}
+
+	prefix := rbac.RangePrefix(req.GetPrefix(), req.GetFrom(), req.GetTo())
+	return h.authorize(ctx, action, req.GetNamespace(), prefix, true)
+}
+
+// authorize проверяет запрос по политике RBAC и возвращает PermissionDenied с причиной
+func (h *Handler) authorize(ctx context.Context, action rbac.Action, namespace, key string, prefix bool) error {
+	err := h.policy.Authorize(rbac.Request{
+		Principal: principalFromContext(ctx),
+		Action:    action,
+		Namespace: namespaceOrDefault(namespace),
+		Key:       key,
+		Prefix:    prefix,
+	})
+	if err == nil {
+		return nil
+	}
+
+	var denied *rbac.DeniedError
+	if errors.As(err, &denied) {
+		h.logger.Warn("Access denied", "action", action, "reason", denied.Reason)
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	h.logger.Error("Failed to authorize request", "error", err)
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+func (h *Handler) kv(namespace string) *service.KVService {
+	return h.service.Namespace(namespaceOrDefault(namespace))
+}
+
+func (h *Handler) record(kv *domain.KV, err error, message, key string) (*kvpb.Record, error) {
+	if err != nil {
+		return nil, h.statusError(err, message, key)
+	}
+
+	record, err := toRecord(kv)
+	if err != nil {
+		return nil, h.statusError(err, "Failed to encode KV", key)
+	}
+	return record, nil
+}
+
+// statusError переводит ошибки домена в коды gRPC, как HTTP-обработчики переводят их в статусы
+func (h *Handler) statusError(err error, message, key string) error {
+	switch err {
+	case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL,
+		domain.ErrValidationError, domain.ErrInvalidCursor, domain.ErrUnsupportedContentType:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case domain.ErrKeyExists, domain.ErrKeyAlreadyExists:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case domain.ErrVersionConflict, domain.ErrNotDeleted:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case domain.ErrTimeout:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case domain.ErrCanceled:
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		h.logger.Error(message, "key", key, "error", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+}
+
+func namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return domain.DefaultNamespace
+	}
+	return namespace
+}
@@ -0,0 +1,357 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"testing"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+	"kv-storage/internal/transport/grpc/kvpb"
+	"kv-storage/internal/transport/http/middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Fatal(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Sync() error                                    { return nil }
+
+// memoryRepository хранит записи в памяти, как MockRepository тестов сервиса;
+// остальные методы репозитория в этих тестах не вызываются
+type memoryRepository struct {
+	interfaces.KVRepository
+	records map[string]*domain.KV
+	// lists считает страницы, выбранные List
+	lists int
+}
+
+func newMemoryRepository(keys ...string) *memoryRepository {
+	repo := &memoryRepository{records: make(map[string]*domain.KV)}
+	for _, key := range keys {
+		repo.records[key] = &domain.KV{Key: key, Value: "a", ContentType: domain.ContentTypeJSON, Version: 1}
+	}
+	return repo
+}
+
+func (r *memoryRepository) Create(ctx context.Context, kv *domain.KV) error {
+	if _, ok := r.records[kv.Key]; ok {
+		return domain.ErrKeyAlreadyExists
+	}
+	kv.Version = 1
+	r.records[kv.Key] = kv
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, key string) (*domain.KV, error) {
+	kv, ok := r.records[key]
+	if !ok || kv.IsDeleted {
+		return nil, domain.ErrKeyNotFound
+	}
+	return kv, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error) {
+	previous, err := r.Get(ctx, kv.Key)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && previous.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	kv.Version = previous.Version + 1
+	r.records[kv.Key] = kv
+	return previous, nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	kv, err := r.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && kv.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	delete(r.records, key)
+	return kv, nil
+}
+
+func (r *memoryRepository) List(ctx context.Context, opts domain.ListOptions) (*domain.ListPage, error) {
+	r.lists++
+
+	keys := make([]string, 0, len(r.records))
+	for key, kv := range r.records {
+		if !kv.IsDeleted {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	page := &domain.ListPage{Total: len(keys)}
+	for _, key := range keys {
+		if key <= opts.After {
+			continue
+		}
+		if len(page.Items) == opts.Limit {
+			page.NextKey = page.Items[len(page.Items)-1].Key
+			break
+		}
+		page.Items = append(page.Items, r.records[key])
+	}
+	return page, nil
+}
+
+// startServer запускает Server поверх bufconn и возвращает подключённого клиента
+func startServer(t *testing.T, repo interfaces.KVRepository, auth *middleware.Authenticator, policy *rbac.Policy) kvpb.KVClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(&config.Config{}, nopLogger{}, service.NewKVService(repo, nopLogger{}, nil), auth, policy).(*Server)
+	go server.server.Serve(listener)
+	t.Cleanup(server.server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return kvpb.NewKVClient(conn)
+}
+
+// streamKeys читает поток StreamList до конца и возвращает ключи записей
+func streamKeys(ctx context.Context, client kvpb.KVClient, req *kvpb.ListRequest) ([]string, error) {
+	stream, err := client.StreamList(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for {
+		record, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return keys, nil
+		}
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, record.GetKey())
+	}
+}
+
+func TestHandler_StatusError(t *testing.T) {
+	handler := NewHandler(nil, nil, nopLogger{})
+
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{domain.ErrInvalidKey, codes.InvalidArgument},
+		{domain.ErrInvalidValue, codes.InvalidArgument},
+		{domain.ErrInvalidTTL, codes.InvalidArgument},
+		{domain.ErrValidationError, codes.InvalidArgument},
+		{domain.ErrInvalidCursor, codes.InvalidArgument},
+		{domain.ErrUnsupportedContentType, codes.InvalidArgument},
+		{domain.ErrKeyNotFound, codes.NotFound},
+		{domain.ErrNamespaceNotFound, codes.NotFound},
+		{domain.ErrKeyExists, codes.AlreadyExists},
+		{domain.ErrKeyAlreadyExists, codes.AlreadyExists},
+		{domain.ErrVersionConflict, codes.FailedPrecondition},
+		{domain.ErrNotDeleted, codes.FailedPrecondition},
+		{domain.ErrTimeout, codes.DeadlineExceeded},
+		{domain.ErrCanceled, codes.Canceled},
+		{domain.ErrDatabaseError, codes.Internal},
+		{errors.New("connection refused"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			st := status.Convert(handler.statusError(tt.err, "Failed", "key"))
+			if st.Code() != tt.want {
+				t.Errorf("statusError(%v) code = %v, want %v", tt.err, st.Code(), tt.want)
+			}
+			// Внутренние ошибки не раскрываются клиенту
+			if tt.want == codes.Internal && st.Message() != "Internal server error" {
+				t.Errorf("statusError(%v) message = %q", tt.err, st.Message())
+			}
+		})
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	client := startServer(t, newMemoryRepository("user:1"), nil, nil)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, &kvpb.GetRequest{Key: "missing"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("Get(missing) code = %v, want %v", code, codes.NotFound)
+	}
+
+	_, err = client.Create(ctx, &kvpb.CreateRequest{Key: "user:2", Value: []byte(`{"name":`)})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("Create(invalid json) code = %v, want %v", code, codes.InvalidArgument)
+	}
+
+	_, err = client.Create(ctx, &kvpb.CreateRequest{Key: "user:1", Value: []byte(`"b"`)})
+	if code := status.Code(err); code != codes.AlreadyExists {
+		t.Errorf("Create(existing) code = %v, want %v", code, codes.AlreadyExists)
+	}
+
+	record, err := client.Update(ctx, &kvpb.UpdateRequest{Key: "user:1", Value: []byte(`"b"`), ExpectedVersion: 1})
+	if err != nil || record.GetVersion() != 2 {
+		t.Fatalf("Update() = %v, %v, want version 2", record, err)
+	}
+	_, err = client.Update(ctx, &kvpb.UpdateRequest{Key: "user:1", Value: []byte(`"c"`), ExpectedVersion: 1})
+	if code := status.Code(err); code != codes.FailedPrecondition {
+		t.Errorf("Update(stale version) code = %v, want %v", code, codes.FailedPrecondition)
+	}
+
+	_, err = client.Get(metadata.AppendToOutgoingContext(ctx, "x-consistency", "linearizable"), &kvpb.GetRequest{Key: "user:1"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("Get(invalid consistency) code = %v, want %v", code, codes.InvalidArgument)
+	}
+
+	_, err = client.List(ctx, &kvpb.ListRequest{Limit: maxListLimit + 1})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("List(limit %d) code = %v, want %v", maxListLimit+1, code, codes.InvalidArgument)
+	}
+	_, err = client.List(ctx, &kvpb.ListRequest{Cursor: "!"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("List(invalid cursor) code = %v, want %v", code, codes.InvalidArgument)
+	}
+}
+
+func TestHandler_StreamList(t *testing.T) {
+	repo := newMemoryRepository("a", "b", "c", "d", "e")
+	client := startServer(t, repo, nil, nil)
+	ctx := context.Background()
+
+	keys, err := streamKeys(ctx, client, &kvpb.ListRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("StreamList() error = %v", err)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("StreamList() keys = %v, want %v", keys, want)
+	}
+	// Страницы a-b, c-d и e: последняя неполная завершает поток
+	if repo.lists != 3 {
+		t.Errorf("StreamList() read %d pages, want 3", repo.lists)
+	}
+
+	// Поток продолжает выборку с курсора, полученного от List
+	page, err := client.List(ctx, &kvpb.ListRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if page.GetTotal() != 5 || page.GetCursor() == "" {
+		t.Fatalf("List() = %v, want total 5 and a cursor", page)
+	}
+	keys, err = streamKeys(ctx, client, &kvpb.ListRequest{Limit: 2, Cursor: page.GetCursor()})
+	if err != nil {
+		t.Fatalf("StreamList(cursor) error = %v", err)
+	}
+	if want := []string{"c", "d", "e"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("StreamList(cursor) keys = %v, want %v", keys, want)
+	}
+}
+
+func TestHandler_Authorization(t *testing.T) {
+	policy, err := rbac.NewPolicy(config.RBACConfig{
+		Enabled: true,
+		Rules: []config.RBACRule{
+			{Roles: []string{"admin"}, Actions: []string{"*"}},
+			{Principals: []string{"billing"}, Actions: []string{"read", "write"}, Keys: []string{"billing/*"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	auth, err := middleware.NewAuthenticator(config.AuthConfig{
+		Enabled: true,
+		APIKeys: []config.APIKeyConfig{
+			{Name: "root", Key: "root-key", Roles: []string{"admin"}},
+			{Name: "billing", Key: "billing-key"},
+		},
+	}, nopLogger{})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	client := startServer(t, newMemoryRepository("billing/1", "billing/2", "users/1"), auth, policy)
+	as := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"no credentials", func() error {
+			_, err := client.Get(context.Background(), &kvpb.GetRequest{Key: "billing/1"})
+			return err
+		}, codes.Unauthenticated},
+		{"unknown api key", func() error {
+			_, err := client.Get(as("wrong-key"), &kvpb.GetRequest{Key: "billing/1"})
+			return err
+		}, codes.Unauthenticated},
+		{"read own prefix", func() error {
+			_, err := client.Get(as("billing-key"), &kvpb.GetRequest{Key: "billing/1"})
+			return err
+		}, codes.OK},
+		{"read foreign key", func() error {
+			_, err := client.Get(as("billing-key"), &kvpb.GetRequest{Key: "users/1"})
+			return err
+		}, codes.PermissionDenied},
+		{"soft delete without delete action", func() error {
+			_, err := client.Delete(as("billing-key"), &kvpb.DeleteRequest{Key: "billing/1", Soft: true})
+			return err
+		}, codes.PermissionDenied},
+		{"admin hard delete", func() error {
+			_, err := client.Delete(as("root-key"), &kvpb.DeleteRequest{Key: "billing/2"})
+			return err
+		}, codes.OK},
+		{"stream without credentials", func() error {
+			_, err := streamKeys(context.Background(), client, &kvpb.ListRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"stream outside own prefix", func() error {
+			_, err := streamKeys(as("billing-key"), client, &kvpb.ListRequest{})
+			return err
+		}, codes.PermissionDenied},
+		{"admin stream", func() error {
+			_, err := streamKeys(as("root-key"), client, &kvpb.ListRequest{})
+			return err
+		}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.want {
+				t.Errorf("code = %v, want %v", code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+	"kv-storage/internal/transport/grpc/kvpb"
+	"kv-storage/internal/transport/http/middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+type principalKey struct{}
+
+// Server — gRPC-транспорт KVService, работающий рядом с HTTP-роутером
+type Server struct {
+	server *grpc.Server
+	logger interfaces.Logger
+	auth   *middleware.Authenticator
+}
+
+func NewServer(cfg *config.Config, logger interfaces.Logger, kvService *service.KVService, auth *middleware.Authenticator, policy *rbac.Policy) interfaces.Router {
+	s := &Server{
+		logger: logger,
+		auth:   auth,
+	}
+
+	// Трассировка идёт первой, чтобы отказы аутентификации тоже попадали в трассы
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(traceUnary, consistencyUnary),
+		grpc.ChainStreamInterceptor(traceStream, consistencyStream),
+	}
+	// auth равен nil, если аутентификация выключена в конфигурации
+	if auth != nil {
+		options = append(options,
+			grpc.ChainUnaryInterceptor(s.authenticateUnary),
+			grpc.ChainStreamInterceptor(s.authenticateStream),
+		)
+	}
+
+	s.server = grpc.NewServer(options...)
+	kvpb.RegisterKVServer(s.server, NewHandler(kvService, policy, logger))
+	// Reflection позволяет вызывать методы из grpcurl и аналогов без .proto
+	reflection.Register(s.server)
+
+	return s
+}
+
+func (s *Server) Run(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Starting gRPC server", "addr", addr)
+	return s.server.Serve(listener)
+}
+
+// Shutdown дожидается завершения активных вызовов; по истечении ctx
+// оставшиеся вызовы и потоки List обрываются
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down gRPC server")
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate проверяет метаданные x-api-key и authorization так же, как HTTP-заголовки
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	principal, err := s.auth.Verify(firstValue(md, "x-api-key"), firstValue(md, "authorization"))
+	if err != nil {
+		s.logger.Warn("Authentication failed", "method", method, "reason", err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+func principalFromContext(ctx context.Context) *domain.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*domain.Principal)
+	return principal
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// contextStream подменяет контекст потока, например контекстом с Principal
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/transport/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorize проверяет запрос по политике RBAC; при отказе сам отвечает 403 с причиной.
+// Nil-политика (RBAC выключен) разрешает всё.
+func authorize(c *gin.Context, policy *rbac.Policy, logger interfaces.Logger, req rbac.Request) bool {
+	req.Principal, _ = middleware.PrincipalFromContext(c)
+
+	err := policy.Authorize(req)
+	if err == nil {
+		return true
+	}
+
+	var denied *rbac.DeniedError
+	if !errors.As(err, &denied) {
+		logger.Error("Failed to authorize request", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+
+	logger.Warn("Access denied",
+		"path", c.Request.URL.Path,
+		"action", req.Action,
+		"reason", denied.Reason,
+	)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": domain.ErrForbidden.Error(), "reason": denied.Reason})
+	return false
+}
+
+// authorizeKey проверяет доступ к ключу в namespace запроса
+func (h *Handler) authorizeKey(c *gin.Context, action rbac.Action, key string) bool {
+	return authorize(c, h.policy, h.logger, rbac.Request{
+		Action:    action,
+		Namespace: namespaceParam(c),
+		Key:       key,
+	})
+}
+
+// authorizePrefix проверяет доступ ко всем ключам с префиксом в namespace запроса
+func (h *Handler) authorizePrefix(c *gin.Context, action rbac.Action, prefix string) bool {
+	return authorize(c, h.policy, h.logger, rbac.Request{
+		Action:    action,
+		Namespace: namespaceParam(c),
+		Key:       prefix,
+		Prefix:    true,
+	})
+}
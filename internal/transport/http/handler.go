@@ -1,57 +1,108 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"kv-storage/internal/domain"
+	"kv-storage/internal/events"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
 	"kv-storage/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 type Handler struct {
 	service *service.KVService
+	broker  *events.Broker
+	policy  *rbac.Policy
 	logger  interfaces.Logger
 }
 
-func NewHandler(service *service.KVService, logger interfaces.Logger) *Handler {
+func NewHandler(service *service.KVService, broker *events.Broker, policy *rbac.Policy, logger interfaces.Logger) *Handler {
 	return &Handler{
 		service: service,
+		broker:  broker,
+		policy:  policy,
 		logger:  logger,
 	}
 }
 
+// kv возвращает сервис namespace запроса: маршруты /ns/:namespace работают в указанном
+// namespace, маршруты /kv — в namespace по умолчанию
+func (h *Handler) kv(c *gin.Context) *service.KVService {
+	return h.service.Namespace(namespaceParam(c))
+}
+
+func namespaceParam(c *gin.Context) string {
+	if namespace := c.Param("namespace"); namespace != "" {
+		return namespace
+	}
+	return domain.DefaultNamespace
+}
+
 // Create godoc
 // @Summary Create a new key-value pair
-// @Description Create a new key-value pair in the storage
+// @Description Create a new key-value pair in the storage. The value may be any JSON value; binary values are sent either as base64 with content_type "application/octet-stream" or as a raw application/octet-stream body with the key in ?key=
 // @Tags kv
-// @Accept json
+// @Accept json,octet-stream
 // @Produce json
 // @Param kv body domain.CreateKVRequest true "Key-value pair to create"
+// @Param key query string false "Key for a raw application/octet-stream body"
+// @Param ttl query int false "TTL in seconds for a raw application/octet-stream body"
 // @Success 201 {object} domain.KV
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 409 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv [post]
 func (h *Handler) Create(c *gin.Context) {
 	var req domain.CreateKVRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if c.ContentType() == domain.ContentTypeBinary {
+		value, ttl, err := readBinaryBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req = domain.CreateKVRequest{
+			Key:         c.Query("key"),
+			Value:       value,
+			ContentType: domain.ContentTypeBinary,
+			TTL:         ttl,
+		}
+	} else if err := bindJSON(c, &req); err != nil {
 		h.logger.Error("Failed to bind JSON", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	kv, err := h.service.Create(&req)
+	if !h.authorizeKey(c, rbac.ActionWrite, req.Key) {
+		return
+	}
+
+	kv, err := h.kv(c).Create(c.Request.Context(), &req)
 	if err != nil {
 		switch err {
-		case domain.ErrInvalidKey, domain.ErrInvalidValue:
+		case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrUnsupportedContentType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
 		case domain.ErrKeyExists:
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		case domain.ErrKeyAlreadyExists:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
 		default:
 			h.logger.Error("Failed to create KV", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -59,17 +110,22 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
+	setETag(c, kv)
 	c.JSON(http.StatusCreated, kv)
 }
 
 // Get godoc
 // @Summary Get a key-value pair by key
-// @Description Retrieve a key-value pair from the storage by its key
+// @Description Retrieve a key-value pair from the storage by its key. With ?at= returns a past revision: an integer selects a version, an RFC 3339 timestamp selects the value the key had at that moment. Binary values are returned as a raw body when the request accepts application/octet-stream
 // @Tags kv
-// @Produce json
+// @Produce json,octet-stream
 // @Param key path string true "Key to retrieve"
+// @Param at query string false "Version number or RFC 3339 timestamp"
+// @Param consistency query string false "Read consistency: eventual (replica, default) or strong (master)"
 // @Success 200 {object} domain.KV
+// @Header 200 {string} ETag "Record version"
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv/{key} [get]
@@ -80,15 +136,28 @@ func (h *Handler) Get(c *gin.Context) {
 		return
 	}
 
-	kv, err := h.service.Get(key)
+	if !h.authorizeKey(c, rbac.ActionRead, key) {
+		return
+	}
+
+	var kv *domain.KV
+	var err error
+
+	if at := c.Query("at"); at != "" {
+		kv, err = h.getAt(c, key, at)
+	} else {
+		kv, err = h.kv(c).Get(c.Request.Context(), key)
+	}
 	h.logger.Info("Get result", "key", key, "kv", kv, "err", err)
 
 	if err != nil {
 		switch err {
-		case domain.ErrInvalidKey:
+		case domain.ErrInvalidKey, domain.ErrValidationError:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case domain.ErrKeyNotFound:
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound, domain.ErrRevisionNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
 		default:
 			h.logger.Error("Failed to get KV", "key", key, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -96,20 +165,151 @@ func (h *Handler) Get(c *gin.Context) {
 		return
 	}
 
+	setETag(c, kv)
+	if data, ok := kv.Value.([]byte); ok && acceptsBinary(c) {
+		c.Data(http.StatusOK, domain.ContentTypeBinary, data)
+		return
+	}
+	c.JSON(http.StatusOK, kv)
+}
+
+// getAt читает ревизию по номеру версии или по моменту времени
+func (h *Handler) getAt(c *gin.Context, key, at string) (*domain.KV, error) {
+	if version, err := strconv.ParseUint(at, 10, 64); err == nil {
+		return h.kv(c).GetVersion(c.Request.Context(), key, version)
+	}
+
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return nil, domain.ErrValidationError
+	}
+	return h.kv(c).GetAt(c.Request.Context(), key, t)
+}
+
+// History godoc
+// @Summary Get revision history of a key
+// @Description Return the current state of the key (including a soft-deleted one) followed by its previous revisions, newest first. Only the last history.depth revisions are kept
+// @Tags kv
+// @Produce json
+// @Param key path string true "Key"
+// @Param consistency query string false "Read consistency: eventual (replica, default) or strong (master)"
+// @Success 200 {object} domain.HistoryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kv/{key}/history [get]
+func (h *Handler) History(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key is required"})
+		return
+	}
+
+	if !h.authorizeKey(c, rbac.ActionRead, key) {
+		return
+	}
+
+	response, err := h.kv(c).History(c.Request.Context(), key)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to get KV history", "key", key, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Revert godoc
+// @Summary Revert a key to an older revision
+// @Description Write the value of the given revision as a new version through the regular update path
+// @Tags kv
+// @Produce json
+// @Param key path string true "Key to revert"
+// @Param version query int true "Version to revert to"
+// @Param If-Match header string false "Expected current record version (ETag)"
+// @Success 200 {object} domain.KV
+// @Header 200 {string} ETag "Record version"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kv/{key}/revert [post]
+func (h *Handler) Revert(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key is required"})
+		return
+	}
+
+	if !h.authorizeKey(c, rbac.ActionWrite, key) {
+		return
+	}
+
+	version, err := strconv.ParseUint(c.Query("version"), 10, 64)
+	if err != nil || version == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version parameter"})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	kv, err := h.kv(c).Revert(c.Request.Context(), key, version, expectedVersion)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound, domain.ErrRevisionNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrVersionConflict:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to revert KV", "key", key, "version", version, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	setETag(c, kv)
 	c.JSON(http.StatusOK, kv)
 }
 
 // Update godoc
 // @Summary Update a key-value pair
-// @Description Update an existing key-value pair in the storage
+// @Description Update an existing key-value pair in the storage. A raw application/octet-stream body stores a binary value. With ?if_absent=true the key is created only if it does not exist yet; otherwise 409 is returned together with the existing record
 // @Tags kv
-// @Accept json
+// @Accept json,octet-stream
 // @Produce json
 // @Param key path string true "Key to update"
 // @Param kv body domain.UpdateKVRequest true "New value for the key"
+// @Param ttl query int false "TTL in seconds for a raw application/octet-stream body"
+// @Param if_absent query bool false "Create the key only if it does not exist"
+// @Param If-Match header string false "Expected record version (ETag)"
 // @Success 200 {object} domain.KV
+// @Success 201 {object} domain.KV
+// @Header 200 {string} ETag "Record version"
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv/{key} [put]
 func (h *Handler) Update(c *gin.Context) {
@@ -119,20 +319,58 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeKey(c, rbac.ActionWrite, key) {
+		return
+	}
+
 	var req domain.UpdateKVRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if c.ContentType() == domain.ContentTypeBinary {
+		value, ttl, err := readBinaryBody(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req = domain.UpdateKVRequest{
+			Value:       value,
+			ContentType: domain.ContentTypeBinary,
+			TTL:         ttl,
+		}
+	} else if err := bindJSON(c, &req); err != nil {
 		h.logger.Error("Failed to bind JSON", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	kv, err := h.service.Update(key, &req)
+	ifAbsent, err := strconv.ParseBool(c.DefaultQuery("if_absent", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid if_absent parameter"})
+		return
+	}
+	if ifAbsent {
+		h.putIfAbsent(c, key, &req)
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
+	kv, err := h.kv(c).Update(c.Request.Context(), key, &req)
 	if err != nil {
 		switch err {
-		case domain.ErrInvalidKey, domain.ErrInvalidValue:
+		case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case domain.ErrKeyNotFound:
+		case domain.ErrUnsupportedContentType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrVersionConflict:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
 		default:
 			h.logger.Error("Failed to update KV", "key", key, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -140,6 +378,149 @@ func (h *Handler) Update(c *gin.Context) {
 		return
 	}
 
+	setETag(c, kv)
+	c.JSON(http.StatusOK, kv)
+}
+
+func (h *Handler) putIfAbsent(c *gin.Context, key string, req *domain.UpdateKVRequest) {
+	kv, err := h.kv(c).PutIfAbsent(c.Request.Context(), key, req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrUnsupportedContentType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case domain.ErrKeyAlreadyExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "kv": kv})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to put KV if absent", "key", key, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	setETag(c, kv)
+	c.JSON(http.StatusCreated, kv)
+}
+
+// CompareAndSwap godoc
+// @Summary Compare and swap a value
+// @Description Atomically replace the value with new_value only if the current value equals expected_value and/or the current version equals expected_version. At least one condition is required
+// @Tags kv
+// @Accept json
+// @Produce json
+// @Param key path string true "Key"
+// @Param cas body domain.CASRequest true "Expected and new values"
+// @Success 200 {object} domain.KV
+// @Header 200 {string} ETag "Record version"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 415 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kv/{key}/cas [post]
+func (h *Handler) CompareAndSwap(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key is required"})
+		return
+	}
+
+	if !h.authorizeKey(c, rbac.ActionWrite, key) {
+		return
+	}
+
+	var req domain.CASRequest
+	if err := bindJSON(c, &req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	kv, err := h.kv(c).CompareAndSwap(c.Request.Context(), key, &req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL, domain.ErrValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrUnsupportedContentType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrValueMismatch:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case domain.ErrVersionConflict:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to compare and swap KV", "key", key, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	setETag(c, kv)
+	c.JSON(http.StatusOK, kv)
+}
+
+// Increment godoc
+// @Summary Atomically increment a counter
+// @Description Add delta (default 1, may be negative) to a numeric value in a single server-side upsert. A missing key is created with initial + delta; initial, ttl and expires_at apply only on creation
+// @Tags kv
+// @Accept json
+// @Produce json
+// @Param key path string true "Counter key"
+// @Param incr body domain.IncrementRequest false "Increment options"
+// @Success 200 {object} domain.KV
+// @Header 200 {string} ETag "Record version"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kv/{key}/incr [post]
+func (h *Handler) Increment(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key is required"})
+		return
+	}
+
+	if !h.authorizeKey(c, rbac.ActionWrite, key) {
+		return
+	}
+
+	var req domain.IncrementRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err.Error() != "EOF" {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	kv, err := h.kv(c).Increment(c.Request.Context(), key, &req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey, domain.ErrInvalidTTL:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNotNumeric:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to increment KV", "key", key, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	setETag(c, kv)
 	c.JSON(http.StatusOK, kv)
 }
 
@@ -151,9 +532,12 @@ func (h *Handler) Update(c *gin.Context) {
 // @Produce json
 // @Param key path string true "Key to delete"
 // @Param delete body domain.DeleteKVRequest false "Delete options"
+// @Param If-Match header string false "Expected record version (ETag)"
 // @Success 200 {object} domain.KV
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv/{key} [delete]
 func (h *Handler) Delete(c *gin.Context) {
@@ -170,21 +554,38 @@ func (h *Handler) Delete(c *gin.Context) {
 		return
 	}
 
+	action := rbac.ActionHardDelete
+	if deleteReq.SoftDelete {
+		action = rbac.ActionDelete
+	}
+	if !h.authorizeKey(c, action, key) {
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var kv *domain.KV
-	var err error
 
 	if !deleteReq.SoftDelete {
-		kv, err = h.service.SoftDelete(key)
+		kv, err = h.kv(c).SoftDelete(c.Request.Context(), key, expectedVersion)
 	} else {
-		kv, err = h.service.Delete(key)
+		kv, err = h.kv(c).Delete(c.Request.Context(), key, expectedVersion)
 	}
 
 	if err != nil {
 		switch err {
 		case domain.ErrInvalidKey:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case domain.ErrKeyNotFound:
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrVersionConflict:
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
 		default:
 			h.logger.Error("Failed to delete KV", "key", key, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -192,6 +593,7 @@ func (h *Handler) Delete(c *gin.Context) {
 		return
 	}
 
+	setETag(c, kv)
 	c.JSON(http.StatusOK, kv)
 }
 
@@ -203,6 +605,7 @@ func (h *Handler) Delete(c *gin.Context) {
 // @Param key path string true "Key to restore"
 // @Success 200 {object} domain.KV
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv/{key}/restore [post]
@@ -213,13 +616,19 @@ func (h *Handler) Restore(c *gin.Context) {
 		return
 	}
 
-	kv, err := h.service.Restore(key)
+	if !h.authorizeKey(c, rbac.ActionWrite, key) {
+		return
+	}
+
+	kv, err := h.kv(c).Restore(c.Request.Context(), key)
 	if err != nil {
 		switch err {
 		case domain.ErrInvalidKey:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case domain.ErrKeyNotFound:
+		case domain.ErrKeyNotFound, domain.ErrNamespaceNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
 		default:
 			h.logger.Error("Failed to restore KV", "key", key, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
@@ -227,6 +636,7 @@ func (h *Handler) Restore(c *gin.Context) {
 		return
 	}
 
+	setETag(c, kv)
 	c.JSON(http.StatusOK, kv)
 }
 
@@ -237,30 +647,52 @@ func (h *Handler) Restore(c *gin.Context) {
 // @Produce json
 // @Param limit query int false "Number of items to return (default: 10, max: 100)"
 // @Param offset query int false "Number of items to skip (default: 0)"
+// @Param cursor query string false "Opaque cursor from the previous page; takes precedence over offset"
+// @Param count query bool false "Compute the total number of records (default: true)"
+// @Param prefix query string false "Return only keys with this prefix"
+// @Param from query string false "Lower bound of the key range (inclusive)"
+// @Param to query string false "Upper bound of the key range (exclusive)"
+// @Param consistency query string false "Read consistency: eventual (replica, default) or strong (master)"
 // @Success 200 {object} domain.ListKVResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv [get]
 func (h *Handler) List(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+	req, ok := parseListRequest(c)
+	if !ok {
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+	if !h.authorizePrefix(c, rbac.ActionRead, rbac.RangePrefix(c.Query("prefix"), c.Query("from"), c.Query("to"))) {
 		return
 	}
 
-	response, err := h.service.List(limit, offset)
+	var response *domain.ListKVResponse
+	var err error
+
+	if scan := scanRequest(c, req, false); scan != nil {
+		if req.Offset > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Offset is not supported with prefix or range"})
+			return
+		}
+		response, err = h.kv(c).Scan(c.Request.Context(), scan)
+	} else {
+		response, err = h.kv(c).List(c.Request.Context(), req)
+	}
+
 	if err != nil {
-		h.logger.Error("Failed to list KV", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		switch err {
+		case domain.ErrInvalidCursor, domain.ErrValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to list KV", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
 		return
 	}
 
@@ -274,56 +706,282 @@ func (h *Handler) List(c *gin.Context) {
 // @Produce json
 // @Param limit query int false "Number of items to return (default: 10, max: 100)"
 // @Param offset query int false "Number of items to skip (default: 0)"
+// @Param cursor query string false "Opaque cursor from the previous page; takes precedence over offset"
+// @Param count query bool false "Compute the total number of records (default: true)"
+// @Param prefix query string false "Return only keys with this prefix"
+// @Param from query string false "Lower bound of the key range (inclusive)"
+// @Param to query string false "Upper bound of the key range (exclusive)"
+// @Param consistency query string false "Read consistency: eventual (replica, default) or strong (master)"
 // @Success 200 {object} domain.ListKVResponse
 // @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/v1/kv/all [get]
 func (h *Handler) ListIncludingDeleted(c *gin.Context) {
+	req, ok := parseListRequest(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorizePrefix(c, rbac.ActionListDeleted, rbac.RangePrefix(c.Query("prefix"), c.Query("from"), c.Query("to"))) {
+		return
+	}
+
+	var response *domain.ListKVResponse
+	var err error
+
+	if scan := scanRequest(c, req, true); scan != nil {
+		if req.Offset > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Offset is not supported with prefix or range"})
+			return
+		}
+		response, err = h.kv(c).Scan(c.Request.Context(), scan)
+	} else {
+		response, err = h.kv(c).ListIncludingDeleted(c.Request.Context(), req)
+	}
+
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidCursor, domain.ErrValidationError:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to list KV including deleted", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Batch godoc
+// @Summary Execute a batch of operations
+// @Description Execute get/create/update/delete operations in one request. In "atomic" mode (default) the whole batch runs in a single transaction and is rolled back on the first failure, including a get of a missing key; in "best_effort" mode every operation is applied independently
+// @Tags kv
+// @Accept json
+// @Produce json
+// @Param batch body domain.BatchRequest true "Batch operations"
+// @Success 200 {object} domain.BatchResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/kv/_batch [post]
+func (h *Handler) Batch(c *gin.Context) {
+	var req domain.BatchRequest
+	if err := bindJSON(c, &req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Пакет выполняется, только если разрешены все его операции
+	for _, op := range req.Operations {
+		if !h.authorizeKey(c, batchAction(op.Op), op.Key) {
+			return
+		}
+	}
+
+	response, err := h.kv(c).Batch(c.Request.Context(), &req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey, domain.ErrInvalidValue, domain.ErrInvalidTTL, domain.ErrValidationError,
+			domain.ErrCrossShard:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrUnsupportedContentType:
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case domain.ErrBatchAborted:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "results": response.Results})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to execute batch", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Changes godoc
+// @Summary Read the change log
+// @Description Return durable change log entries after the given position, oldest first. Pass next_cursor from the response as cursor to continue (next_since as since also works without sharding); entries older than the configured retention are trimmed
+// @Tags changes
+// @Produce json
+// @Param since query int false "LSN of the last processed entry (default: 0)"
+// @Param cursor query string false "Opaque cursor from the previous response; takes precedence over since"
+// @Param limit query int false "Number of entries to return (default: 100, max: 1000)"
+// @Success 200 {object} domain.ChangesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/changes [get]
+func (h *Handler) Changes(c *gin.Context) {
+	// Журнал содержит изменения всех ключей namespace
+	if !h.authorizePrefix(c, rbac.ActionRead, "") {
+		return
+	}
+
+	since, err := strconv.ParseUint(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since parameter"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	response, err := h.kv(c).Changes(c.Request.Context(), &domain.ChangesRequest{
+		Since:  since,
+		Cursor: c.Query("cursor"),
+		Limit:  limit,
+	})
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidCursor:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to read changes", "since", since, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// batchAction возвращает действие RBAC для операции пакета; delete в пакете мягкое
+func batchAction(op domain.BatchOp) rbac.Action {
+	switch op {
+	case domain.BatchOpCreate, domain.BatchOpUpdate:
+		return rbac.ActionWrite
+	case domain.BatchOpDelete:
+		return rbac.ActionDelete
+	default:
+		return rbac.ActionRead
+	}
+}
+
+// parseListRequest разбирает параметры пагинации; при ошибке сам отвечает 400
+func parseListRequest(c *gin.Context) (*domain.ListKVRequest, bool) {
 	limitStr := c.DefaultQuery("limit", "10")
 	offsetStr := c.DefaultQuery("offset", "0")
 
 	limit, err := strconv.Atoi(limitStr)
 	if err != nil || limit <= 0 || limit > 100 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
-		return
+		return nil, false
 	}
 
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
-		return
+		return nil, false
 	}
 
-	response, err := h.service.ListIncludingDeleted(limit, offset)
+	count, err := strconv.ParseBool(c.DefaultQuery("count", "true"))
 	if err != nil {
-		h.logger.Error("Failed to list KV including deleted", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		return
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid count parameter"})
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, response)
+	return &domain.ListKVRequest{
+		Limit:     limit,
+		Offset:    offset,
+		Cursor:    c.Query("cursor"),
+		SkipCount: !count,
+	}, true
 }
 
-// HealthCheck godoc
-// @Summary Health check
-// @Description Returns the health status of the service
-// @Tags health
-// @Accept json
-// @Produce json
-// @Success 200 {object} HealthResponse
-// @Router /health [get]
-func (h *Handler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, HealthResponse{
-		Status:  "ok",
-		Service: "kv-storage",
-	})
+// scanRequest возвращает запрос обхода диапазона, если заданы prefix, from или to
+func scanRequest(c *gin.Context, req *domain.ListKVRequest, includeDeleted bool) *domain.ScanKVRequest {
+	prefix, from, to := c.Query("prefix"), c.Query("from"), c.Query("to")
+	if prefix == "" && from == "" && to == "" {
+		return nil
+	}
+
+	return &domain.ScanKVRequest{
+		Prefix:         prefix,
+		From:           from,
+		To:             to,
+		Limit:          req.Limit,
+		Cursor:         req.Cursor,
+		IncludeDeleted: includeDeleted,
+	}
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// readBinaryBody читает тело application/octet-stream и TTL из ?ttl=
+func readBinaryBody(c *gin.Context) ([]byte, int64, error) {
+	var ttl int64
+	if ttlStr := c.Query("ttl"); ttlStr != "" {
+		parsed, err := strconv.ParseInt(ttlStr, 10, 64)
+		if err != nil {
+			return nil, 0, errors.New("Invalid ttl parameter")
+		}
+		ttl = parsed
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, 0, errors.New("Invalid request body")
+	}
+	return data, ttl, nil
 }
 
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
+// acceptsBinary сообщает, что клиент готов принять бинарное значение без JSON-обёртки
+func acceptsBinary(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, domain.ContentTypeBinary) == domain.ContentTypeBinary
+}
+
+// bindJSON разбирает тело как ShouldBindJSON, но числа в значениях декодирует как json.Number,
+// чтобы целые не теряли точность во float64
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// setETag отдаёт версию записи как сильный ETag
+func setETag(c *gin.Context, kv *domain.KV) {
+	c.Header("ETag", strconv.Quote(strconv.FormatUint(kv.Version, 10)))
+}
+
+// parseIfMatch возвращает версию из заголовка If-Match.
+// Отсутствующий заголовок и "*" дают 0, то есть операцию без проверки версии.
+func parseIfMatch(c *gin.Context) (uint64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version == 0 {
+		return 0, errors.New("Invalid If-Match header")
+	}
+	return version, nil
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
 }
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Fatal(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Sync() error                                    { return nil }
+
+// memoryRepository хранит записи в памяти и проверяет версии, как kv_update и kv_soft_delete;
+// остальные методы репозитория в этих тестах не вызываются
+type memoryRepository struct {
+	interfaces.KVRepository
+	records map[string]*domain.KV
+}
+
+func (r *memoryRepository) Create(ctx context.Context, kv *domain.KV) error {
+	if _, ok := r.records[kv.Key]; ok {
+		return domain.ErrKeyAlreadyExists
+	}
+	kv.Version = 1
+	r.records[kv.Key] = kv
+	return nil
+}
+
+func (r *memoryRepository) Get(ctx context.Context, key string) (*domain.KV, error) {
+	kv, ok := r.records[key]
+	if !ok || kv.IsDeleted {
+		return nil, domain.ErrKeyNotFound
+	}
+	return kv, nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, kv *domain.KV, expectedVersion uint64) (*domain.KV, error) {
+	previous, err := r.Get(ctx, kv.Key)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && previous.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	kv.Version = previous.Version + 1
+	r.records[kv.Key] = kv
+	return previous, nil
+}
+
+func (r *memoryRepository) SoftDelete(ctx context.Context, key string, expectedVersion uint64) (*domain.KV, error) {
+	current, err := r.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != 0 && current.Version != expectedVersion {
+		return nil, domain.ErrVersionConflict
+	}
+	deleted := *current
+	deleted.IsDeleted = true
+	deleted.Version++
+	r.records[key] = &deleted
+	return &deleted, nil
+}
+
+func newTestEngine(repo interfaces.KVRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewHandler(service.NewKVService(repo, nopLogger{}, nil), nil, nil, nopLogger{})
+	engine := gin.New()
+	registerKVRoutes(engine.Group("/api/v1/kv"), handler)
+	return engine
+}
+
+func TestHandler_IfMatch(t *testing.T) {
+	repo := &memoryRepository{records: map[string]*domain.KV{
+		"user:1": {Key: "user:1", Value: "a", ContentType: domain.ContentTypeJSON, Version: 1},
+	}}
+	engine := newTestEngine(repo)
+
+	// Шаги выполняются по порядку: каждый успешный PUT увеличивает версию на 1
+	steps := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		wantStatus int
+		wantETag   string
+	}{
+		{"get", http.MethodGet, "", http.StatusOK, `"1"`},
+		{"update without If-Match", http.MethodPut, "", http.StatusOK, `"2"`},
+		{"update with current version", http.MethodPut, `"2"`, http.StatusOK, `"3"`},
+		{"update with stale version", http.MethodPut, `"2"`, http.StatusPreconditionFailed, ""},
+		{"update with unquoted version", http.MethodPut, "3", http.StatusOK, `"4"`},
+		{"update with any version", http.MethodPut, "*", http.StatusOK, `"5"`},
+		{"update with invalid If-Match", http.MethodPut, `"abc"`, http.StatusBadRequest, ""},
+		{"update with zero version", http.MethodPut, `"0"`, http.StatusBadRequest, ""},
+		{"get after updates", http.MethodGet, "", http.StatusOK, `"5"`},
+		{"delete with stale version", http.MethodDelete, `"4"`, http.StatusPreconditionFailed, ""},
+		{"delete with current version", http.MethodDelete, `"5"`, http.StatusOK, `"6"`},
+		{"update deleted record", http.MethodPut, `"6"`, http.StatusNotFound, ""},
+	}
+
+	for _, step := range steps {
+		body := ""
+		if step.method == http.MethodPut {
+			body = `{"value": "b"}`
+		}
+		req := httptest.NewRequest(step.method, "/api/v1/kv/user:1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.ifMatch != "" {
+			req.Header.Set("If-Match", step.ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d (body %s)", step.name, w.Code, step.wantStatus, w.Body)
+		}
+		if got := w.Header().Get("ETag"); got != step.wantETag {
+			t.Errorf("%s: ETag = %q, want %q", step.name, got, step.wantETag)
+		}
+	}
+
+	if kv := repo.records["user:1"]; kv.Version != 6 || !kv.IsDeleted {
+		t.Errorf("record after steps = %+v, want deleted at version 6", kv)
+	}
+}
+
+func TestHandler_CreateValues(t *testing.T) {
+	repo := &memoryRepository{records: map[string]*domain.KV{}}
+	engine := newTestEngine(repo)
+
+	tests := []struct {
+		name       string
+		key        string
+		value      string
+		wantStatus int
+		wantValue  interface{}
+	}{
+		// 2^53 + 1 не представимо во float64 и без json.Number превратилось бы в 2^53
+		{"large integer", "int", "9007199254740993", http.StatusCreated, int64(9007199254740993)},
+		{"float", "float", "1.5", http.StatusCreated, 1.5},
+		{"empty object", "object", "{}", http.StatusCreated, map[string]interface{}{}},
+		{"empty array", "array", "[]", http.StatusCreated, []interface{}{}},
+		{"empty string", "string", `""`, http.StatusBadRequest, nil},
+		{"null", "null", "null", http.StatusBadRequest, nil},
+		{"missing key", "", "1", http.StatusBadRequest, nil},
+		{"malformed body", "bad", "{", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"key": %q, "value": %s}`, tt.key, tt.value)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/kv", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantValue == nil {
+				return
+			}
+			if stored := repo.records[tt.key].Value; !reflect.DeepEqual(stored, tt.wantValue) {
+				t.Errorf("stored value = %#v, want %#v", stored, tt.wantValue)
+			}
+		})
+	}
+}
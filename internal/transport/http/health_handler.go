@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	service *service.HealthService
+	logger  interfaces.Logger
+}
+
+func NewHealthHandler(service *service.HealthService, logger interfaces.Logger) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Livez godoc
+// @Summary Liveness probe
+// @Description Returns ok while the process is able to serve HTTP. Dependencies are not checked, use /readyz for that. /health is kept as an alias
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{
+		Status:  "ok",
+		Service: "kv-storage",
+	})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Pings Tarantool, checks that the kv space and its indexes exist and reports connection pool saturation. Every check is listed with its status and latency; warn does not make the instance unready
+// @Tags health
+// @Produce json
+// @Success 200 {object} domain.HealthReport
+// @Failure 503 {object} domain.HealthReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.service.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status == domain.HealthFail {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+}
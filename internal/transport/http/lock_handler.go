@@ -0,0 +1,193 @@
+package http
+
+import (
+	"net/http"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LockHandler struct {
+	service *service.LockService
+	policy  *rbac.Policy
+	logger  interfaces.Logger
+}
+
+func NewLockHandler(service *service.LockService, policy *rbac.Policy, logger interfaces.Logger) *LockHandler {
+	return &LockHandler{
+		service: service,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+// Acquire godoc
+// @Summary Acquire a lease
+// @Description Acquire the named lease for owner for ttl seconds (default from config). A free or expired lease gets a new fencing token; acquiring a lease already held by the same owner extends it and keeps the token. A lease held by another owner is returned with 409 in "lock"
+// @Tags locks
+// @Accept json
+// @Produce json
+// @Param name path string true "Lock name"
+// @Param lock body domain.AcquireLockRequest true "Owner and TTL"
+// @Success 200 {object} domain.Lock
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/locks/{name} [post]
+func (h *LockHandler) Acquire(c *gin.Context) {
+	name := c.Param("name")
+	if !h.authorize(c, name) {
+		return
+	}
+
+	var req domain.AcquireLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	lock, err := h.service.Acquire(c.Request.Context(), name, &req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidKey, domain.ErrValidationError, domain.ErrInvalidTTL:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrLockHeld:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "lock": lock})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to acquire lock", "name", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// Renew godoc
+// @Summary Renew a lease
+// @Description Extend the lease by ttl seconds from now. Owner and fencing token must match the current holder
+// @Tags locks
+// @Accept json
+// @Produce json
+// @Param name path string true "Lock name"
+// @Param lock body domain.RenewLockRequest true "Owner, fencing token and TTL"
+// @Success 200 {object} domain.Lock
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/locks/{name} [put]
+func (h *LockHandler) Renew(c *gin.Context) {
+	name := c.Param("name")
+	if !h.authorize(c, name) {
+		return
+	}
+
+	var req domain.RenewLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	lock, err := h.service.Renew(c.Request.Context(), name, &req)
+	if err != nil {
+		h.handleError(c, name, "Failed to renew lock", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// Release godoc
+// @Summary Release a lease
+// @Description Release the lease. Owner and fencing token must match the current holder
+// @Tags locks
+// @Accept json
+// @Produce json
+// @Param name path string true "Lock name"
+// @Param lock body domain.ReleaseLockRequest true "Owner and fencing token"
+// @Success 200 {object} domain.Lock
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/locks/{name} [delete]
+func (h *LockHandler) Release(c *gin.Context) {
+	name := c.Param("name")
+	if !h.authorize(c, name) {
+		return
+	}
+
+	var req domain.ReleaseLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	lock, err := h.service.Release(c.Request.Context(), name, &req)
+	if err != nil {
+		h.handleError(c, name, "Failed to release lock", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+// Get godoc
+// @Summary Get a lease
+// @Description Return the current holder of the lease
+// @Tags locks
+// @Produce json
+// @Param name path string true "Lock name"
+// @Success 200 {object} domain.Lock
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/locks/{name} [get]
+func (h *LockHandler) Get(c *gin.Context) {
+	name := c.Param("name")
+	if !h.authorize(c, name) {
+		return
+	}
+
+	lock, err := h.service.Get(c.Request.Context(), name)
+	if err != nil {
+		h.handleError(c, name, "Failed to get lock", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lock)
+}
+
+func (h *LockHandler) handleError(c *gin.Context, name, message string, err error) {
+	switch err {
+	case domain.ErrInvalidKey, domain.ErrValidationError, domain.ErrInvalidTTL:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case domain.ErrLockNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case domain.ErrNotLockOwner:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case domain.ErrTimeout, domain.ErrCanceled:
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, "name", name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// authorize проверяет доступ к аренде: её имя сопоставляется с шаблонами ключей правил
+func (h *LockHandler) authorize(c *gin.Context, name string) bool {
+	return authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionLock, Key: name})
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PrincipalKey — ключ gin.Context, под которым лежит *domain.Principal запроса
+const PrincipalKey = "principal"
+
+const (
+	apiKeyHeader      = "X-API-Key"
+	defaultRolesClaim = "roles"
+)
+
+var (
+	errNoCredentials  = errors.New("authentication required")
+	errInvalidAPIKey  = errors.New("invalid api key")
+	errInvalidToken   = errors.New("invalid token")
+	errUnknownSignKey = errors.New("unknown signing key")
+)
+
+// Authenticator проверяет статические API-ключи (заголовок X-API-Key) и JWT
+// (Authorization: Bearer) с подписью HS256 или RS256
+type Authenticator struct {
+	// API-ключи ищутся по SHA-256, чтобы время поиска не выдавало совпавший префикс ключа
+	apiKeys    map[[sha256.Size]byte]*domain.Principal
+	hmacSecret []byte
+	jwks       *keySet
+	parser     *jwt.Parser
+	rolesClaim string
+	logger     interfaces.Logger
+}
+
+func NewAuthenticator(cfg config.AuthConfig, logger interfaces.Logger) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:    make(map[[sha256.Size]byte]*domain.Principal, len(cfg.APIKeys)),
+		rolesClaim: cfg.JWT.RolesClaim,
+		logger:     logger,
+	}
+	if a.rolesClaim == "" {
+		a.rolesClaim = defaultRolesClaim
+	}
+
+	for i, key := range cfg.APIKeys {
+		if key.Key == "" || key.Name == "" {
+			return nil, fmt.Errorf("api key %d: name and key are required", i)
+		}
+		a.apiKeys[sha256.Sum256([]byte(key.Key))] = &domain.Principal{
+			Name:   key.Name,
+			Method: domain.AuthMethodAPIKey,
+			Roles:  key.Roles,
+		}
+	}
+
+	if cfg.JWT.HS256Secret != "" {
+		a.hmacSecret = []byte(cfg.JWT.HS256Secret)
+	}
+	if cfg.JWT.JWKSFile != "" {
+		jwks, err := loadKeySet(cfg.JWT.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwks: %w", err)
+		}
+		a.jwks = jwks
+	}
+
+	// Разрешены только HS256 и RS256: это исключает alg=none и подмену алгоритма
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.JWT.Leeway),
+	}
+	if cfg.JWT.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.JWT.Issuer))
+	}
+	if cfg.JWT.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.JWT.Audience))
+	}
+	a.parser = jwt.NewParser(options...)
+
+	logger.Info("Authentication enabled",
+		"api_keys", len(a.apiKeys),
+		"hs256", a.hmacSecret != nil,
+		"jwks_keys", a.jwks.len(),
+	)
+	return a, nil
+}
+
+// Authenticate отклоняет запрос с 401, если он не несёт действительных учётных данных,
+// иначе кладёт *domain.Principal в контекст под PrincipalKey
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := a.authenticate(c.Request)
+		if err != nil {
+			a.logger.Warn("Authentication failed",
+				"path", c.Request.URL.Path,
+				"client_ip", c.ClientIP(),
+				"reason", err,
+			)
+			c.Header("WWW-Authenticate", `Bearer realm="kv-storage"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
+
+// PrincipalFromContext возвращает клиента, аутентифицированного Authenticate
+func PrincipalFromContext(c *gin.Context) (*domain.Principal, bool) {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*domain.Principal)
+	return principal, ok
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (*domain.Principal, error) {
+	return a.Verify(r.Header.Get(apiKeyHeader), r.Header.Get("Authorization"))
+}
+
+// Verify проверяет значения заголовков X-API-Key и Authorization независимо от
+// транспорта; API-ключ имеет приоритет над токеном
+func (a *Authenticator) Verify(apiKey, authorization string) (*domain.Principal, error) {
+	if apiKey != "" {
+		principal, ok := a.apiKeys[sha256.Sum256([]byte(apiKey))]
+		if !ok {
+			return nil, errInvalidAPIKey
+		}
+		return principal, nil
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, errNoCredentials
+	}
+
+	return a.parseToken(strings.TrimSpace(token))
+}
+
+func (a *Authenticator) parseToken(raw string) (*domain.Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(raw, claims, a.signingKey); err != nil {
+		a.logger.Debug("JWT rejected", "error", err)
+		return nil, errInvalidToken
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, errInvalidToken
+	}
+
+	return &domain.Principal{
+		Name:   subject,
+		Method: domain.AuthMethodJWT,
+		Roles:  claimStrings(claims[a.rolesClaim]),
+	}, nil
+}
+
+// signingKey выбирает ключ проверки по алгоритму и kid токена. HS256 проверяется
+// только симметричными ключами, RS256 — только RSA, поэтому публичный ключ
+// не может быть использован как HMAC-секрет.
+func (a *Authenticator) signingKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if secret, ok := a.jwks.hmac(kid); ok {
+			return secret, nil
+		}
+		if a.hmacSecret != nil {
+			return a.hmacSecret, nil
+		}
+	case jwt.SigningMethodRS256.Alg():
+		if key, ok := a.jwks.rsa(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, errUnknownSignKey
+}
+
+// claimStrings принимает роли как массив строк или как строку через пробел (формат scope)
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
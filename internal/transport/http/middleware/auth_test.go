@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, fields ...interface{}) {}
+func (nopLogger) Info(msg string, fields ...interface{})  {}
+func (nopLogger) Warn(msg string, fields ...interface{})  {}
+func (nopLogger) Error(msg string, fields ...interface{}) {}
+func (nopLogger) Fatal(msg string, fields ...interface{}) {}
+func (nopLogger) Sync() error                             { return nil }
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	document := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuthenticator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.AuthConfig{
+		Enabled: true,
+		APIKeys: []config.APIKeyConfig{{Name: "ci", Key: "secret-key", Roles: []string{"writer"}}},
+		JWT: config.JWTConfig{
+			HS256Secret: "hmac-secret",
+			JWKSFile:    writeJWKS(t, "key-1", &rsaKey.PublicKey),
+			Issuer:      "https://auth.example.com",
+		},
+	}
+	auth, err := NewAuthenticator(cfg, nopLogger{})
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	claims := func(subject string, expiresIn time.Duration) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   subject,
+			"iss":   "https://auth.example.com",
+			"exp":   time.Now().Add(expiresIn).Unix(),
+			"roles": []string{"reader"},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		header        string
+		value         string
+		wantStatus    int
+		wantPrincipal string
+	}{
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+		{name: "api key", header: "X-API-Key", value: "secret-key", wantStatus: http.StatusOK, wantPrincipal: "ci"},
+		{name: "wrong api key", header: "X-API-Key", value: "secret-kez", wantStatus: http.StatusUnauthorized},
+		{
+			name:          "hs256",
+			header:        "Authorization",
+			value:         "Bearer " + sign(jwt.SigningMethodHS256, []byte("hmac-secret"), "", claims("alice", time.Hour)),
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "alice",
+		},
+		{
+			name:          "rs256 from jwks",
+			header:        "Authorization",
+			value:         "Bearer " + sign(jwt.SigningMethodRS256, rsaKey, "key-1", claims("bob", time.Hour)),
+			wantStatus:    http.StatusOK,
+			wantPrincipal: "bob",
+		},
+		{
+			name:       "unknown kid",
+			header:     "Authorization",
+			value:      "Bearer " + sign(jwt.SigningMethodRS256, rsaKey, "key-2", claims("bob", time.Hour)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired",
+			header:     "Authorization",
+			value:      "Bearer " + sign(jwt.SigningMethodHS256, []byte("hmac-secret"), "", claims("alice", -time.Hour)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong secret",
+			header:     "Authorization",
+			value:      "Bearer " + sign(jwt.SigningMethodHS256, []byte("other"), "", claims("alice", time.Hour)),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "alg none",
+			header:     "Authorization",
+			value:      "Bearer " + sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims("alice", time.Hour)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal *domain.Principal
+
+			engine := gin.New()
+			engine.Use(auth.Authenticate())
+			engine.GET("/", func(c *gin.Context) {
+				principal, _ = PrincipalFromContext(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantPrincipal != "" && (principal == nil || principal.Name != tt.wantPrincipal) {
+				t.Errorf("principal = %+v, want %s", principal, tt.wantPrincipal)
+			}
+		})
+	}
+}
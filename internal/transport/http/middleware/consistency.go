@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"kv-storage/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Consistency читает параметр ?consistency=eventual|strong и кладёт его в контекст запроса.
+// Без параметра чтения могут уйти на реплику; strong направляет их на мастер.
+func Consistency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Query("consistency")
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		consistency := domain.Consistency(value)
+		if !domain.ValidConsistency(consistency) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid consistency"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(domain.WithConsistency(c.Request.Context(), consistency))
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kv-storage/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConsistency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(Consistency())
+
+	var got domain.Consistency
+	engine.GET("/kv/:key", func(c *gin.Context) {
+		got = domain.ConsistencyFromContext(c.Request.Context())
+	})
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		want       domain.Consistency
+	}{
+		{"/kv/user:1", http.StatusOK, domain.ConsistencyEventual},
+		{"/kv/user:1?consistency=strong", http.StatusOK, domain.ConsistencyStrong},
+		{"/kv/user:1?consistency=eventual", http.StatusOK, domain.ConsistencyEventual},
+		{"/kv/user:1?consistency=linearizable", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		got = ""
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+		}
+		if got != tt.want {
+			t.Errorf("GET %s: consistency = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
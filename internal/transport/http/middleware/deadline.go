@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline задаёт срок обработки запроса: по его истечении контекст запроса отменяется,
+// и ожидание соединения из пула и запросы к Tarantool прерываются. Срок ищется в routes
+// по методу и шаблону маршрута ("POST /api/v1/kv/_batch"), иначе берётся timeout;
+// нулевой срок снимает ограничение.
+func Deadline(timeout time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := timeout
+		if route, ok := routes[c.Request.Method+" "+c.FullPath()]; ok {
+			d = route
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.Use(Deadline(time.Second, map[string]time.Duration{
+		"POST /kv/_batch": time.Minute,
+		"GET /kv/_watch":  0,
+	}))
+
+	var remaining time.Duration
+	var hasDeadline bool
+	handler := func(c *gin.Context) {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+	}
+	engine.GET("/kv/_watch", handler)
+	engine.GET("/kv/:key", handler)
+	engine.POST("/kv/_batch", handler)
+
+	tests := []struct {
+		method, path string
+		want         time.Duration
+	}{
+		{http.MethodGet, "/kv/user:1", time.Second},
+		{http.MethodPost, "/kv/_batch", time.Minute},
+		{http.MethodGet, "/kv/_watch", 0},
+	}
+
+	for _, tt := range tests {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+		if tt.want == 0 {
+			if hasDeadline {
+				t.Errorf("%s %s: deadline set, want none", tt.method, tt.path)
+			}
+			continue
+		}
+		if !hasDeadline || remaining > tt.want || remaining < tt.want-time.Second/2 {
+			t.Errorf("%s %s: deadline in %v, want %v", tt.method, tt.path, remaining, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jsonWebKey — поля JWK (RFC 7517), нужные для RSA и симметричных ключей
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// keySet — ключи из JWKS-файла по kid. Ключ без kid подходит только токенам без kid.
+type keySet struct {
+	rsaKeys  map[string]*rsa.PublicKey
+	hmacKeys map[string][]byte
+}
+
+func loadKeySet(path string) (*keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	set := &keySet{
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+		hmacKeys: make(map[string][]byte),
+	}
+	for i, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		switch key.Kty {
+		case "RSA":
+			publicKey, err := key.rsaPublicKey()
+			if err != nil {
+				return nil, fmt.Errorf("key %d (%s): %w", i, key.Kid, err)
+			}
+			set.rsaKeys[key.Kid] = publicKey
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(key.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %d (%s): invalid k", i, key.Kid)
+			}
+			set.hmacKeys[key.Kid] = secret
+		}
+	}
+
+	return set, nil
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, fmt.Errorf("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+}
+
+func (s *keySet) rsa(kid string) (*rsa.PublicKey, bool) {
+	if s == nil {
+		return nil, false
+	}
+	key, ok := s.rsaKeys[kid]
+	return key, ok
+}
+
+func (s *keySet) hmac(kid string) ([]byte, bool) {
+	if s == nil {
+		return nil, false
+	}
+	key, ok := s.hmacKeys[kid]
+	return key, ok
+}
+
+func (s *keySet) len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rsaKeys) + len(s.hmacKeys)
+}
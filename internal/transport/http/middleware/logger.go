@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"kv-storage/internal/domain"
 	"kv-storage/internal/interfaces"
 
 	"github.com/gin-gonic/gin"
@@ -8,14 +9,19 @@ import (
 
 func Logger(logger interfaces.Logger) gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		logger.Info("HTTP Request",
+		fields := []interface{}{
 			"method", param.Method,
 			"path", param.Path,
 			"status", param.StatusCode,
 			"latency", param.Latency,
 			"client_ip", param.ClientIP,
 			"user_agent", param.Request.UserAgent(),
-		)
+		}
+		// Principal кладёт Authenticate; к моменту записи лога обработчики уже выполнены
+		if principal, ok := param.Keys[PrincipalKey].(*domain.Principal); ok {
+			fields = append(fields, "principal", principal.Name)
+		}
+		logger.Info("HTTP Request", fields...)
 		return ""
 	})
 }
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"time"
+
+	"kv-storage/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute — метка запросов, не попавших ни в один маршрут
+const unmatchedRoute = "unmatched"
+
+// Metrics учитывает число и длительность запросов по шаблону маршрута и статусу
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/metrics"
 )
 
 type RateLimiter struct {
@@ -14,6 +15,7 @@ type RateLimiter struct {
 	mu       sync.RWMutex
 	rate     int
 	burst    int
+	metrics  *metrics.Metrics
 	logger   interfaces.Logger
 }
 
@@ -25,11 +27,12 @@ type TokenBucket struct {
 	mu         sync.Mutex
 }
 
-func NewRateLimiter(rate, burst int, logger interfaces.Logger) *RateLimiter {
+func NewRateLimiter(rate, burst int, metrics *metrics.Metrics, logger interfaces.Logger) *RateLimiter {
 	return &RateLimiter{
 		requests: make(map[string]*TokenBucket),
 		rate:     rate,
 		burst:    burst,
+		metrics:  metrics,
 		logger:   logger,
 	}
 }
@@ -40,6 +43,7 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 
 		if !rl.allowRequest(clientIP) {
 			rl.logger.Warn("Rate limit exceeded", "client_ip", clientIP)
+			rl.metrics.RateLimited()
 			c.AbortWithStatus(http.StatusTooManyRequests)
 			return
 		}
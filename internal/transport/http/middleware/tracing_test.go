@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	var handlerSpan trace.SpanContext
+	engine := gin.New()
+	engine.Use(Tracing())
+	engine.GET("/kv/:key", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusNotFound)
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/kv/user:1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "GET /kv/:key" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s", got)
+	}
+	if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+		t.Error("request context does not carry the server span")
+	}
+	if span.Status().Code != codes.Unset {
+		t.Errorf("404 span status = %v, want unset", span.Status().Code)
+	}
+
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("500 span status = %v, want error", spans[1].Status().Code)
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"net/http"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NamespaceHandler struct {
+	service *service.NamespaceService
+	policy  *rbac.Policy
+	logger  interfaces.Logger
+}
+
+func NewNamespaceHandler(service *service.NamespaceService, policy *rbac.Policy, logger interfaces.Logger) *NamespaceHandler {
+	return &NamespaceHandler{
+		service: service,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+// Create godoc
+// @Summary Create a namespace
+// @Description Create an isolated keyspace. Names are 1-48 characters of lowercase latin letters, digits and underscores
+// @Tags namespaces
+// @Accept json
+// @Produce json
+// @Param namespace body domain.CreateNamespaceRequest true "Namespace to create"
+// @Success 201 {object} domain.Namespace
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/admin/namespaces [post]
+func (h *NamespaceHandler) Create(c *gin.Context) {
+	var req domain.CreateNamespaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if !authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionAdmin, Namespace: req.Name}) {
+		return
+	}
+
+	namespace, err := h.service.Create(c.Request.Context(), &req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidNamespace:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceExists:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to create namespace", "namespace", req.Name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, namespace)
+}
+
+// List godoc
+// @Summary List namespaces
+// @Description List all namespaces with their key counts and sizes
+// @Tags namespaces
+// @Produce json
+// @Success 200 {object} domain.ListNamespacesResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/admin/namespaces [get]
+func (h *NamespaceHandler) List(c *gin.Context) {
+	if !authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionAdmin}) {
+		return
+	}
+
+	response, err := h.service.List(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list namespaces", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Get godoc
+// @Summary Get namespace stats
+// @Description Key counts, data size, stored revisions and changelog entries of a namespace
+// @Tags namespaces
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Success 200 {object} domain.Namespace
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/admin/namespaces/{namespace} [get]
+func (h *NamespaceHandler) Get(c *gin.Context) {
+	name := c.Param("namespace")
+	if !authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionAdmin, Namespace: name}) {
+		return
+	}
+
+	h.get(c, name)
+}
+
+// Stats godoc
+// @Summary Get namespace stats
+// @Description Same as the admin endpoint, available to callers allowed to read every key of the namespace
+// @Tags namespaces
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Success 200 {object} domain.Namespace
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/ns/{namespace}/stats [get]
+func (h *NamespaceHandler) Stats(c *gin.Context) {
+	name := c.Param("namespace")
+	if !authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionRead, Namespace: name, Prefix: true}) {
+		return
+	}
+
+	h.get(c, name)
+}
+
+func (h *NamespaceHandler) get(c *gin.Context, name string) {
+	namespace, err := h.service.Get(c.Request.Context(), name)
+	if err != nil {
+		switch err {
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to get namespace", "namespace", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, namespace)
+}
+
+// Drop godoc
+// @Summary Drop a namespace
+// @Description Drop a namespace together with all its keys, revisions and changelog. The default namespace cannot be dropped
+// @Tags namespaces
+// @Produce json
+// @Param namespace path string true "Namespace"
+// @Success 200 {object} domain.Namespace
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/admin/namespaces/{namespace} [delete]
+func (h *NamespaceHandler) Drop(c *gin.Context) {
+	name := c.Param("namespace")
+	if !authorize(c, h.policy, h.logger, rbac.Request{Action: rbac.ActionAdmin, Namespace: name}) {
+		return
+	}
+
+	namespace, err := h.service.Drop(c.Request.Context(), name)
+	if err != nil {
+		switch err {
+		case domain.ErrNamespaceProtected:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case domain.ErrNamespaceNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case domain.ErrTimeout, domain.ErrCanceled:
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to drop namespace", "namespace", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, namespace)
+}
@@ -3,9 +3,13 @@ package http
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"kv-storage/internal/config"
+	"kv-storage/internal/events"
 	"kv-storage/internal/interfaces"
+	"kv-storage/internal/metrics"
+	"kv-storage/internal/rbac"
 	"kv-storage/internal/service"
 	"kv-storage/internal/transport/http/middleware"
 
@@ -15,32 +19,61 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+const defaultMetricsPath = "/metrics"
+
+// watchRoutes — потоки изменений живут, пока подключён клиент, и срок на них не действует
+var watchRoutes = []string{
+	"GET /api/v1/kv/_watch",
+	"GET /api/v1/ns/:namespace/kv/_watch",
+}
+
 type Router struct {
-	engine  *gin.Engine
-	server  *http.Server
-	logger  interfaces.Logger
-	config  *config.Config
-	service *service.KVService
+	engine     *gin.Engine
+	server     *http.Server
+	logger     interfaces.Logger
+	config     *config.Config
+	service    *service.KVService
+	locks      *service.LockService
+	namespaces *service.NamespaceService
+	health     *service.HealthService
+	broker     *events.Broker
+	auth       *middleware.Authenticator
+	policy     *rbac.Policy
+	metrics    *metrics.Metrics
 }
 
-func NewRouter(cfg *config.Config, logger interfaces.Logger, kvService *service.KVService) interfaces.Router {
+func NewRouter(cfg *config.Config, logger interfaces.Logger, kvService *service.KVService, lockService *service.LockService, namespaceService *service.NamespaceService, healthService *service.HealthService, broker *events.Broker, auth *middleware.Authenticator, policy *rbac.Policy, metrics *metrics.Metrics) interfaces.Router {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
 
-	rateLimiter := middleware.NewRateLimiter(100, 200, logger)
+	rateLimiter := middleware.NewRateLimiter(100, 200, metrics, logger)
+
+	// Метрики подключаются первыми, чтобы учесть ответы Recovery и отказы rate limiter
+	if metrics != nil {
+		engine.Use(middleware.Metrics(metrics))
+	}
 
 	engine.Use(
+		middleware.Tracing(),
 		gin.Recovery(),
 		middleware.Logger(logger),
 		cors.Default(),
 		rateLimiter.RateLimit(),
+		middleware.Deadline(cfg.HTTPServer.RequestTimeout, routeTimeouts(cfg.HTTPServer.RouteTimeouts)),
 	)
 
 	router := &Router{
-		engine:  engine,
-		logger:  logger,
-		config:  cfg,
-		service: kvService,
+		engine:     engine,
+		logger:     logger,
+		config:     cfg,
+		service:    kvService,
+		locks:      lockService,
+		namespaces: namespaceService,
+		health:     healthService,
+		broker:     broker,
+		auth:       auth,
+		policy:     policy,
+		metrics:    metrics,
 	}
 
 	router.setupRoutes()
@@ -57,27 +90,90 @@ func NewRouter(cfg *config.Config, logger interfaces.Logger, kvService *service.
 
 func (r *Router) setupRoutes() {
 	api := r.engine.Group("/api/v1")
+	// auth равен nil, если аутентификация выключена в конфигурации
+	if r.auth != nil {
+		api.Use(r.auth.Authenticate())
+	}
+	api.Use(middleware.Consistency())
 	{
-		kv := api.Group("/kv")
+		handler := NewHandler(r.service, r.broker, r.policy, r.logger)
+
+		namespaceHandler := NewNamespaceHandler(r.namespaces, r.policy, r.logger)
+
+		registerKVRoutes(api.Group("/kv"), handler)
+		api.GET("/changes", handler.Changes)
+
+		// Те же маршруты внутри namespace; /kv и /changes работают в namespace default
+		ns := api.Group("/ns/:namespace")
+		{
+			registerKVRoutes(ns.Group("/kv"), handler)
+			ns.GET("/changes", handler.Changes)
+			ns.GET("/stats", namespaceHandler.Stats)
+		}
+
+		admin := api.Group("/admin")
 		{
-			handler := NewHandler(r.service, r.logger)
-			kv.POST("", handler.Create)
-			kv.GET("", handler.List)
-			kv.GET("/all", handler.ListIncludingDeleted)
-			kv.GET("/:key", handler.Get)
-			kv.PUT("/:key", handler.Update)
-			kv.DELETE("/:key", handler.Delete)
-			kv.POST("/:key/restore", handler.Restore)
+			admin.POST("/namespaces", namespaceHandler.Create)
+			admin.GET("/namespaces", namespaceHandler.List)
+			admin.GET("/namespaces/:namespace", namespaceHandler.Get)
+			admin.DELETE("/namespaces/:namespace", namespaceHandler.Drop)
+		}
+
+		lockHandler := NewLockHandler(r.locks, r.policy, r.logger)
+
+		locks := api.Group("/locks")
+		{
+			locks.GET("/:name", lockHandler.Get)
+			locks.POST("/:name", lockHandler.Acquire)
+			locks.PUT("/:name", lockHandler.Renew)
+			locks.DELETE("/:name", lockHandler.Release)
 		}
 	}
 
-	r.engine.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	// Пробы открыты и не проходят аутентификацию; /health оставлен для старых клиентов
+	healthHandler := NewHealthHandler(r.health, r.logger)
+	r.engine.GET("/livez", healthHandler.Livez)
+	r.engine.GET("/health", healthHandler.Livez)
+	r.engine.GET("/readyz", healthHandler.Readyz)
+
+	if r.metrics != nil {
+		path := r.config.Metrics.Path
+		if path == "" {
+			path = defaultMetricsPath
+		}
+		r.engine.GET(path, gin.WrapH(r.metrics.Handler()))
+	}
 
 	r.engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
+func routeTimeouts(configured map[string]time.Duration) map[string]time.Duration {
+	routes := make(map[string]time.Duration, len(configured)+len(watchRoutes))
+	for _, route := range watchRoutes {
+		routes[route] = 0
+	}
+	for route, timeout := range configured {
+		routes[route] = timeout
+	}
+	return routes
+}
+
+func registerKVRoutes(kv *gin.RouterGroup, handler *Handler) {
+	kv.POST("", handler.Create)
+	kv.POST("/_batch", handler.Batch)
+	kv.GET("/_watch", handler.Watch)
+	kv.GET("", handler.List)
+	kv.GET("/all", handler.ListIncludingDeleted)
+	kv.GET("/:key", handler.Get)
+	kv.PUT("/:key", handler.Update)
+	kv.DELETE("/:key", handler.Delete)
+	kv.POST("/:key/restore", handler.Restore)
+	kv.POST("/:key/incr", handler.Increment)
+	kv.POST("/:key/cas", handler.CompareAndSwap)
+	kv.GET("/:key/history", handler.History)
+	kv.POST("/:key/revert", handler.Revert)
+}
+
 func (r *Router) Run(addr string) error {
 	r.logger.Info("Starting HTTP server", "addr", addr)
 	return r.server.ListenAndServe()
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/events"
+	"kv-storage/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const watchHeartbeatInterval = 15 * time.Second
+
+var upgrader = websocket.Upgrader{
+	// CORS открыт для всех источников, поэтому и WebSocket принимается с любого Origin
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Watch godoc
+// @Summary Watch key changes
+// @Description Stream change events as Server-Sent Events, or as JSON messages when the request is a WebSocket upgrade. Pass Last-Event-ID (header or last_event_id query) to resume after a reconnect
+// @Tags kv
+// @Produce text/event-stream
+// @Param prefix query string false "Only stream changes of keys with this prefix"
+// @Param Last-Event-ID header string false "ID of the last received event"
+// @Param last_event_id query string false "ID of the last received event"
+// @Success 200 {object} domain.ChangeEvent
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/kv/_watch [get]
+func (h *Handler) Watch(c *gin.Context) {
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("last_event_id")
+	}
+
+	var lastEventID uint64
+	if lastEventIDStr != "" {
+		id, err := strconv.ParseUint(lastEventIDStr, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = id
+	}
+
+	if !h.authorizePrefix(c, rbac.ActionRead, c.Query("prefix")) {
+		return
+	}
+
+	sub, replay, err := h.broker.Subscribe(namespaceParam(c), c.Query("prefix"), lastEventID)
+	if err != nil {
+		switch err {
+		case domain.ErrEventsExpired:
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		case events.ErrClosed:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to subscribe to changes", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	defer sub.Close()
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		h.watchWebSocket(c, sub, replay)
+		return
+	}
+	h.watchSSE(c, sub, replay)
+}
+
+func (h *Handler) watchSSE(c *gin.Context, sub *events.Subscription, replay []domain.ChangeEvent) {
+	// Поток живёт дольше WriteTimeout сервера, поэтому дедлайн записи снимается
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Warn("Failed to reset write deadline for change feed", "error", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for _, event := range replay {
+		if err := writeSSE(c, event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := writeSSE(c, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeSSE(c *gin.Context, event domain.ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Op, data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+func (h *Handler) watchWebSocket(c *gin.Context, sub *events.Subscription, replay []domain.ChangeEvent) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Warn("Failed to upgrade change feed to WebSocket", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// Входящие сообщения не ожидаются: чтение нужно только чтобы заметить закрытие соединения
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range replay {
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+					time.Now().Add(time.Second))
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
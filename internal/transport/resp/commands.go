@@ -0,0 +1,665 @@
+package resp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"kv-storage/internal/domain"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+)
+
+const (
+	defaultScanCount = 10
+	maxScanCount     = 100
+	// Курсоры SCAN хранятся в соединении; старые вытесняются, чтобы брошенные обходы не копились
+	maxScanCursors = 64
+)
+
+// session — состояние одного соединения
+type session struct {
+	ctx       context.Context
+	server    *Server
+	kv        *service.KVService
+	reader    *reader
+	writer    *writer
+	remote    string
+	principal *domain.Principal
+
+	// Клиенты Redis ожидают числовой курсор, поэтому непрозрачные курсоры
+	// KVService хранятся здесь под порядковыми номерами
+	cursors    map[uint64]string
+	nextCursor uint64
+}
+
+// Клиенты Redis рассчитывают прочитать только что записанное, поэтому по умолчанию
+// чтения идут на мастер; READONLY разрешает читать с реплик, как в Redis Cluster
+func newSession(server *Server, conn net.Conn) *session {
+	return &session{
+		ctx:     domain.WithConsistency(context.Background(), domain.ConsistencyStrong),
+		server:  server,
+		kv:      server.service.Namespace(domain.DefaultNamespace),
+		reader:  newReader(conn),
+		writer:  newWriter(conn),
+		remote:  conn.RemoteAddr().String(),
+		cursors: make(map[uint64]string),
+	}
+}
+
+// execute выполняет команду и возвращает true, если соединение нужно закрыть
+func (s *session) execute(args [][]byte) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name := strings.ToUpper(string(args[0]))
+	args = args[1:]
+
+	switch name {
+	case "HELLO":
+		s.hello(args)
+		return false
+	case "AUTH":
+		s.authenticate(args)
+		return false
+	case "QUIT":
+		s.writer.SimpleString("OK")
+		return true
+	}
+
+	if s.server.auth != nil && s.principal == nil {
+		s.writer.Error("NOAUTH Authentication required.")
+		return false
+	}
+
+	switch name {
+	case "PING":
+		s.ping(args)
+	case "GET":
+		s.get(args)
+	case "SET":
+		s.set(args)
+	case "DEL":
+		s.del(args)
+	case "EXISTS":
+		s.exists(args)
+	case "INCR":
+		s.incr(args)
+	case "TTL":
+		s.ttl(args)
+	case "SCAN":
+		s.scan(args)
+	case "SELECT":
+		s.selectDB(args)
+	case "CLIENT":
+		s.client(args)
+	case "READONLY":
+		s.consistency(args, "readonly", domain.ConsistencyEventual)
+	case "READWRITE":
+		s.consistency(args, "readwrite", domain.ConsistencyStrong)
+	case "COMMAND":
+		// redis-cli запрашивает описание команд при подключении
+		s.writer.ArrayHeader(0)
+	default:
+		s.writer.Error("ERR unknown command '" + strings.ToLower(name) + "'")
+	}
+	return false
+}
+
+// hello переключает протокол (HELLO 2|3) и может аутентифицировать: HELLO 3 AUTH user pass
+func (s *session) hello(args [][]byte) {
+	proto := s.writer.proto
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			s.writer.Error("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if version != 2 && version != 3 {
+			s.writer.Error("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = version
+		args = args[1:]
+	}
+
+	for len(args) > 0 {
+		switch strings.ToUpper(string(args[0])) {
+		case "AUTH":
+			if len(args) < 3 {
+				s.writer.Error("ERR syntax error")
+				return
+			}
+			if !s.login(string(args[2])) {
+				return
+			}
+			args = args[3:]
+		case "SETNAME":
+			if len(args) < 2 {
+				s.writer.Error("ERR syntax error")
+				return
+			}
+			args = args[2:]
+		default:
+			s.writer.Error("ERR syntax error")
+			return
+		}
+	}
+
+	if s.server.auth != nil && s.principal == nil {
+		s.writer.Error("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+		return
+	}
+
+	s.writer.proto = proto
+	s.writer.MapHeader(4)
+	s.writer.BulkString("server")
+	s.writer.BulkString("kv-storage")
+	s.writer.BulkString("proto")
+	s.writer.Integer(int64(proto))
+	s.writer.BulkString("mode")
+	s.writer.BulkString("standalone")
+	s.writer.BulkString("role")
+	s.writer.BulkString("master")
+}
+
+// authenticate обрабатывает AUTH [user] password: пароль — API-ключ или JWT, имя игнорируется
+func (s *session) authenticate(args [][]byte) {
+	if len(args) != 1 && len(args) != 2 {
+		s.wrongArgs("auth")
+		return
+	}
+	if s.server.auth == nil {
+		s.writer.Error("ERR AUTH called without any password configured for the default user. Are you sure your configuration is correct?")
+		return
+	}
+
+	if s.login(string(args[len(args)-1])) {
+		s.writer.SimpleString("OK")
+	}
+}
+
+func (s *session) login(password string) bool {
+	if s.server.auth == nil {
+		return true
+	}
+
+	principal, err := s.server.auth.Verify(password, "")
+	// Пароль вида header.payload.signature проверяется как JWT
+	if err != nil && strings.Count(password, ".") == 2 {
+		principal, err = s.server.auth.Verify("", "Bearer "+password)
+	}
+	if err != nil {
+		s.server.logger.Warn("RESP authentication failed", "remote", s.remote, "reason", err)
+		s.writer.Error("WRONGPASS invalid username-password pair or user is disabled.")
+		return false
+	}
+
+	s.principal = principal
+	return true
+}
+
+func (s *session) ping(args [][]byte) {
+	switch len(args) {
+	case 0:
+		s.writer.SimpleString("PONG")
+	case 1:
+		s.writer.Bulk(args[0])
+	default:
+		s.wrongArgs("ping")
+	}
+}
+
+func (s *session) get(args [][]byte) {
+	if len(args) != 1 {
+		s.wrongArgs("get")
+		return
+	}
+	key := string(args[0])
+	if !s.authorize(rbac.ActionRead, key, false) {
+		return
+	}
+
+	kv, err := s.kv.Get(s.ctx, key)
+	switch err {
+	case nil:
+		s.writer.Bulk(encodeValue(kv.Value))
+	case domain.ErrKeyNotFound:
+		s.writer.Null()
+	default:
+		s.fail("Failed to get KV", key, err)
+	}
+}
+
+// set поддерживает SET key value [NX|XX] [EX seconds|PX milliseconds|KEEPTTL].
+// Как в Redis, без EX, PX и KEEPTTL срок жизни существующего ключа снимается.
+func (s *session) set(args [][]byte) {
+	if len(args) < 2 {
+		s.wrongArgs("set")
+		return
+	}
+	key := string(args[0])
+
+	req := &domain.UpdateKVRequest{}
+	req.Value, req.ContentType = decodeValue(args[1])
+
+	var nx, xx, keepTTL bool
+	for i := 2; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch {
+		case option == "NX" && !xx:
+			nx = true
+		case option == "XX" && !nx:
+			xx = true
+		case option == "KEEPTTL" && req.TTL == 0 && req.ExpiresAt == nil:
+			keepTTL = true
+		case (option == "EX" || option == "PX") && i+1 < len(args) && !keepTTL && req.TTL == 0 && req.ExpiresAt == nil:
+			i++
+			n, err := strconv.ParseInt(string(args[i]), 10, 64)
+			if err != nil || n <= 0 {
+				s.writer.Error("ERR invalid expire time in 'set' command")
+				return
+			}
+			if option == "EX" {
+				req.TTL = n
+			} else {
+				at := time.Now().Add(time.Duration(n) * time.Millisecond)
+				req.ExpiresAt = &at
+			}
+		default:
+			s.writer.Error("ERR syntax error")
+			return
+		}
+	}
+
+	if !s.authorize(rbac.ActionWrite, key, false) {
+		return
+	}
+	req.Persist = !keepTTL && req.TTL == 0 && req.ExpiresAt == nil
+
+	var err error
+	switch {
+	case nx:
+		_, err = s.kv.PutIfAbsent(s.ctx, key, req)
+	case xx:
+		_, err = s.kv.Update(s.ctx, key, req)
+	default:
+		err = s.upsert(key, req)
+	}
+
+	switch {
+	case err == nil:
+		s.writer.SimpleString("OK")
+	case nx && err == domain.ErrKeyAlreadyExists, xx && err == domain.ErrKeyNotFound:
+		s.writer.Null()
+	case err == domain.ErrInvalidValue:
+		s.writer.Error("ERR empty values are not supported")
+	default:
+		s.fail("Failed to set KV", key, err)
+	}
+}
+
+// upsert обновляет ключ или создаёт его, если ключа нет; гонку с параллельным
+// созданием разрешает повторное обновление
+func (s *session) upsert(key string, req *domain.UpdateKVRequest) error {
+	_, err := s.kv.Update(s.ctx, key, req)
+	if err != domain.ErrKeyNotFound {
+		return err
+	}
+
+	_, err = s.kv.PutIfAbsent(s.ctx, key, req)
+	if err != domain.ErrKeyAlreadyExists {
+		return err
+	}
+
+	_, err = s.kv.Update(s.ctx, key, req)
+	return err
+}
+
+func (s *session) del(args [][]byte) {
+	if len(args) == 0 {
+		s.wrongArgs("del")
+		return
+	}
+	for _, arg := range args {
+		if !s.authorize(rbac.ActionHardDelete, string(arg), false) {
+			return
+		}
+	}
+
+	var deleted int64
+	for _, arg := range args {
+		key := string(arg)
+		_, err := s.kv.Delete(s.ctx, key, 0)
+		switch err {
+		case nil:
+			deleted++
+		case domain.ErrKeyNotFound:
+		default:
+			s.fail("Failed to delete KV", key, err)
+			return
+		}
+	}
+	s.writer.Integer(deleted)
+}
+
+func (s *session) exists(args [][]byte) {
+	if len(args) == 0 {
+		s.wrongArgs("exists")
+		return
+	}
+	for _, arg := range args {
+		if !s.authorize(rbac.ActionRead, string(arg), false) {
+			return
+		}
+	}
+
+	var count int64
+	for _, arg := range args {
+		key := string(arg)
+		_, err := s.kv.Get(s.ctx, key)
+		switch err {
+		case nil:
+			count++
+		case domain.ErrKeyNotFound:
+		default:
+			s.fail("Failed to get KV", key, err)
+			return
+		}
+	}
+	s.writer.Integer(count)
+}
+
+func (s *session) incr(args [][]byte) {
+	if len(args) != 1 {
+		s.wrongArgs("incr")
+		return
+	}
+	key := string(args[0])
+	if !s.authorize(rbac.ActionWrite, key, false) {
+		return
+	}
+
+	kv, err := s.kv.Increment(s.ctx, key, &domain.IncrementRequest{})
+	if err != nil {
+		switch err {
+		case domain.ErrNotNumeric:
+			s.writer.Error("ERR value is not an integer or out of range")
+		default:
+			s.fail("Failed to increment KV", key, err)
+		}
+		return
+	}
+
+	n, ok := toInt64(kv.Value)
+	if !ok {
+		s.writer.Error("ERR value is not an integer or out of range")
+		return
+	}
+	s.writer.Integer(n)
+}
+
+// ttl возвращает оставшийся срок в секундах, -1 для ключа без срока и -2 для отсутствующего
+func (s *session) ttl(args [][]byte) {
+	if len(args) != 1 {
+		s.wrongArgs("ttl")
+		return
+	}
+	key := string(args[0])
+	if !s.authorize(rbac.ActionRead, key, false) {
+		return
+	}
+
+	kv, err := s.kv.Get(s.ctx, key)
+	switch {
+	case err == domain.ErrKeyNotFound:
+		s.writer.Integer(-2)
+	case err != nil:
+		s.fail("Failed to get KV", key, err)
+	case kv.ExpiresAt == nil:
+		s.writer.Integer(-1)
+	default:
+		remaining := time.Until(*kv.ExpiresAt)
+		if remaining < 0 {
+			remaining = 0
+		}
+		s.writer.Integer(int64(math.Round(remaining.Seconds())))
+	}
+}
+
+// scan реализует SCAN cursor [MATCH pattern] [COUNT count] поверх постраничного списка.
+// Литеральное начало MATCH выбирается диапазоном ключей, остаток шаблона
+// проверяется на стороне сервера, поэтому страница может оказаться пустой.
+func (s *session) scan(args [][]byte) {
+	if len(args) == 0 {
+		s.wrongArgs("scan")
+		return
+	}
+
+	cursorID, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		s.writer.Error("ERR invalid cursor")
+		return
+	}
+	var cursor string
+	if cursorID != 0 {
+		var ok bool
+		if cursor, ok = s.cursors[cursorID]; !ok {
+			s.writer.Error("ERR invalid cursor")
+			return
+		}
+		delete(s.cursors, cursorID)
+	}
+
+	pattern := "*"
+	count := defaultScanCount
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			s.writer.Error("ERR syntax error")
+			return
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "MATCH":
+			pattern = string(args[i+1])
+		case "COUNT":
+			n, err := strconv.Atoi(string(args[i+1]))
+			if err != nil || n < 1 {
+				s.writer.Error("ERR value is not an integer or out of range")
+				return
+			}
+			count = n
+		case "TYPE":
+			// Все значения хранилища — строки
+			if !strings.EqualFold(string(args[i+1]), "string") {
+				s.writer.ArrayHeader(2)
+				s.writer.BulkString("0")
+				s.writer.ArrayHeader(0)
+				return
+			}
+		default:
+			s.writer.Error("ERR syntax error")
+			return
+		}
+	}
+	if count > maxScanCount {
+		count = maxScanCount
+	}
+
+	prefix := literalPrefix(pattern)
+	if !s.authorize(rbac.ActionRead, prefix, true) {
+		return
+	}
+
+	var page *domain.ListKVResponse
+	if prefix != "" {
+		page, err = s.kv.Scan(s.ctx, &domain.ScanKVRequest{Prefix: prefix, Limit: count, Cursor: cursor})
+	} else {
+		page, err = s.kv.List(s.ctx, &domain.ListKVRequest{Limit: count, Cursor: cursor, SkipCount: true})
+	}
+	if err != nil {
+		s.fail("Failed to scan KV", prefix, err)
+		return
+	}
+
+	keys := make([]string, 0, len(page.Items))
+	for _, kv := range page.Items {
+		if matchPattern(pattern, kv.Key) {
+			keys = append(keys, kv.Key)
+		}
+	}
+
+	next := "0"
+	if page.Cursor != "" {
+		next = strconv.FormatUint(s.saveCursor(page.Cursor), 10)
+	}
+
+	s.writer.ArrayHeader(2)
+	s.writer.BulkString(next)
+	s.writer.ArrayHeader(len(keys))
+	for _, key := range keys {
+		s.writer.BulkString(key)
+	}
+}
+
+func (s *session) saveCursor(cursor string) uint64 {
+	if len(s.cursors) >= maxScanCursors {
+		oldest := uint64(math.MaxUint64)
+		for id := range s.cursors {
+			if id < oldest {
+				oldest = id
+			}
+		}
+		delete(s.cursors, oldest)
+	}
+
+	s.nextCursor++
+	s.cursors[s.nextCursor] = cursor
+	return s.nextCursor
+}
+
+// selectDB принимает только базу 0: клиенты выбирают её при подключении
+func (s *session) selectDB(args [][]byte) {
+	if len(args) != 1 {
+		s.wrongArgs("select")
+		return
+	}
+	if string(args[0]) != "0" {
+		s.writer.Error("ERR DB index is out of range")
+		return
+	}
+	s.writer.SimpleString("OK")
+}
+
+// client отвечает OK на CLIENT SETNAME и SETINFO, которые клиенты отправляют при подключении
+func (s *session) client(args [][]byte) {
+	if len(args) == 0 {
+		s.wrongArgs("client")
+		return
+	}
+	switch strings.ToUpper(string(args[0])) {
+	case "SETNAME", "SETINFO":
+		s.writer.SimpleString("OK")
+	default:
+		s.writer.Error("ERR unknown subcommand '" + string(args[0]) + "'")
+	}
+}
+
+// consistency переключает чтения соединения между репликами (READONLY) и мастером (READWRITE)
+func (s *session) consistency(args [][]byte, command string, consistency domain.Consistency) {
+	if len(args) != 0 {
+		s.wrongArgs(command)
+		return
+	}
+	s.ctx = domain.WithConsistency(s.ctx, consistency)
+	s.writer.SimpleString("OK")
+}
+
+func (s *session) authorize(action rbac.Action, key string, prefix bool) bool {
+	err := s.server.policy.Authorize(rbac.Request{
+		Principal: s.principal,
+		Action:    action,
+		Namespace: domain.DefaultNamespace,
+		Key:       key,
+		Prefix:    prefix,
+	})
+	if err == nil {
+		return true
+	}
+
+	var denied *rbac.DeniedError
+	if errors.As(err, &denied) {
+		s.server.logger.Warn("Access denied", "remote", s.remote, "action", action, "reason", denied.Reason)
+		s.writer.Error("NOPERM " + denied.Reason)
+		return false
+	}
+
+	s.fail("Failed to authorize request", key, err)
+	return false
+}
+
+func (s *session) fail(message, key string, err error) {
+	switch err {
+	case domain.ErrInvalidKey:
+		s.writer.Error("ERR invalid key")
+	default:
+		s.server.logger.Error(message, "key", key, "error", err)
+		s.writer.Error("ERR internal error")
+	}
+}
+
+func (s *session) wrongArgs(command string) {
+	s.writer.Error("ERR wrong number of arguments for '" + command + "' command")
+}
+
+// decodeValue выбирает представление строки Redis в хранилище: целое число, чтобы
+// работал INCR, текст как JSON-строку, остальное как бинарное значение
+func decodeValue(data []byte) (interface{}, string) {
+	s := string(data)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+		return n, domain.ContentTypeJSON
+	}
+	if utf8.Valid(data) {
+		return s, domain.ContentTypeJSON
+	}
+	return data, domain.ContentTypeBinary
+}
+
+// encodeValue возвращает строку Redis для значения: текст и бинарные данные как есть,
+// числа в десятичной записи, объекты и массивы в JSON
+func encodeValue(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(v), true
+	case float64:
+		return int64(v), v == math.Trunc(v)
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package resp
+
+// matchPattern сопоставляет ключ с шаблоном MATCH в синтаксисе Redis:
+// "*", "?", классы "[abc]", "[^a]", "[a-z]" и экранирование "\"
+func matchPattern(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			matched, rest, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				// Незакрытая "[" сравнивается как обычный символ
+				if s[0] != '[' {
+					return false
+				}
+				pattern = pattern[1:]
+			} else {
+				if !matched {
+					return false
+				}
+				pattern = rest
+			}
+			s = s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass проверяет символ c по классу, начинающемуся после "[", и возвращает
+// остаток шаблона после "]"; ok=false, если класс не закрыт
+func matchClass(class string, c byte) (matched bool, rest string, ok bool) {
+	negate := false
+	if len(class) > 0 && class[0] == '^' {
+		negate = true
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		switch {
+		case class[i] == ']' && i > 0:
+			return matched != negate, class[i+1:], true
+		case class[i] == '\\' && i+1 < len(class):
+			i++
+			if class[i] == c {
+				matched = true
+			}
+		case i+2 < len(class) && class[i+1] == '-' && class[i+2] != ']':
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+		case class[i] == c:
+			matched = true
+		}
+	}
+	return false, "", false
+}
+
+// literalPrefix возвращает начало шаблона до первого спецсимвола: по нему
+// SCAN выбирает ключи диапазоном первичного индекса, а не перебором всех ключей
+func literalPrefix(pattern string) string {
+	prefix := make([]byte, 0, len(pattern))
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*', '?', '[':
+			return string(prefix)
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+		}
+		prefix = append(prefix, pattern[i])
+	}
+	return string(prefix)
+}
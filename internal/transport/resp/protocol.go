@@ -0,0 +1,194 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Ограничения защищают сервер от клиентов, объявляющих огромные массивы и строки
+	maxArgs       = 1024 * 1024
+	maxBulkLength = 64 << 20
+	maxInlineSize = 64 << 10
+)
+
+var errProtocol = errors.New("Protocol error")
+
+// reader разбирает команды клиента: массивы bulk-строк RESP и inline-команды,
+// которые отправляют telnet и redis-cli в режиме без протокола
+type reader struct {
+	r *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// ReadCommand возвращает аргументы очередной команды; пустые строки и строки из одних
+// пробелов пропускаются
+func (r *reader) ReadCommand() ([][]byte, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		if line[0] != '*' {
+			if args := inlineArgs(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+
+		args := make([][]byte, 0, n)
+		for i := 0; i < n; i++ {
+			arg, err := r.readBulk()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return args, nil
+	}
+}
+
+// Buffered сообщает, есть ли уже прочитанные, но не разобранные команды (конвейер)
+func (r *reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+func (r *reader) readBulk() ([]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return nil, fmt.Errorf("%w: expected '$'", errProtocol)
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n < 0 || n > maxBulkLength {
+		return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	data := make([]byte, n+2)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, err
+	}
+	if data[n] != '\r' || data[n+1] != '\n' {
+		return nil, fmt.Errorf("%w: expected CRLF after bulk", errProtocol)
+	}
+	return data[:n], nil
+}
+
+func (r *reader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxInlineSize {
+			return nil, fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+func inlineArgs(line []byte) [][]byte {
+	fields := strings.Fields(string(line))
+	args := make([][]byte, len(fields))
+	for i, field := range fields {
+		args[i] = []byte(field)
+	}
+	return args
+}
+
+// writer формирует ответы RESP2 или RESP3; версия переключается командой HELLO.
+// Различаются только null и словари: в RESP2 словарь передаётся плоским массивом.
+type writer struct {
+	w     *bufio.Writer
+	proto int
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w), proto: 2}
+}
+
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *writer) SimpleString(s string) {
+	w.w.WriteByte('+')
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
+
+// Error пишет ошибку; сообщение начинается с кода (ERR, WRONGTYPE, NOAUTH...)
+func (w *writer) Error(message string) {
+	w.w.WriteByte('-')
+	w.w.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(message))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Integer(n int64) {
+	w.w.WriteByte(':')
+	w.w.WriteString(strconv.FormatInt(n, 10))
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) Bulk(data []byte) {
+	w.w.WriteByte('$')
+	w.w.WriteString(strconv.Itoa(len(data)))
+	w.w.WriteString("\r\n")
+	w.w.Write(data)
+	w.w.WriteString("\r\n")
+}
+
+func (w *writer) BulkString(s string) {
+	w.Bulk([]byte(s))
+}
+
+func (w *writer) Null() {
+	if w.proto >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+func (w *writer) ArrayHeader(n int) {
+	w.w.WriteByte('*')
+	w.w.WriteString(strconv.Itoa(n))
+	w.w.WriteString("\r\n")
+}
+
+// MapHeader начинает словарь из n пар; в RESP2 это массив из 2n элементов
+func (w *writer) MapHeader(n int) {
+	if w.proto >= 3 {
+		w.w.WriteByte('%')
+		w.w.WriteString(strconv.Itoa(n))
+		w.w.WriteString("\r\n")
+		return
+	}
+	w.ArrayHeader(2 * n)
+}
@@ -0,0 +1,134 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"kv-storage/internal/domain"
+)
+
+func TestReadCommand(t *testing.T) {
+	input := "*3\r\n$3\r\nSET\r\n$4\r\nuser\r\n$5\r\na\r\nbc\r\n" +
+		"\r\n" +
+		" \t \r\n" +
+		"PING  hello\r\n"
+	r := newReader(strings.NewReader(input))
+
+	args, err := r.ReadCommand()
+	if err != nil {
+		t.Fatalf("ReadCommand() error = %v", err)
+	}
+	if got := joinArgs(args); got != "SET|user|a\r\nbc" {
+		t.Errorf("array command = %q", got)
+	}
+
+	args, err = r.ReadCommand()
+	if err != nil {
+		t.Fatalf("ReadCommand() error = %v", err)
+	}
+	if got := joinArgs(args); got != "PING|hello" {
+		t.Errorf("inline command = %q", got)
+	}
+}
+
+func TestReadCommandProtocolErrors(t *testing.T) {
+	inputs := []string{
+		"*x\r\n",
+		"*1\r\n+OK\r\n",
+		"*1\r\n$-1\r\n",
+		"*1\r\n$3\r\nGETX\r\n",
+	}
+
+	for _, input := range inputs {
+		_, err := newReader(strings.NewReader(input)).ReadCommand()
+		if !errors.Is(err, errProtocol) {
+			t.Errorf("ReadCommand(%q) error = %v, want protocol error", input, err)
+		}
+	}
+}
+
+func TestWriterProtocols(t *testing.T) {
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+
+	w.Null()
+	w.MapHeader(1)
+	w.BulkString("a")
+	w.Integer(1)
+	w.Flush()
+	if got, want := buf.String(), "$-1\r\n*2\r\n$1\r\na\r\n:1\r\n"; got != want {
+		t.Errorf("RESP2 output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	w.proto = 3
+	w.Null()
+	w.MapHeader(1)
+	w.Flush()
+	if got, want := buf.String(), "_\r\n%1\r\n"; got != want {
+		t.Errorf("RESP3 output = %q, want %q", got, want)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"user:*", "user:1", true},
+		{"user:*", "users:1", false},
+		{"user:?", "user:12", false},
+		{"user:[0-9]", "user:7", true},
+		{"user:[^0-9]", "user:7", false},
+		{"user:[abc]", "user:b", true},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"*:*:end", "a:b:end", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := map[string]string{
+		"*":          "",
+		"user:*":     "user:",
+		"user:?0":    "user:",
+		"user:[ab]*": "user:",
+		`a\*b*`:      "a*b",
+		"exact":      "exact",
+	}
+
+	for pattern, want := range tests {
+		if got := literalPrefix(pattern); got != want {
+			t.Errorf("literalPrefix(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	if value, _ := decodeValue([]byte("42")); value != int64(42) {
+		t.Errorf("decodeValue(42) = %#v, want int64", value)
+	}
+	if value, _ := decodeValue([]byte("042")); value != "042" {
+		t.Errorf("decodeValue(042) = %#v, want string", value)
+	}
+	if _, contentType := decodeValue([]byte{0xff, 0x00}); contentType != domain.ContentTypeBinary {
+		t.Errorf("decodeValue(binary) content type = %q", contentType)
+	}
+}
+
+func joinArgs(args [][]byte) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = string(arg)
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,170 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/rbac"
+	"kv-storage/internal/service"
+	"kv-storage/internal/transport/http/middleware"
+)
+
+// Server — TCP-listener протокола Redis (RESP2/RESP3) поверх KVService.
+// Все команды выполняются в namespace default.
+type Server struct {
+	service *service.KVService
+	auth    *middleware.Authenticator
+	policy  *rbac.Policy
+	logger  interfaces.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewServer(cfg *config.Config, logger interfaces.Logger, kvService *service.KVService, auth *middleware.Authenticator, policy *rbac.Policy) interfaces.Router {
+	return &Server{
+		service: kvService,
+		auth:    auth,
+		policy:  policy,
+		logger:  logger,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+func (s *Server) Run(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return nil
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	s.logger.Info("Starting RESP server", "addr", addr)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		if !s.track(conn) {
+			conn.Close()
+			return nil
+		}
+		go s.serve(conn)
+	}
+}
+
+// Shutdown перестаёт принимать соединения и прерывает ожидание следующей команды;
+// выполняемые команды завершаются и успевают отправить ответ
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down RESP server")
+
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.untrack(conn)
+	defer conn.Close()
+
+	session := newSession(s, conn)
+	for {
+		args, err := session.reader.ReadCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				session.writer.Error("ERR " + err.Error())
+				session.writer.Flush()
+			} else if !errors.Is(err, io.EOF) && !s.isClosed() {
+				s.logger.Debug("RESP connection closed", "remote", conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+
+		quit := session.execute(args)
+
+		// Ответы на конвейер команд отправляются одной записью
+		if session.reader.Buffered() == 0 || quit {
+			if err := session.writer.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrack(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
@@ -0,0 +1,123 @@
+//go:build integration
+
+package resp
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"kv-storage/internal/config"
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/repository"
+)
+
+// Integration-тесты выполняются против Tarantool с загруженным init.lua:
+//
+//	TARANTOOL_HOST=localhost TARANTOOL_PORT=3301 go test -tags integration ./internal/...
+func newIntegrationRepository(t *testing.T) interfaces.KVRepository {
+	t.Helper()
+
+	port, err := strconv.Atoi(integrationEnv("TARANTOOL_PORT", "3301"))
+	if err != nil {
+		t.Fatalf("invalid TARANTOOL_PORT: %v", err)
+	}
+	cfg := &config.Config{Tarantool: config.TarantoolConfig{
+		Username: integrationEnv("TARANTOOL_USERNAME", "admin"),
+		Password: integrationEnv("TARANTOOL_PASSWORD", "admin"),
+		Timeout:  5 * time.Second,
+		Pool:     config.PoolConfig{MinSize: 1, MaxSize: 4},
+	}}
+	cluster := repository.NewCluster(cfg, []config.InstanceConfig{{
+		Host: integrationEnv("TARANTOOL_HOST", "localhost"),
+		Port: port,
+		Role: repository.RoleMaster,
+	}}, nopLogger{})
+
+	repo := repository.NewTarantoolRepository(cluster, cfg, nil, nopLogger{})
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func integrationEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// После DEL ключ отсутствует для всех команд, в том числе для SET NX и INCR
+func TestIntegration_WriteAfterDel(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	key := "it:resp:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "SET",
+			input: "SET " + key + ":set a\r\nDEL " + key + ":set\r\nSET " + key + ":set b\r\nGET " + key + ":set\r\n",
+			want:  "+OK\r\n:1\r\n+OK\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "SET NX",
+			input: "SET " + key + ":nx a\r\nDEL " + key + ":nx\r\nEXISTS " + key + ":nx\r\nSET " + key + ":nx b NX\r\nGET " + key + ":nx\r\n",
+			want:  "+OK\r\n:1\r\n:0\r\n+OK\r\n$1\r\nb\r\n",
+		},
+		{
+			name:  "INCR",
+			input: "INCR " + key + ":incr\r\nINCR " + key + ":incr\r\nDEL " + key + ":incr\r\nINCR " + key + ":incr\r\n",
+			want:  ":1\r\n:2\r\n:1\r\n:1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runSession(t, repo, tt.input)
+			if want := tt.want + "+OK\r\n"; got != want {
+				t.Errorf("replies = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// SET без EX, PX и KEEPTTL снимает срок жизни ключа, как в Redis
+func TestIntegration_SetTTL(t *testing.T) {
+	repo := newIntegrationRepository(t)
+	key := "it:resp:ttl:" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "SET clears TTL",
+			input: "SET " + key + ":set a EX 100\r\nSET " + key + ":set b\r\nTTL " + key + ":set\r\n",
+			want:  "+OK\r\n+OK\r\n:-1\r\n",
+		},
+		{
+			name:  "KEEPTTL with EX",
+			input: "SET " + key + ":both a KEEPTTL EX 100\r\n",
+			want:  "-ERR syntax error\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runSession(t, repo, tt.input)
+			if want := tt.want + "+OK\r\n"; got != want {
+				t.Errorf("replies = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// expires_at хранится в секундах, поэтому остаток может округлиться до 99
+	got := runSession(t, repo, "SET "+key+":keep a EX 100\r\nSET "+key+":keep b KEEPTTL\r\nTTL "+key+":keep\r\n")
+	if got != "+OK\r\n+OK\r\n:100\r\n+OK\r\n" && got != "+OK\r\n+OK\r\n:99\r\n+OK\r\n" {
+		t.Errorf("SET KEEPTTL replies = %q, want the TTL to be kept", got)
+	}
+}
@@ -0,0 +1,58 @@
+package resp
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"kv-storage/internal/interfaces"
+	"kv-storage/internal/service"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Info(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warn(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Error(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Fatal(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Sync() error                                    { return nil }
+
+// runSession отправляет команды одному соединению сервера и возвращает все ответы;
+// соединение завершается командой QUIT
+func runSession(t *testing.T, repo interfaces.KVRepository, input string) string {
+	t.Helper()
+
+	server := NewServer(nil, nopLogger{}, service.NewKVService(repo, nopLogger{}, nil), nil, nil).(*Server)
+	client, conn := net.Pipe()
+	defer client.Close()
+
+	server.track(conn)
+	go server.serve(conn)
+
+	go func() {
+		io.WriteString(client, input+"QUIT\r\n")
+	}()
+
+	output, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatalf("read replies: %v", err)
+	}
+	return string(output)
+}
+
+// Строка из одних пробелов — пустая inline-команда, она пропускается
+func TestServeBlankInlineLine(t *testing.T) {
+	got := runSession(t, nil, "   \r\nPING\r\n")
+	if want := "+PONG\r\n+OK\r\n"; got != want {
+		t.Errorf("replies = %q, want %q", got, want)
+	}
+}
+
+func TestExecuteEmptyCommand(t *testing.T) {
+	server := &Server{}
+	session := &session{server: server, writer: newWriter(io.Discard)}
+	if session.execute(nil) {
+		t.Error("execute(nil) closes the connection")
+	}
+}